@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/keystore"
+	"github.com/vigozhang/neb-go/core/rpc"
+)
+
+var accountCommands = []*command{
+	{name: "new", usage: "[--keystore dir] --passphrase passphrase", summary: "create an account in the keystore", run: runAccountNew},
+	{name: "import", usage: "[--keystore dir] --passphrase passphrase <keyfile|--private-key hex>", summary: "import a keystore file or a private key", run: runAccountImport},
+	{name: "export", usage: "[--keystore dir] <address>", summary: "print the keystore json of an account", run: runAccountExport},
+	{name: "validate", usage: "<address>", summary: "check an address", run: runAccountValidate},
+}
+
+var accountCommand = &command{
+	name:    "account",
+	usage:   "[--height h] <address> | " + subcommandsUsage(accountCommands),
+	summary: "print the state of an address, or manage the local accounts",
+	run:     runAccount,
+}
+
+// runAccount prints the state of an address, the other arguments are local
+// account commands.
+func runAccount(cli *cli, args []string) error {
+	for _, sub := range accountCommands {
+		if len(args) > 0 && args[0] == sub.name {
+			return subcommands(cli, "account", accountCommands, args)
+		}
+	}
+
+	flags := cli.flagSet("account")
+	height := flags.Uint64("height", 0, "block height of the state, the tail when 0")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	req := rpc.GetAccountStateRequest{Address: rest[0], Height: *height}
+	resp, err := cli.neb().Api.GetAccountStateWithContext(cli.context(), req)
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+// addressResult is the output of the local account commands.
+type addressResult struct {
+	Address string `json:"address"`
+}
+
+// keystoreFlags registers the flags of the commands that write the keystore.
+func keystoreFlags(flags *flag.FlagSet) (dir *string, passphrase func() string) {
+	dir = flags.String("keystore", DefaultKeystore, "keystore directory")
+	return dir, passphraseFlag(flags, "passphrase of the key")
+}
+
+func runAccountNew(cli *cli, args []string) error {
+	flags := cli.flagSet("account new")
+	dir, passphrase := keystoreFlags(flags)
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	if passphrase() == "" {
+		return fmt.Errorf("%w: --passphrase is required", errUsage)
+	}
+
+	ks, err := keystore.NewKeystore(*dir, nil)
+	if err != nil {
+		return err
+	}
+	address, err := ks.NewAccount(passphrase())
+	if err != nil {
+		return err
+	}
+	return cli.print(&addressResult{Address: address})
+}
+
+func runAccountImport(cli *cli, args []string) error {
+	flags := cli.flagSet("account import")
+	dir, passphrase := keystoreFlags(flags)
+	privateKey := flags.String("private-key", "", "hex private key to import instead of a keyfile")
+	rest, err := parse(flags, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	if (len(rest) == 1) == (*privateKey != "") {
+		return fmt.Errorf("%w: give either a keyfile or --private-key", errUsage)
+	}
+
+	ks, err := keystore.NewKeystore(*dir, nil)
+	if err != nil {
+		return err
+	}
+	var address string
+	if *privateKey != "" {
+		if passphrase() == "" {
+			return fmt.Errorf("%w: --passphrase is required", errUsage)
+		}
+		key, err := hex.DecodeString(*privateKey)
+		if err != nil {
+			return fmt.Errorf("invalid private key: %v", err)
+		}
+		address, err = ks.ImportPrivateKey(key, passphrase())
+		if err != nil {
+			return err
+		}
+	} else {
+		keyjson, err := ioutil.ReadFile(rest[0])
+		if err != nil {
+			return err
+		}
+		address, err = ks.Import(string(keyjson), passphrase())
+		if err != nil {
+			return err
+		}
+	}
+	return cli.print(&addressResult{Address: address})
+}
+
+func runAccountExport(cli *cli, args []string) error {
+	flags := cli.flagSet("account export")
+	dir := flags.String("keystore", DefaultKeystore, "keystore directory")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(*dir); err != nil {
+		return err
+	}
+	ks, err := keystore.NewKeystore(*dir, nil)
+	if err != nil {
+		return err
+	}
+	keyjson, err := ks.Export(rest[0])
+	if err != nil {
+		return err
+	}
+	return cli.print(json.RawMessage(keyjson))
+}
+
+// validateResult is the output of account validate.
+type validateResult struct {
+	Address string `json:"address"`
+	Valid   bool   `json:"valid"`
+	// Type normal or contract
+	Type string `json:"type,omitempty"`
+}
+
+func runAccountValidate(cli *cli, args []string) error {
+	flags := cli.flagSet("account validate")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	result := &validateResult{Address: rest[0], Valid: account.IsValidAddress(rest[0])}
+	if result.Valid {
+		acc, _ := account.FromAddress(rest[0])
+		result.Type = "normal"
+		if acc.GetAddress()[1] == account.ContractType {
+			result.Type = "contract"
+		}
+	}
+	return cli.print(result)
+}
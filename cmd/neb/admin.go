@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+var adminCommands = []*command{
+	{name: "nodeinfo", usage: "", summary: "print the node info", run: runAdminNodeInfo},
+	{name: "accounts", usage: "", summary: "list the accounts of the node keystore", run: runAdminAccounts},
+	{name: "new", usage: "--passphrase passphrase", summary: "create an account in the node keystore", run: runAdminNewAccount},
+	{name: "unlock", usage: "--passphrase passphrase [--duration d] <address>", summary: "unlock an account of the node", run: runAdminUnlock},
+	{name: "lock", usage: "<address>", summary: "lock an account of the node", run: runAdminLock},
+	{name: "send", usage: "--from address --to address [--passphrase passphrase] [transaction flags]", summary: "send a transaction signed by the node", run: runAdminSend},
+	{name: "sign", usage: "--from address --to address --passphrase passphrase [transaction flags]", summary: "sign a transaction with the node", run: runAdminSign},
+	{name: "sign-hash", usage: "<address> <hex hash>", summary: "sign a hash with an unlocked account of the node", run: runAdminSignHash},
+	{name: "pprof", usage: "<listen address>", summary: "start the pprof server of the node", run: runAdminPprof},
+	{name: "config", usage: "", summary: "print the node config", run: runAdminConfig},
+}
+
+var adminCommand = &command{
+	name:    "admin",
+	usage:   subcommandsUsage(adminCommands),
+	summary: "call the admin api of the node",
+	run: func(cli *cli, args []string) error {
+		return subcommands(cli, "admin", adminCommands, args)
+	},
+}
+
+func runAdminNodeInfo(cli *cli, args []string) error {
+	flags := cli.flagSet("admin nodeinfo")
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Admin.NodeInfoWithContext(cli.context())
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runAdminAccounts(cli *cli, args []string) error {
+	flags := cli.flagSet("admin accounts")
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Admin.AccountsWithContext(cli.context())
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runAdminNewAccount(cli *cli, args []string) error {
+	flags := cli.flagSet("admin new")
+	passphrase := passphraseFlag(flags, "passphrase of the key")
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Admin.NewAccountWithContext(cli.context(), rpc.NewAccountRequest{Passphrase: passphrase()})
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runAdminUnlock(cli *cli, args []string) error {
+	flags := cli.flagSet("admin unlock")
+	passphrase := passphraseFlag(flags, "passphrase of the key")
+	duration := flags.Duration("duration", 0, "unlock duration, the node default when 0")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	req := rpc.UnlockAccountRequest{Address: rest[0], Passphrase: passphrase(), Duration: uint64(*duration / time.Nanosecond)}
+	resp, err := cli.neb().Admin.UnlockAccountWithContext(cli.context(), req)
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runAdminLock(cli *cli, args []string) error {
+	flags := cli.flagSet("admin lock")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Admin.LockAccountWithContext(cli.context(), rpc.LockAccountRequest{Address: rest[0]})
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+// runAdminSend sends a transaction signed by the node, with an unlocked
+// account or the passphrase of the account.
+func runAdminSend(cli *cli, args []string) error {
+	flags := cli.flagSet("admin send")
+	passphrase := passphraseFlag(flags, "passphrase of the from account, which must be unlocked otherwise")
+	tx := new(txFlags)
+	tx.register(flags)
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	req, err := tx.request()
+	if err != nil {
+		return err
+	}
+
+	admin := cli.neb().Admin
+	var resp *rpc.SendTransactionResponse
+	if phrase := passphrase(); phrase != "" {
+		resp, err = admin.SendTransactionWithPassphraseWithContext(cli.context(), rpc.SendTransactionPassphraseRequest{Transaction: &req, Passphrase: phrase})
+	} else {
+		resp, err = admin.SendTransactionWithContext(cli.context(), req)
+	}
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runAdminSign(cli *cli, args []string) error {
+	flags := cli.flagSet("admin sign")
+	passphrase := passphraseFlag(flags, "passphrase of the from account")
+	tx := new(txFlags)
+	tx.register(flags)
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	req, err := tx.request()
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Admin.SignTransactionWithPassphraseWithContext(cli.context(), rpc.SignTransactionPassphraseRequest{Transaction: &req, Passphrase: passphrase()})
+	if err != nil {
+		return err
+	}
+	if resp.Result == nil {
+		return emptyResult("SignTransactionWithPassphrase")
+	}
+	raw := base64.StdEncoding.EncodeToString(resp.Result.Data)
+	signed, err := new(transaction.Transaction).FromProto(raw)
+	if err != nil {
+		return err
+	}
+	return cli.print(&signedTransaction{Hash: hex.EncodeToString(signed.Hash), Raw: raw, Transaction: signed})
+}
+
+func runAdminSignHash(cli *cli, args []string) error {
+	flags := cli.flagSet("admin sign-hash")
+	rest, err := parse(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	hash, err := hex.DecodeString(rest[1])
+	if err != nil || len(hash) != 32 {
+		return fmt.Errorf("invalid hash %s", rest[1])
+	}
+
+	req := rpc.SignHashRequest{Address: rest[0], Hash: base64.StdEncoding.EncodeToString(hash), Alg: transaction.SECP256K1}
+	resp, err := cli.neb().Admin.SignHashWithContext(cli.context(), req)
+	if err != nil {
+		return err
+	}
+	if resp.Result == nil {
+		return emptyResult("SignHash")
+	}
+	return cli.print(&struct {
+		Signature string `json:"signature"`
+	}{hex.EncodeToString(resp.Result.Data)})
+}
+
+func runAdminPprof(cli *cli, args []string) error {
+	flags := cli.flagSet("admin pprof")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Admin.StartPprofWithContext(cli.context(), rpc.PprofRequest{Listen: rest[0]})
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runAdminConfig(cli *cli, args []string) error {
+	flags := cli.flagSet("admin config")
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Admin.GetConfigWithContext(cli.context())
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/rpc"
+)
+
+var apiCommands = []*command{
+	{name: "state", usage: "", summary: "print the node state", run: runState},
+	{name: "block", usage: "[--full] <height|hash|lib|tail>", summary: "print a block", run: runBlock},
+	{name: "receipt", usage: "[--wait timeout] [--contract] <hash|contract address>", summary: "print a transaction receipt", run: runReceipt},
+	{name: "events", usage: "<hash>", summary: "print the events of a transaction", run: runEvents},
+	{name: "dynasty", usage: "[height]", summary: "print the miners of a block", run: runDynasty},
+	{name: "gasprice", usage: "", summary: "print the gas price of the node", run: runGasPrice},
+	{name: "estimate", usage: "--from address --to address [transaction flags]", summary: "estimate the gas of a transaction", run: runEstimate},
+	{name: "call", usage: "--from address --to contract --function name [--args json] [transaction flags]", summary: "call a contract without sending a transaction", run: runCall},
+	{name: "send", usage: "--raw data | --from address --to address [key flags] [transaction flags] [--wait timeout]", summary: "send a raw or locally signed transaction", run: runSend},
+}
+
+func runState(cli *cli, args []string) error {
+	flags := cli.flagSet("state")
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Api.GetNebStateWithContext(cli.context())
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runBlock(cli *cli, args []string) error {
+	flags := cli.flagSet("block")
+	full := flags.Bool("full", false, "include the transactions instead of their hashes")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	api := cli.neb().Api
+	ctx := cli.context()
+	var resp *rpc.BlockResponse
+	switch rest[0] {
+	case "lib":
+		resp, err = api.LatestIrreversibleBlockWithContext(ctx)
+	case "tail":
+		state, stateErr := api.GetNebStateWithContext(ctx)
+		if stateErr != nil {
+			return stateErr
+		}
+		if state.Result == nil {
+			return emptyResult("GetNebState")
+		}
+		resp, err = api.GetBlockByHashWithContext(ctx, rpc.GetBlockByHashRequest{Hash: state.Result.Tail, FullFillTransaction: *full})
+	default:
+		height, parseErr := strconv.ParseUint(rest[0], 10, 64)
+		if parseErr == nil {
+			resp, err = api.GetBlockByHeightWithContext(ctx, rpc.GetBlockByHeightRequest{Height: height, FullFillTransaction: *full})
+		} else {
+			resp, err = api.GetBlockByHashWithContext(ctx, rpc.GetBlockByHashRequest{Hash: rest[0], FullFillTransaction: *full})
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runReceipt(cli *cli, args []string) error {
+	flags := cli.flagSet("receipt")
+	wait := flags.Duration("wait", 0, "wait up to this long for the transaction to be packed")
+	contract := flags.Bool("contract", false, "print the deploy transaction of a contract address")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	api := cli.neb().Api
+	if *contract {
+		resp, err := api.GetTransactionByContractWithContext(cli.context(), rpc.GetTransactionByContractRequest{Address: rest[0]})
+		if err != nil {
+			return err
+		}
+		return cli.print(resp.Result)
+	}
+	if *wait > 0 {
+		return cli.waitReceipt(api, rest[0], *wait)
+	}
+
+	resp, err := api.GetTransactionReceiptWithContext(cli.context(), rpc.HashRequest{Hash: rest[0]})
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+// waitReceipt prints the receipt of hash once the transaction is packed.
+func (cli *cli) waitReceipt(api *rpc.Api, hash string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(cli.context(), timeout)
+	defer cancel()
+
+	receipt, err := api.WaitForReceipt(ctx, hash, rpc.WaitOptions{PollInterval: time.Second})
+	if err != nil {
+		return err
+	}
+	return cli.print(receipt.Transaction)
+}
+
+func runEvents(cli *cli, args []string) error {
+	flags := cli.flagSet("events")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Api.GetEventsByHashWithContext(cli.context(), rpc.HashRequest{Hash: rest[0]})
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runDynasty(cli *cli, args []string) error {
+	flags := cli.flagSet("dynasty")
+	rest, err := parse(flags, args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	req := rpc.ByBlockHeightRequest{}
+	if len(rest) == 1 {
+		req.Height, err = strconv.ParseUint(rest[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid height %s", errUsage, rest[0])
+		}
+	}
+	resp, err := cli.neb().Api.GetDynastyWithContext(cli.context(), req)
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runGasPrice(cli *cli, args []string) error {
+	flags := cli.flagSet("gasprice")
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Api.GasPriceWithContext(cli.context())
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runEstimate(cli *cli, args []string) error {
+	flags := cli.flagSet("estimate")
+	tx := new(txFlags)
+	tx.register(flags)
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	req, err := tx.request()
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Api.EstimateGasWithContext(cli.context(), req)
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+func runCall(cli *cli, args []string) error {
+	flags := cli.flagSet("call")
+	tx := new(txFlags)
+	tx.register(flags)
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	req, err := tx.request()
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.neb().Api.CallWithContext(cli.context(), req)
+	if err != nil {
+		return err
+	}
+	return cli.print(resp.Result)
+}
+
+// runSend sends a raw transaction, or builds one with the node state, signs
+// it with a local key and sends it.
+func runSend(cli *cli, args []string) error {
+	flags := cli.flagSet("send")
+	raw := flags.String("raw", "", "base64 protobuf of a signed transaction, e.g. from transaction sign")
+	wait := flags.Duration("wait", 0, "wait up to this long for the transaction to be packed and print its receipt")
+	tx := new(txFlags)
+	tx.register(flags)
+	key := new(keyFlags)
+	key.register(flags)
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	neb := cli.neb()
+	ctx := cli.context()
+	var resp *rpc.SendTransactionResponse
+	if *raw != "" {
+		var err error
+		resp, err = neb.Api.SendRawTransactionWithContext(ctx, rpc.SendRawTransactionRequest{Data: *raw})
+		if err != nil {
+			return err
+		}
+	} else {
+		opts, err := tx.options(cli)
+		if err != nil {
+			return err
+		}
+		opts.From, err = key.account(tx.from)
+		if err != nil {
+			return err
+		}
+		_, resp, err = rpc.NewTransactionBuilder(neb.Api).Send(ctx, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if resp.Result == nil {
+		return emptyResult("SendRawTransaction")
+	}
+	if *wait > 0 {
+		return cli.waitReceipt(neb.Api, resp.Result.Txhash, *wait)
+	}
+	return cli.print(resp.Result)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/vigozhang/neb-go/core/contract"
+)
+
+var bindCommand = &command{
+	name:    "bind",
+	usage:   "--pkg name --type Name [--out file] <interface.json|->",
+	summary: "generate the Go binding of a contract interface",
+	run:     runBind,
+}
+
+// runBind prints the generated Go source instead of json, or writes it to
+// --out.
+func runBind(cli *cli, args []string) error {
+	flags := cli.flagSet("bind")
+	pkg := flags.String("pkg", "", "package of the generated file")
+	typeName := flags.String("type", "", "name of the binding type")
+	out := flags.String("out", "", "file to write, stdout when empty")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if *pkg == "" || *typeName == "" {
+		return fmt.Errorf("%w: --pkg and --type are required", errUsage)
+	}
+
+	var data []byte
+	if rest[0] == "-" {
+		data, err = ioutil.ReadAll(cli.stdin)
+	} else {
+		data, err = ioutil.ReadFile(rest[0])
+	}
+	if err != nil {
+		return err
+	}
+	abi, err := contract.ParseABI(data)
+	if err != nil {
+		return err
+	}
+	src, err := contract.Bind(abi, *pkg, *typeName)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = cli.stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(*out, src, 0644)
+}
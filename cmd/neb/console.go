@@ -0,0 +1,503 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/keystore"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// DefaultConsoleTimeout the time a console call may take without --timeout.
+const DefaultConsoleTimeout = 30 * time.Second
+
+var consoleCommand = &command{
+	name:    "console",
+	usage:   "[--keystore dir] [--timeout duration]",
+	summary: "start an interactive console, e.g. api.getAccountState(\"n1...\")",
+	run:     runConsole,
+}
+
+// console evaluates the lines ns.method(args) against the namespaces.
+type console struct {
+	cli        *cli
+	neb        *rpc.Neb
+	out        io.Writer
+	lines      lineReader
+	namespaces map[string]*namespace
+
+	// timeout bounds every call, interrupt cancels it on Ctrl-C until the
+	// returned stop is called.
+	timeout   time.Duration
+	interrupt func(cancel context.CancelFunc) (stop func())
+
+	keystoreDir string
+	keystore    *keystore.Keystore
+}
+
+// lineReader reads the console input, from a terminal or a script.
+type lineReader interface {
+	readLine() (string, error)
+	readPassword(prompt string) (string, error)
+}
+
+func runConsole(cli *cli, args []string) error {
+	flags := cli.flagSet("console")
+	dir := flags.String("keystore", DefaultKeystore, "keystore directory of the ks namespace")
+	timeout := flags.Duration("timeout", DefaultConsoleTimeout, "time a call may take before it is cancelled")
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	c := newConsole(cli, *dir)
+	c.timeout = *timeout
+	if file, ok := cli.stdin.(*os.File); ok && terminal.IsTerminal(int(file.Fd())) {
+		state, err := terminal.MakeRaw(int(file.Fd()))
+		if err != nil {
+			return err
+		}
+		defer terminal.Restore(int(file.Fd()), state)
+
+		// raw mode delivers Ctrl-C as a key instead of SIGINT
+		input := newInterruptReader(file)
+		term := terminal.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{input, cli.stdout}, "> ")
+		term.AutoCompleteCallback = c.autoComplete(term)
+		c.out = term
+		c.lines = &terminalReader{term, input}
+		c.interrupt = input.watch
+		fmt.Fprintf(c.out, "connected to %s\ntype help for the namespaces, exit to quit\n", cli.node)
+	} else {
+		c.out = cli.stdout
+		c.lines = &scriptReader{bufio.NewScanner(cli.stdin)}
+	}
+	return c.loop()
+}
+
+func newConsole(cli *cli, keystoreDir string) *console {
+	c := &console{
+		cli:         cli,
+		neb:         cli.neb(),
+		out:         cli.stdout,
+		timeout:     DefaultConsoleTimeout,
+		interrupt:   watchSignal,
+		keystoreDir: keystoreDir,
+	}
+	c.namespaces = make(map[string]*namespace)
+	for _, ns := range []*namespace{
+		newNamespace("api", "the rpc api of the node", c.neb.Api),
+		newNamespace("admin", "the admin api of the node", c.neb.Admin),
+		newNamespace("ks", "the local keystore", &consoleKeystore{c}),
+		newNamespace("tx", "transactions signed with the local keystore", &consoleTx{c}),
+	} {
+		c.namespaces[ns.name] = ns
+	}
+	return c
+}
+
+// loop evaluates the lines until exit or the end of the input.
+func (c *console) loop() error {
+	for {
+		line, err := c.lines.readLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		switch line {
+		case "":
+		case "exit", "quit":
+			return nil
+		case "help":
+			c.help()
+		default:
+			c.eval(line)
+		}
+	}
+}
+
+func (c *console) help() {
+	fmt.Fprintln(c.out, "namespaces, type one to list its methods:")
+	for _, name := range c.namespaceNames() {
+		fmt.Fprintf(c.out, "  %-6s %s\n", name, c.namespaces[name].summary)
+	}
+	fmt.Fprintln(c.out, "call a method with json arguments, e.g. api.getAccountState(\"n1...\"), Tab completes")
+}
+
+// eval prints the result of a line, or its error.
+func (c *console) eval(line string) {
+	if ns, ok := c.namespaces[line]; ok {
+		for _, name := range ns.names() {
+			fmt.Fprintln(c.out, ns.methods[name].usage(ns.name))
+		}
+		return
+	}
+
+	result, err := c.call(line)
+	if err == nil {
+		var data []byte
+		data, err = json.MarshalIndent(result, "", "  ")
+		if err == nil {
+			fmt.Fprintln(c.out, string(data))
+			return
+		}
+	}
+	fmt.Fprintf(c.out, "Error: %v\n", err)
+}
+
+func (c *console) call(line string) (interface{}, error) {
+	nsName, name, args, err := parseCall(line)
+	if err != nil {
+		return nil, err
+	}
+	ns, ok := c.namespaces[nsName]
+	if !ok {
+		return nil, fmt.Errorf("unknown namespace %s", nsName)
+	}
+	m, ok := ns.methods[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown method %s.%s", nsName, name)
+	}
+	ctx, cancel := context.WithTimeout(c.cli.context(), c.timeout)
+	defer cancel()
+	defer c.interrupt(cancel)()
+	return m.call(ctx, args)
+}
+
+func (c *console) namespaceNames() []string {
+	names := make([]string, 0, len(c.namespaces))
+	for name := range c.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// complete completes the namespace or method name before pos, it returns the
+// new line and cursor, and the candidates.
+func (c *console) complete(line string, pos int) (string, int, []string) {
+	start := pos
+	for start > 0 && isNameByte(line[start-1]) {
+		start--
+	}
+	word := line[start:pos]
+
+	var candidates []string
+	if dot := strings.IndexByte(word, '.'); dot >= 0 {
+		ns, ok := c.namespaces[word[:dot]]
+		if !ok {
+			return line, pos, nil
+		}
+		for _, name := range ns.names() {
+			if strings.HasPrefix(name, word[dot+1:]) {
+				candidates = append(candidates, ns.name+"."+name+"(")
+			}
+		}
+	} else {
+		for _, name := range c.namespaceNames() {
+			if strings.HasPrefix(name, word) {
+				candidates = append(candidates, name+".")
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return line, pos, nil
+	}
+
+	completed := commonPrefix(candidates)
+	return line[:start] + completed + line[pos:], start + len(completed), candidates
+}
+
+// autoComplete completes on Tab, and lists the candidates when the
+// completion is ambiguous.
+func (c *console) autoComplete(term *terminal.Terminal) func(string, int, rune) (string, int, bool) {
+	return func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		newLine, newPos, candidates := c.complete(line, pos)
+		if len(candidates) > 1 && newPos == pos {
+			fmt.Fprintln(term, strings.Join(candidates, "  "))
+		}
+		return newLine, newPos, len(candidates) > 0
+	}
+}
+
+func isNameByte(b byte) bool {
+	return b == '.' || b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// passphrase returns passphrase, or reads it when it is empty.
+func (c *console) passphrase(passphrase string) (string, error) {
+	if passphrase != "" {
+		return passphrase, nil
+	}
+	return c.lines.readPassword("Passphrase: ")
+}
+
+// openKeystore opens the keystore on its first use.
+func (c *console) openKeystore() (*keystore.Keystore, error) {
+	if c.keystore == nil {
+		ks, err := keystore.NewKeystore(c.keystoreDir, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.keystore = ks
+	}
+	return c.keystore, nil
+}
+
+type terminalReader struct {
+	term  *terminal.Terminal
+	input *interruptReader
+}
+
+func (r *terminalReader) readLine() (string, error) {
+	return r.term.ReadLine()
+}
+
+// readPassword lets Ctrl-C through to the terminal, which gives up the
+// prompt, even during a call.
+func (r *terminalReader) readPassword(prompt string) (string, error) {
+	defer r.input.watch(nil)()
+	return r.term.ReadPassword(prompt)
+}
+
+// interruptReader reads the terminal in the background, so that a Ctrl-C
+// typed while a call runs and nothing reads the terminal cancels the call.
+type interruptReader struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	err    error
+	cancel context.CancelFunc
+}
+
+func newInterruptReader(r io.Reader) *interruptReader {
+	input := &interruptReader{}
+	input.cond = sync.NewCond(&input.mu)
+	go input.run(r)
+	return input
+}
+
+func (r *interruptReader) run(reader io.Reader) {
+	chunk := make([]byte, 256)
+	for {
+		n, err := reader.Read(chunk)
+
+		r.mu.Lock()
+		for _, key := range chunk[:n] {
+			if key == keyCtrlC && r.cancel != nil {
+				r.cancel()
+				continue
+			}
+			r.buf = append(r.buf, key)
+		}
+		r.err = err
+		r.cond.Broadcast()
+		r.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (r *interruptReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for len(r.buf) == 0 && r.err == nil {
+		r.cond.Wait()
+	}
+	if len(r.buf) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// watch cancels with cancel on Ctrl-C until stop is called, a nil cancel
+// leaves Ctrl-C in the input.
+func (r *interruptReader) watch(cancel context.CancelFunc) (stop func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.cancel
+	r.cancel = cancel
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.cancel = previous
+	}
+}
+
+// keyCtrlC the key of Ctrl-C in raw mode.
+const keyCtrlC = 3
+
+// watchSignal cancels with cancel on SIGINT until stop is called, the
+// console reads a script or a terminal not in raw mode.
+func watchSignal(cancel context.CancelFunc) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// scriptReader reads the lines of stdin, a passphrase is the next line.
+type scriptReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *scriptReader) readLine() (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *scriptReader) readPassword(prompt string) (string, error) {
+	return r.readLine()
+}
+
+// consoleKeystore is the ks namespace, the accounts of the local keystore.
+// The passphrases are prompted for when they are not given.
+type consoleKeystore struct {
+	console *console
+}
+
+func (ks *consoleKeystore) Accounts() ([]string, error) {
+	store, err := ks.console.openKeystore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Accounts()
+}
+
+func (ks *consoleKeystore) NewAccount(req rpc.NewAccountRequest) (*addressResult, error) {
+	store, err := ks.console.openKeystore()
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := ks.console.passphrase(req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	address, err := store.NewAccount(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &addressResult{Address: address}, nil
+}
+
+// Unlock unlocks an account for the tx namespace, the duration is in
+// nanoseconds like admin.unlockAccount.
+func (ks *consoleKeystore) Unlock(req rpc.UnlockAccountRequest) (bool, error) {
+	store, err := ks.console.openKeystore()
+	if err != nil {
+		return false, err
+	}
+	passphrase, err := ks.console.passphrase(req.Passphrase)
+	if err != nil {
+		return false, err
+	}
+	err = store.Unlock(req.Address, passphrase, time.Duration(req.Duration))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (ks *consoleKeystore) Lock(req rpc.LockAccountRequest) (bool, error) {
+	store, err := ks.console.openKeystore()
+	if err != nil {
+		return false, err
+	}
+	store.Lock(req.Address)
+	return true, nil
+}
+
+// consoleTx is the tx namespace, transactions built with the node state and
+// signed by an unlocked account of the local keystore.
+type consoleTx struct {
+	console *console
+}
+
+func (tx *consoleTx) Sign(ctx context.Context, req rpc.TransactionRequest) (*signedTransaction, error) {
+	store, err := tx.console.openKeystore()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := requestOptions(&req, uint32(tx.console.cli.chainID))
+	if err != nil {
+		return nil, err
+	}
+	opts.From, err = account.FromAddress(req.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %s", req.From)
+	}
+
+	builder := rpc.NewTransactionBuilder(tx.console.neb.Api)
+	builder.Signer = store.Signer(req.From)
+	signed, err := builder.Build(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.ToProtoString()
+	if err != nil {
+		return nil, err
+	}
+	return &signedTransaction{Hash: hex.EncodeToString(signed.Hash), Raw: raw, Transaction: signed}, nil
+}
+
+func (tx *consoleTx) Send(ctx context.Context, req rpc.TransactionRequest) (*rpc.SendTransactionResponse, error) {
+	signed, err := tx.Sign(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return tx.console.neb.Api.SendRawTransactionWithContext(ctx, rpc.SendRawTransactionRequest{Data: signed.Raw})
+}
+
+func (tx *consoleTx) Decode(req rpc.SendRawTransactionRequest) (*decodedTransaction, error) {
+	return decodeTransaction(req.Data)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/rpc/rpctest"
+)
+
+func TestConsole_Run(t *testing.T) {
+	node := rpctest.NewNode(rpctest.Options{AutoMine: true})
+	defer node.Close()
+	dir := t.TempDir()
+
+	var created addressResult
+	if runNeb(t, "", &created, "account", "new", "--keystore", dir, "--passphrase", "passphrase") != 0 {
+		t.Fatal("TestConsole_Run new failed")
+	}
+	node.SetBalance(created.Address, big.NewInt(1e18))
+
+	script := strings.Join([]string{
+		`api.getNebState()`,
+		fmt.Sprintf(`api.getAccountState(%q)`, created.Address),
+		fmt.Sprintf(`ks.unlock(%q)`, created.Address),
+		`passphrase`,
+		fmt.Sprintf(`tx.send({"from": %q, "to": %q, "value": "7"})`, created.Address, testTo),
+		`exit`,
+		`api.getNebState()`,
+	}, "\n")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--node", node.URL(), "console", "--keystore", dir}, strings.NewReader(script), &stdout, &stderr); code != 0 {
+		t.Fatalf("TestConsole_Run exit %d: %s", code, stderr.String())
+	}
+
+	var state struct {
+		ChainID uint32 `json:"chain_id"`
+	}
+	var accountState struct {
+		Balance string `json:"balance"`
+	}
+	var unlocked bool
+	var sent struct {
+		Txhash string `json:"txhash"`
+	}
+	decoder := json.NewDecoder(&stdout)
+	for _, out := range []interface{}{&state, &accountState, &unlocked, &sent} {
+		if err := decoder.Decode(out); err != nil {
+			t.Fatal("TestConsole_Run output:", err)
+		}
+	}
+	if state.ChainID != rpctest.DefaultChainID || accountState.Balance != "1000000000000000000" || !unlocked || sent.Txhash == "" {
+		t.Errorf("TestConsole_Run results %+v %+v %v %+v", state, accountState, unlocked, sent)
+	}
+	if err := decoder.Decode(new(interface{})); err != io.EOF {
+		t.Errorf("TestConsole_Run evaluated after exit: %v", err)
+	}
+}
+
+func TestConsole_Eval(t *testing.T) {
+	node := rpctest.NewNode(rpctest.Options{})
+	defer node.Close()
+
+	var out bytes.Buffer
+	c := newConsole(&cli{node: node.URL(), stdout: &out}, t.TempDir())
+	c.out = &out
+
+	c.eval("api")
+	if !strings.Contains(out.String(), "api.getAccountState(address, height)\n") || strings.Contains(out.String(), "WithContext") {
+		t.Errorf("TestConsole_Eval methods %s", out.String())
+	}
+	for _, line := range []string{"api.unknown()", "unknown.getNebState()", "api.getNebState", `api.getAccountState("n1", 1, 2)`} {
+		out.Reset()
+		c.eval(line)
+		if !strings.HasPrefix(out.String(), "Error: ") {
+			t.Errorf("TestConsole_Eval %s printed %s", line, out.String())
+		}
+	}
+}
+
+func TestConsole_Timeout(t *testing.T) {
+	// a node that never answers
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	c := newConsole(&cli{node: server.URL, stdout: &out}, t.TempDir())
+	c.out = &out
+	c.timeout = 100 * time.Millisecond
+	c.eval("api.getNebState()")
+	if !strings.HasPrefix(out.String(), "Error: ") || !strings.Contains(out.String(), context.DeadlineExceeded.Error()) {
+		t.Errorf("TestConsole_Timeout printed %s", out.String())
+	}
+
+	out.Reset()
+	c.timeout = time.Minute
+	c.interrupt = func(cancel context.CancelFunc) func() {
+		time.AfterFunc(100*time.Millisecond, cancel)
+		return func() {}
+	}
+	c.eval("api.getNebState()")
+	if !strings.HasPrefix(out.String(), "Error: ") || !strings.Contains(out.String(), context.Canceled.Error()) {
+		t.Errorf("TestConsole_Timeout interrupted printed %s", out.String())
+	}
+}
+
+func TestInterruptReader(t *testing.T) {
+	in, keys := io.Pipe()
+	input := newInterruptReader(in)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stop := input.watch(cancel)
+	keys.Write([]byte{'a', keyCtrlC})
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("TestInterruptReader Ctrl-C did not cancel")
+	}
+	stop()
+	keys.Write([]byte{keyCtrlC, 'b'})
+	keys.Close()
+
+	read, err := ioutil.ReadAll(input)
+	if err != nil || string(read) != "a\x03b" {
+		t.Errorf("TestInterruptReader read %q: %v", read, err)
+	}
+}
+
+func TestConsole_Complete(t *testing.T) {
+	c := newConsole(&cli{node: "http://localhost:8685"}, t.TempDir())
+
+	tests := []struct {
+		line       string
+		pos        int
+		want       string
+		candidates int
+	}{
+		{"a", 1, "a", 2},
+		{"ap", 2, "api.", 1},
+		{"api.getAcc", 10, "api.getAccountState(", 1},
+		{"admin.unlo", 10, "admin.unlockAccount(", 1},
+		{`api.getBlockBy("x")`, 14, `api.getBlockByH("x")`, 2},
+		{"foo.bar", 7, "foo.bar", 0},
+	}
+	for _, tt := range tests {
+		line, pos, candidates := c.complete(tt.line, tt.pos)
+		if line != tt.want || len(candidates) != tt.candidates {
+			t.Errorf("TestConsole_Complete %q: %q %d %v", tt.line, line, pos, candidates)
+		}
+	}
+
+	if _, _, candidates := c.complete("tx.", 3); !reflect.DeepEqual(candidates, []string{"tx.decode(", "tx.send(", "tx.sign("}) {
+		t.Errorf("TestConsole_Complete tx methods %v", candidates)
+	}
+}
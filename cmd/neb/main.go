@@ -0,0 +1,239 @@
+// Command neb is a command-line client of the Nebulas rpc api built on the
+// SDK. Every command but bind prints its result as json on stdout.
+//
+//	neb [--node url] [--chain-id id] <command> [flags] [args]
+//
+// Run neb help for the list of commands.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+// errUsage the command line is invalid, the usage of the command is printed
+var errUsage = errors.New("invalid usage")
+
+// usageError is an invalid usage of a subcommand, with its usage.
+type usageError struct {
+	err   error
+	usage string
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+func (e *usageError) Unwrap() error { return e.err }
+
+// command is a subcommand of neb. run gets the arguments after the command
+// name.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(cli *cli, args []string) error
+}
+
+// cli is the state of one neb invocation.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	node    string
+	chainID uint
+}
+
+var commands []*command
+
+func init() {
+	commands = append(commands, apiCommands...)
+	commands = append(commands, accountCommand, transactionCommand, adminCommand, consoleCommand, bindCommand)
+	sort.Slice(commands, func(i, j int) bool { return commands[i].name < commands[j].name })
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command line args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	cli := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	flags := cli.flagSet("neb")
+	flags.Usage = func() { cli.usage() }
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	args = flags.Args()
+	if len(args) == 0 || args[0] == "help" {
+		cli.usage()
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "neb: unknown command %q\n", args[0])
+		cli.usage()
+		return 2
+	}
+
+	err := cmd.run(cli, args[1:])
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "neb %s: %v\n", cmd.name, err)
+		}
+		usage := "neb " + cmd.name + " " + cmd.usage
+		var subErr *usageError
+		if errors.As(err, &subErr) {
+			usage = subErr.usage
+		}
+		fmt.Fprintf(stderr, "usage: %s\n", usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "neb %s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func (cli *cli) usage() {
+	fmt.Fprintln(cli.stderr, "usage: neb [--node url] [--chain-id id] <command> [flags] [args]")
+	fmt.Fprintln(cli.stderr, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(cli.stderr, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(cli.stderr, "\nthe node defaults to $NEB_NODE, or the main net")
+}
+
+// flagSet returns a flag set with the global flags, so they can be given
+// before or after the command name.
+func (cli *cli) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(cli.stderr)
+
+	node := os.Getenv("NEB_NODE")
+	if node == "" {
+		node = httprequest.MainNet
+	}
+	if cli.node != "" {
+		node = cli.node
+	}
+	flags.StringVar(&cli.node, "node", node, "url of the node rpc api")
+	flags.UintVar(&cli.chainID, "chain-id", cli.chainID, "chain id of the transactions, from the node when 0")
+	return flags
+}
+
+// parse parses args into flags and checks the number of positional
+// arguments is between min and max, no limit when max is negative.
+func parse(flags *flag.FlagSet, args []string, min int, max int) ([]string, error) {
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, flag.ErrHelp
+	}
+	rest := flags.Args()
+	if len(rest) < min {
+		return nil, fmt.Errorf("%w: missing arguments", errUsage)
+	}
+	if max >= 0 && len(rest) > max {
+		return nil, fmt.Errorf("%w: unexpected arguments %s", errUsage, strings.Join(rest[max:], " "))
+	}
+	return rest, nil
+}
+
+// passphraseFlag registers --passphrase, the returned func gives its value or
+// $NEB_PASSPHRASE. The variable is not the flag default, so usage does not
+// print it.
+func passphraseFlag(flags *flag.FlagSet, usage string) func() string {
+	passphrase := flags.String("passphrase", "", usage+", $NEB_PASSPHRASE by default")
+	return func() string {
+		if *passphrase != "" {
+			return *passphrase
+		}
+		return os.Getenv("NEB_PASSPHRASE")
+	}
+}
+
+func (cli *cli) neb() *rpc.Neb {
+	return rpc.NewNeb(httprequest.NewHttpRequest(strings.TrimRight(cli.node, "/"), httprequest.APIVersion1))
+}
+
+func (cli *cli) context() context.Context {
+	return context.Background()
+}
+
+// input returns arg, or stdin when arg is "-".
+func (cli *cli) input(arg string) (string, error) {
+	if arg != "-" {
+		return arg, nil
+	}
+	data, err := ioutil.ReadAll(cli.stdin)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// print writes v as indented json to stdout.
+func (cli *cli) print(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cli.stdout, string(data))
+	return err
+}
+
+// emptyResult is the error of a node response of method without a result.
+func emptyResult(method string) error {
+	return fmt.Errorf("%s: %w", method, rpc.ErrEmptyResult)
+}
+
+// subcommands runs the subcommand of a command group named by args[0].
+func subcommands(cli *cli, group string, subs []*command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing %s command", errUsage, group)
+	}
+	for _, sub := range subs {
+		if sub.name == args[0] {
+			err := sub.run(cli, args[1:])
+			if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+				return &usageError{err, strings.Join([]string{"neb", group, sub.name, sub.usage}, " ")}
+			}
+			return err
+		}
+	}
+	return fmt.Errorf("%w: unknown %s command %q", errUsage, group, args[0])
+}
+
+// subcommandsUsage lists the subcommands of a command group.
+func subcommandsUsage(subs []*command) string {
+	names := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		names = append(names, sub.name)
+	}
+	return "<" + strings.Join(names, "|") + "> [flags] [args]"
+}
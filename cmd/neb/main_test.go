@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/rpc/rpctest"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+const testTo = "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17"
+
+// runNeb runs neb with args and decodes its json output into out.
+func runNeb(t *testing.T, stdin string, out interface{}, args ...string) int {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	if code == 0 && out != nil {
+		if err := json.Unmarshal(stdout.Bytes(), out); err != nil {
+			t.Fatalf("neb %v printed %s: %v", args, stdout.String(), err)
+		}
+	}
+	if code != 0 {
+		t.Logf("neb %v: %s", args, stderr.String())
+	}
+	return code
+}
+
+func TestRun_Usage(t *testing.T) {
+	if code := runNeb(t, "", nil); code != 2 {
+		t.Errorf("TestRun_Usage no command exit %d", code)
+	}
+	if code := runNeb(t, "", nil, "unknown"); code != 2 {
+		t.Errorf("TestRun_Usage unknown command exit %d", code)
+	}
+	if code := runNeb(t, "", nil, "receipt"); code != 2 {
+		t.Errorf("TestRun_Usage missing argument exit %d", code)
+	}
+	if code := runNeb(t, "", nil, "transaction", "unknown"); code != 2 {
+		t.Errorf("TestRun_Usage unknown subcommand exit %d", code)
+	}
+}
+
+func TestRun_Api(t *testing.T) {
+	node := rpctest.NewNode(rpctest.Options{})
+	defer node.Close()
+
+	var state struct {
+		ChainID uint32 `json:"chain_id"`
+		Height  string `json:"height"`
+	}
+	if runNeb(t, "", &state, "--node", node.URL(), "state") != 0 || state.ChainID != rpctest.DefaultChainID || state.Height != "1" {
+		t.Errorf("TestRun_Api state %+v", state)
+	}
+
+	var block struct {
+		Height string `json:"height"`
+	}
+	if runNeb(t, "", &block, "block", "--node", node.URL(), "1") != 0 || block.Height != "1" {
+		t.Errorf("TestRun_Api block %+v", block)
+	}
+	if runNeb(t, "", &block, "--node", node.URL(), "block", "lib") != 0 {
+		t.Error("TestRun_Api lib failed")
+	}
+
+	var gasPrice struct {
+		GasPrice string `json:"gas_price"`
+	}
+	if runNeb(t, "", &gasPrice, "--node", node.URL(), "gasprice") != 0 || gasPrice.GasPrice != "1000000" {
+		t.Errorf("TestRun_Api gasprice %+v", gasPrice)
+	}
+
+	if code := runNeb(t, "", nil, "--node", node.URL(), "receipt", "00"); code != 1 {
+		t.Errorf("TestRun_Api unknown receipt exit %d", code)
+	}
+}
+
+func TestRun_Account(t *testing.T) {
+	node := rpctest.NewNode(rpctest.Options{})
+	defer node.Close()
+	dir := filepath.Join(t.TempDir(), "keydir")
+
+	var created addressResult
+	if runNeb(t, "", &created, "account", "new", "--keystore", dir, "--passphrase", "passphrase") != 0 {
+		t.Fatal("TestRun_Account new failed")
+	}
+	var validated validateResult
+	if runNeb(t, "", &validated, "account", "validate", created.Address) != 0 || !validated.Valid || validated.Type != "normal" {
+		t.Errorf("TestRun_Account validate %+v", validated)
+	}
+	if runNeb(t, "", &validated, "account", "validate", "n1invalid") != 0 || validated.Valid {
+		t.Errorf("TestRun_Account validate invalid %+v", validated)
+	}
+
+	var keyjson json.RawMessage
+	if runNeb(t, "", &keyjson, "account", "export", "--keystore", dir, created.Address) != 0 {
+		t.Fatal("TestRun_Account export failed")
+	}
+	acc, err := account.NewAccount().FromKey(string(keyjson), "passphrase", false)
+	if err != nil || acc.GetAddressString() != created.Address {
+		t.Fatal("TestRun_Account exported key:", err)
+	}
+
+	other := filepath.Join(t.TempDir(), "keydir")
+	var imported addressResult
+	priv := acc.GetPrivateKeyString()
+	if runNeb(t, "", &imported, "account", "import", "--keystore", other, "--passphrase", "other", "--private-key", priv) != 0 || imported.Address != created.Address {
+		t.Errorf("TestRun_Account import %+v", imported)
+	}
+
+	node.SetBalance(created.Address, big.NewInt(1000))
+	var state struct {
+		Balance string `json:"balance"`
+	}
+	if runNeb(t, "", &state, "--node", node.URL(), "account", created.Address) != 0 || state.Balance != "1000" {
+		t.Errorf("TestRun_Account state %+v", state)
+	}
+}
+
+func TestRun_Transaction(t *testing.T) {
+	node := rpctest.NewNode(rpctest.Options{AutoMine: true})
+	defer node.Close()
+	dir := t.TempDir()
+
+	var created addressResult
+	if runNeb(t, "", &created, "account", "new", "--keystore", dir, "--passphrase", "passphrase") != 0 {
+		t.Fatal("TestRun_Transaction new failed")
+	}
+	node.SetBalance(created.Address, big.NewInt(1e18))
+
+	var unsigned json.RawMessage
+	if runNeb(t, "", &unsigned, "--chain-id", "100", "transaction", "build", "--from", created.Address, "--to", testTo, "--value", "10", "--nonce", "1") != 0 {
+		t.Fatal("TestRun_Transaction build failed")
+	}
+	if code := runNeb(t, "", nil, "transaction", "build", "--from", created.Address, "--to", testTo); code != 2 {
+		t.Errorf("TestRun_Transaction build without chain id exit %d", code)
+	}
+
+	var signed signedTransaction
+	if runNeb(t, string(unsigned), &signed, "transaction", "sign", "--keystore", dir, "--passphrase", "passphrase", "-") != 0 {
+		t.Fatal("TestRun_Transaction sign failed")
+	}
+	if code := runNeb(t, string(unsigned), nil, "transaction", "sign", "--keystore", dir, "--passphrase", "wrong", "-"); code != 1 {
+		t.Errorf("TestRun_Transaction sign with a wrong passphrase exit %d", code)
+	}
+
+	var decoded decodedTransaction
+	if runNeb(t, "", &decoded, "transaction", "decode", signed.Raw) != 0 || !decoded.Verified || decoded.Transaction.Value.Int64() != 10 {
+		t.Errorf("TestRun_Transaction decode %+v", decoded)
+	}
+
+	var sent struct {
+		Txhash string `json:"txhash"`
+	}
+	if runNeb(t, "", &sent, "--node", node.URL(), "send", "--raw", signed.Raw) != 0 || sent.Txhash != signed.Hash {
+		t.Fatalf("TestRun_Transaction send %+v", sent)
+	}
+
+	var receipt struct {
+		Status int32 `json:"status"`
+	}
+	if runNeb(t, "", &receipt, "--node", node.URL(), "receipt", "--wait", "10s", sent.Txhash) != 0 || receipt.Status != 1 {
+		t.Errorf("TestRun_Transaction receipt %+v", receipt)
+	}
+
+	if runNeb(t, "", &receipt, "--node", node.URL(), "send", "--keystore", dir, "--passphrase", "passphrase", "--from", created.Address, "--to", testTo, "--value", "5", "--wait", "10s") != 0 || receipt.Status != 1 {
+		t.Errorf("TestRun_Transaction send with the keystore %+v", receipt)
+	}
+	if node.Balance(testTo).Int64() != 15 {
+		t.Errorf("TestRun_Transaction balance %s", node.Balance(testTo))
+	}
+}
+
+// TestRun_TransactionPipe runs build | sign | decode on a call, with the
+// unsigned transaction indented in between like a user editing it would.
+func TestRun_TransactionPipe(t *testing.T) {
+	dir := t.TempDir()
+	var created addressResult
+	if runNeb(t, "", &created, "account", "new", "--keystore", dir, "--passphrase", "passphrase") != 0 {
+		t.Fatal("TestRun_TransactionPipe new failed")
+	}
+
+	var unsigned json.RawMessage
+	if runNeb(t, "", &unsigned, "--chain-id", "100", "transaction", "build", "--from", created.Address, "--to", testTo, "--nonce", "1",
+		"--function", "transfer", "--args", `["n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17", "10"]`) != 0 {
+		t.Fatal("TestRun_TransactionPipe build failed")
+	}
+	var built transaction.Transaction
+	if err := json.Unmarshal(unsigned, &built); err != nil {
+		t.Fatal("TestRun_TransactionPipe build output:", err)
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, unsigned, "", "  "); err != nil {
+		t.Fatal(err)
+	}
+
+	var signed signedTransaction
+	if runNeb(t, indented.String(), &signed, "transaction", "sign", "--keystore", dir, "--passphrase", "passphrase", "-") != 0 {
+		t.Fatal("TestRun_TransactionPipe sign failed")
+	}
+	var decoded decodedTransaction
+	if runNeb(t, signed.Raw, &decoded, "transaction", "decode", "-") != 0 || !decoded.Verified {
+		t.Fatalf("TestRun_TransactionPipe decode %+v", decoded)
+	}
+	if !bytes.Equal(decoded.Transaction.Data.Payload, built.Data.Payload) || !bytes.Equal(signed.Transaction.Data.Payload, built.Data.Payload) {
+		t.Errorf("TestRun_TransactionPipe payload %s, built %s", decoded.Transaction.Data.Payload, built.Data.Payload)
+	}
+	payload, err := decoded.Transaction.DecodePayload()
+	call, ok := payload.(*transaction.TransactionCallPayload)
+	if err != nil || !ok || call.Function != "transfer" || call.Args != `["n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17", "10"]` {
+		t.Errorf("TestRun_TransactionPipe call %+v: %v", payload, err)
+	}
+}
+
+func TestRun_Admin(t *testing.T) {
+	node := rpctest.NewNode(rpctest.Options{AutoMine: true})
+	defer node.Close()
+
+	var created struct {
+		Address string `json:"address"`
+	}
+	if runNeb(t, "", &created, "--node", node.URL(), "admin", "new", "--passphrase", "passphrase") != 0 {
+		t.Fatal("TestRun_Admin new failed")
+	}
+	node.SetBalance(created.Address, big.NewInt(1e18))
+
+	var sent struct {
+		Txhash string `json:"txhash"`
+	}
+	if code := runNeb(t, "", &sent, "--node", node.URL(), "admin", "send", "--from", created.Address, "--to", testTo, "--value", "1", "--nonce", "1"); code != 1 {
+		t.Errorf("TestRun_Admin send with a locked account exit %d", code)
+	}
+	if runNeb(t, "", &sent, "--node", node.URL(), "admin", "send", "--passphrase", "passphrase", "--from", created.Address, "--to", testTo, "--value", "1", "--nonce", "1") != 0 {
+		t.Error("TestRun_Admin send failed")
+	}
+	t.Setenv("NEB_PASSPHRASE", "passphrase")
+	if runNeb(t, "", &sent, "--node", node.URL(), "admin", "send", "--from", created.Address, "--to", testTo, "--value", "1", "--nonce", "2") != 0 {
+		t.Error("TestRun_Admin send with $NEB_PASSPHRASE failed")
+	}
+
+	var signed signedTransaction
+	if runNeb(t, "", &signed, "--node", node.URL(), "admin", "sign", "--passphrase", "passphrase", "--from", created.Address, "--to", testTo, "--value", "1", "--nonce", "2") != 0 || signed.Transaction.Nonce != 2 {
+		t.Errorf("TestRun_Admin sign %+v", signed)
+	}
+}
+
+func TestRun_Bind(t *testing.T) {
+	abi := `{"functions": [{"name": "balanceOf", "args": [{"name": "owner", "type": "address"}], "returns": "bigint", "readonly": true}]}`
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"bind", "--pkg", "token", "--type", "Token", "-"}, strings.NewReader(abi), &stdout, &stderr); code != 0 {
+		t.Fatalf("TestRun_Bind exit %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "func (c *Token) BalanceOf(ctx context.Context, opts *contract.CallOpts, owner string) (*big.Int, error)") {
+		t.Errorf("TestRun_Bind generated %s", stdout.String())
+	}
+
+	out := filepath.Join(t.TempDir(), "token.go")
+	if code := runNeb(t, abi, nil, "bind", "--pkg", "token", "--type", "Token", "--out", out, "-"); code != 0 {
+		t.Fatalf("TestRun_Bind --out exit %d", code)
+	}
+	if code := runNeb(t, `{"functions": [{"name": "_private"}]}`, nil, "bind", "--pkg", "token", "--type", "Token", "-"); code != 1 {
+		t.Errorf("TestRun_Bind invalid interface exit %d", code)
+	}
+	if code := runNeb(t, abi, nil, "bind", "-"); code != 2 {
+		t.Errorf("TestRun_Bind without --pkg exit %d", code)
+	}
+}
+
+func TestRun_EmptyResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":null}`))
+	}))
+	defer server.Close()
+
+	hash := strings.Repeat("ab", 32)
+	for _, args := range [][]string{
+		{"block", "tail"},
+		{"send", "--raw", "CgE="},
+		{"admin", "sign-hash", testTo, hash},
+		{"admin", "sign", "--passphrase", "passphrase", "--from", testTo, "--to", testTo, "--value", "1", "--nonce", "1"},
+	} {
+		var stdout, stderr bytes.Buffer
+		code := run(append([]string{"--node", server.URL}, args...), strings.NewReader(""), &stdout, &stderr)
+		if code != 1 || !strings.Contains(stderr.String(), rpc.ErrEmptyResult.Error()) {
+			t.Errorf("TestRun_EmptyResult neb %v exit %d: %s", args, code, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// namespace is an object of the console, e.g. api, whose methods are the
+// exported methods of a Go value called as name.method(args).
+type namespace struct {
+	name    string
+	summary string
+	methods map[string]*method
+}
+
+// method is a Go method called from the console. Its console name is the Go
+// name starting with a lower case letter, e.g. getNebState.
+type method struct {
+	name  string
+	value reflect.Value
+	// params the parameters after the context, if the method takes one
+	params  []reflect.Type
+	context bool
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// newNamespace returns the namespace of the methods of receiver. Methods with
+// a WithContext variant are called through it, setters, methods taking
+// callbacks and methods without an error result are left out.
+func newNamespace(name string, summary string, receiver interface{}) *namespace {
+	ns := &namespace{name: name, summary: summary, methods: make(map[string]*method)}
+	value := reflect.ValueOf(receiver)
+	valueType := value.Type()
+	for i := 0; i < valueType.NumMethod(); i++ {
+		goName := valueType.Method(i).Name
+		if strings.HasSuffix(goName, "WithContext") || strings.HasPrefix(goName, "Set") {
+			continue
+		}
+		fn := value.Method(i)
+		if withContext := value.MethodByName(goName + "WithContext"); withContext.IsValid() {
+			fn = withContext
+		}
+
+		m := &method{name: lowerFirst(goName), value: fn}
+		if !m.init() {
+			continue
+		}
+		ns.methods[m.name] = m
+	}
+	return ns
+}
+
+// init reads the parameters of the method, it returns false for the methods
+// the console can not call.
+func (m *method) init() bool {
+	fnType := m.value.Type()
+	for i := 0; i < fnType.NumIn(); i++ {
+		param := fnType.In(i)
+		if i == 0 && param == contextType {
+			m.context = true
+			continue
+		}
+		if param.Kind() == reflect.Func || param.Kind() == reflect.Chan || param.Kind() == reflect.Interface {
+			return false
+		}
+		m.params = append(m.params, param)
+	}
+	out := fnType.NumOut()
+	return out > 0 && out <= 2 && fnType.Out(out-1) == errorType
+}
+
+func lowerFirst(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// names returns the sorted method names.
+func (ns *namespace) names() []string {
+	names := make([]string, 0, len(ns.methods))
+	for name := range ns.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// usage returns the call of the method with its parameter names, e.g.
+// getAccountState(address, height).
+func (m *method) usage(ns string) string {
+	var params []string
+	if fields := m.requestFields(); fields != nil {
+		for _, field := range fields {
+			params = append(params, jsonName(field))
+		}
+	} else {
+		for _, param := range m.params {
+			params = append(params, param.String())
+		}
+	}
+	return fmt.Sprintf("%s.%s(%s)", ns, m.name, strings.Join(params, ", "))
+}
+
+// requestFields returns the fields of the request when the method takes a
+// single struct, e.g. GetAccountStateRequest, nil otherwise.
+func (m *method) requestFields() []reflect.StructField {
+	if len(m.params) != 1 {
+		return nil
+	}
+	request := m.params[0]
+	if request.Kind() == reflect.Ptr {
+		request = request.Elem()
+	}
+	if request.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []reflect.StructField
+	for i := 0; i < request.NumField(); i++ {
+		field := request.Field(i)
+		if field.PkgPath == "" && jsonName(field) != "-" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// call calls the method with the json arguments. A method taking a single
+// request struct accepts the request object, or its fields in order, e.g.
+// api.getAccountState("n1...") for {"address": "n1..."}. Missing trailing
+// arguments are zero.
+func (m *method) call(ctx context.Context, args []json.RawMessage) (interface{}, error) {
+	if fields := m.requestFields(); fields != nil && !(len(args) == 1 && isObject(args[0])) {
+		if len(args) > len(fields) {
+			return nil, fmt.Errorf("%s takes at most %d arguments", m.name, len(fields))
+		}
+		request := make(map[string]json.RawMessage)
+		for i, arg := range args {
+			request[jsonName(fields[i])] = arg
+		}
+		object, err := json.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+		args = []json.RawMessage{object}
+	}
+	if len(args) > len(m.params) {
+		return nil, fmt.Errorf("%s takes at most %d arguments", m.name, len(m.params))
+	}
+
+	in := make([]reflect.Value, 0, len(m.params)+1)
+	if m.context {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	for i, param := range m.params {
+		arg := reflect.New(param)
+		if i < len(args) {
+			err := json.Unmarshal(args[i], arg.Interface())
+			if err != nil {
+				return nil, fmt.Errorf("argument %d of %s: %v", i+1, m.name, err)
+			}
+		}
+		in = append(in, arg.Elem())
+	}
+
+	return results(m.value.Call(in))
+}
+
+// results returns the result and error of a method call. Responses are
+// unwrapped to their result.
+func results(out []reflect.Value) (interface{}, error) {
+	var result interface{}
+	for _, value := range out {
+		if value.Type() == errorType {
+			if !value.IsNil() {
+				return nil, value.Interface().(error)
+			}
+			continue
+		}
+		result = value.Interface()
+	}
+
+	value := reflect.ValueOf(result)
+	if value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+		if field := value.Elem().FieldByName("Result"); field.IsValid() && field.Kind() == reflect.Ptr {
+			return field.Interface(), nil
+		}
+	}
+	return result, nil
+}
+
+func isObject(arg json.RawMessage) bool {
+	trimmed := strings.TrimSpace(string(arg))
+	return strings.HasPrefix(trimmed, "{")
+}
+
+// callExpression is ns.method(args), the arguments are json values.
+var callExpression = regexp.MustCompile(`(?s)^([A-Za-z_]\w*)\.([A-Za-z_]\w*)\s*\((.*)\)\s*;?$`)
+
+// errUnknownExpression the console line is not a call
+var errUnknownExpression = errors.New("expected namespace.method(args), e.g. api.getNebState()")
+
+// parseCall splits a console line into its namespace, method and arguments.
+func parseCall(line string) (string, string, []json.RawMessage, error) {
+	match := callExpression.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return "", "", nil, errUnknownExpression
+	}
+
+	var args []json.RawMessage
+	if strings.TrimSpace(match[3]) != "" {
+		err := json.Unmarshal([]byte("["+match[3]+"]"), &args)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("arguments must be json values: %v", err)
+		}
+	}
+	return match[1], match[2], args, nil
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/keystore"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+const (
+	// DefaultGasPrice the gas price of built transactions without --gas-price
+	DefaultGasPrice = 1000000
+
+	// DefaultGasLimit the gas limit of built transactions without --gas-limit,
+	// enough for a transfer
+	DefaultGasLimit = 20000
+
+	// DefaultKeystore the keystore directory of the local accounts, the same
+	// as the node keydir
+	DefaultKeystore = "keydir"
+)
+
+var transactionCommands = []*command{
+	{name: "build", usage: "--chain-id id --from address --to address --nonce n [transaction flags]", summary: "build an unsigned transaction offline", run: runTransactionBuild},
+	{name: "sign", usage: "[key flags] <transaction json|->", summary: "sign a built transaction with a local key", run: runTransactionSign},
+	{name: "decode", usage: "<raw|->", summary: "decode and verify a raw transaction", run: runTransactionDecode},
+}
+
+var transactionCommand = &command{
+	name:    "transaction",
+	usage:   subcommandsUsage(transactionCommands),
+	summary: "build, sign and decode transactions offline",
+	run: func(cli *cli, args []string) error {
+		return subcommands(cli, "transaction", transactionCommands, args)
+	},
+}
+
+// txFlags are the fields of a transaction given on the command line.
+type txFlags struct {
+	from       string
+	to         string
+	value      string
+	nonce      uint64
+	gasPrice   string
+	gasLimit   string
+	function   string
+	args       string
+	source     string
+	sourceType string
+	data       string
+}
+
+func (f *txFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.from, "from", "", "sender address")
+	flags.StringVar(&f.to, "to", "", "receiver or contract address")
+	flags.StringVar(&f.value, "value", "", "amount in wei")
+	flags.Uint64Var(&f.nonce, "nonce", 0, "nonce, the account nonce + 1 from the node when 0")
+	flags.StringVar(&f.gasPrice, "gas-price", "", "gas price in wei, from the node when empty")
+	flags.StringVar(&f.gasLimit, "gas-limit", "", "gas limit, estimated by the node when empty")
+	flags.StringVar(&f.function, "function", "", "contract function to call")
+	flags.StringVar(&f.args, "args", "", "json array of the contract arguments")
+	flags.StringVar(&f.source, "source", "", "file of the contract source to deploy")
+	flags.StringVar(&f.sourceType, "source-type", transaction.SourceTypeJavaScript, "type of the contract source, js or ts")
+	flags.StringVar(&f.data, "data", "", "data of a binary transaction")
+}
+
+// contract returns the payload of the flags, reading the contract source
+// file.
+func (f *txFlags) contract() (*transaction.Contract, error) {
+	contract := &transaction.Contract{Function: f.function, Args: f.args}
+	if f.data != "" {
+		contract.Binary = []byte(f.data)
+	}
+	if f.source != "" {
+		source, err := ioutil.ReadFile(f.source)
+		if err != nil {
+			return nil, err
+		}
+		contract.Source = string(source)
+		contract.SourceType = f.sourceType
+	}
+	return contract, nil
+}
+
+// request returns the flags as the transaction of Call and EstimateGas.
+func (f *txFlags) request() (rpc.TransactionRequest, error) {
+	if f.from == "" || f.to == "" {
+		return rpc.TransactionRequest{}, fmt.Errorf("%w: --from and --to are required", errUsage)
+	}
+	req, err := f.transactionRequest()
+	if req.Value == "" {
+		req.Value = "0"
+	}
+	return req, err
+}
+
+func (f *txFlags) transactionRequest() (rpc.TransactionRequest, error) {
+	contract, err := f.contract()
+	if err != nil {
+		return rpc.TransactionRequest{}, err
+	}
+
+	req := rpc.TransactionRequest{
+		From:     f.from,
+		To:       f.to,
+		Value:    f.value,
+		Nonce:    f.nonce,
+		GasPrice: f.gasPrice,
+		GasLimit: f.gasLimit,
+		Binary:   contract.Binary,
+	}
+	if contract.Function != "" || contract.Source != "" {
+		req.Contract = &rpc.ContractRequest{
+			Function:   contract.Function,
+			Args:       contract.Args,
+			Source:     contract.Source,
+			SourceType: contract.SourceType,
+		}
+	}
+	return req, nil
+}
+
+// options returns the flags as the options of a TransactionBuilder, the
+// fields that are not given are nil or 0. From is left to the caller.
+func (f *txFlags) options(cli *cli) (transaction.TransactionOptions, error) {
+	if f.to == "" {
+		return transaction.TransactionOptions{}, fmt.Errorf("%w: --to is required", errUsage)
+	}
+	req, err := f.transactionRequest()
+	if err != nil {
+		return transaction.TransactionOptions{}, err
+	}
+	return requestOptions(&req, uint32(cli.chainID))
+}
+
+// requestOptions converts req to the options of a TransactionBuilder, the
+// fields that are not given are nil or 0. From is left to the caller.
+func requestOptions(req *rpc.TransactionRequest, chainID uint32) (transaction.TransactionOptions, error) {
+	if !account.IsValidAddress(req.To) {
+		return transaction.TransactionOptions{}, fmt.Errorf("invalid to address %s", req.To)
+	}
+
+	opts := transaction.TransactionOptions{ChainID: chainID, To: req.To, Nonce: req.Nonce}
+	var err error
+	if opts.Value, err = parseAmount("value", req.Value); err != nil {
+		return opts, err
+	}
+	if opts.GasPrice, err = parseAmount("gas price", req.GasPrice); err != nil {
+		return opts, err
+	}
+	if opts.GasLimit, err = parseAmount("gas limit", req.GasLimit); err != nil {
+		return opts, err
+	}
+	opts.Contract = &transaction.Contract{Binary: req.Binary}
+	if req.Contract != nil {
+		opts.Contract.Function = req.Contract.Function
+		opts.Contract.Args = req.Contract.Args
+		opts.Contract.Source = req.Contract.Source
+		opts.Contract.SourceType = req.Contract.SourceType
+	}
+	return opts, nil
+}
+
+// parseAmount parses a decimal amount, nil when value is empty.
+func parseAmount(name string, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid %s %s", name, value)
+	}
+	return amount, nil
+}
+
+// keyFlags select the local key that signs a transaction.
+type keyFlags struct {
+	keystore   string
+	keyfile    string
+	passphrase func() string
+}
+
+func (f *keyFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.keystore, "keystore", DefaultKeystore, "keystore directory of the from account")
+	flags.StringVar(&f.keyfile, "keyfile", "", "keystore file of the from account, instead of --keystore")
+	f.passphrase = passphraseFlag(flags, "passphrase of the key")
+}
+
+// account decrypts the key of address, which may be empty with --keyfile.
+func (f *keyFlags) account(address string) (*account.Account, error) {
+	var keyjson string
+	switch {
+	case f.keyfile != "":
+		data, err := ioutil.ReadFile(f.keyfile)
+		if err != nil {
+			return nil, err
+		}
+		keyjson = string(data)
+	case address == "":
+		return nil, fmt.Errorf("%w: --from or --keyfile is required", errUsage)
+	default:
+		if _, err := os.Stat(f.keystore); err != nil {
+			return nil, err
+		}
+		ks, err := keystore.NewKeystore(f.keystore, nil)
+		if err != nil {
+			return nil, err
+		}
+		keyjson, err = ks.Export(address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	acc, err := account.NewAccount().FromKey(keyjson, f.passphrase(), false)
+	if err != nil {
+		return nil, err
+	}
+	if address != "" && acc.GetAddressString() != address {
+		return nil, fmt.Errorf("the key of %s can not sign for %s", acc.GetAddressString(), address)
+	}
+	return acc, nil
+}
+
+// runTransactionBuild prints an unsigned transaction without querying the
+// node, so it can be signed on another machine.
+func runTransactionBuild(cli *cli, args []string) error {
+	flags := cli.flagSet("transaction build")
+	tx := new(txFlags)
+	tx.register(flags)
+	if _, err := parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+	if cli.chainID == 0 || tx.nonce == 0 {
+		return fmt.Errorf("%w: --chain-id and --nonce are required offline", errUsage)
+	}
+
+	opts, err := tx.options(cli)
+	if err != nil {
+		return err
+	}
+	opts.From, err = account.FromAddress(tx.from)
+	if err != nil {
+		return fmt.Errorf("invalid from address %s", tx.from)
+	}
+	if opts.Value == nil {
+		opts.Value = big.NewInt(0)
+	}
+	if opts.GasPrice == nil {
+		opts.GasPrice = big.NewInt(DefaultGasPrice)
+	}
+	if opts.GasLimit == nil {
+		opts.GasLimit = big.NewInt(DefaultGasLimit)
+	}
+	return cli.print(transaction.NewTransaction(opts))
+}
+
+// signedTransaction is the output of transaction sign.
+type signedTransaction struct {
+	Hash string `json:"hash"`
+	// Raw the data of send --raw
+	Raw         string                   `json:"raw"`
+	Transaction *transaction.Transaction `json:"transaction"`
+}
+
+func runTransactionSign(cli *cli, args []string) error {
+	flags := cli.flagSet("transaction sign")
+	key := new(keyFlags)
+	key.register(flags)
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	input, err := cli.input(rest[0])
+	if err != nil {
+		return err
+	}
+
+	tx, err := new(transaction.Transaction).FromString(input)
+	if err != nil {
+		return fmt.Errorf("invalid transaction: %v", err)
+	}
+	tx.From, err = key.account(tx.From.GetAddressString())
+	if err != nil {
+		return err
+	}
+	err = tx.SignTransaction()
+	if err != nil {
+		return err
+	}
+	raw, err := tx.ToProtoString()
+	if err != nil {
+		return err
+	}
+	return cli.print(&signedTransaction{Hash: hex.EncodeToString(tx.Hash), Raw: raw, Transaction: tx})
+}
+
+// decodedTransaction is the output of transaction decode.
+type decodedTransaction struct {
+	Transaction *transaction.Transaction `json:"transaction"`
+	Verified    bool                     `json:"verified"`
+	VerifyError string                   `json:"verify_error,omitempty"`
+}
+
+func runTransactionDecode(cli *cli, args []string) error {
+	flags := cli.flagSet("transaction decode")
+	rest, err := parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	input, err := cli.input(rest[0])
+	if err != nil {
+		return err
+	}
+
+	decoded, err := decodeTransaction(input)
+	if err != nil {
+		return err
+	}
+	return cli.print(decoded)
+}
+
+// decodeTransaction decodes and verifies a raw transaction.
+func decodeTransaction(raw string) (*decodedTransaction, error) {
+	tx, err := new(transaction.Transaction).FromProto(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid raw transaction: %v", err)
+	}
+	decoded := &decodedTransaction{Transaction: tx, Verified: true}
+	if err := tx.Verify(); err != nil {
+		decoded.Verified = false
+		decoded.VerifyError = err.Error()
+	}
+	return decoded, nil
+}
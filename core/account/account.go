@@ -6,6 +6,8 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"errors"
+	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
@@ -122,6 +124,22 @@ func FromAddress(address string) (*Account, error) {
 	return nil, errors.New("invalid address")
 }
 
+// FromPublicKey returns an account with the public key and address of an
+// uncompressed secp256k1 public key, with or without the 0x04 prefix.
+func FromPublicKey(publicKey []byte) (*Account, error) {
+	if len(publicKey) == 65 && publicKey[0] == UncompressedPublicKeyPrefix {
+		publicKey = publicKey[1:]
+	}
+	if len(publicKey) != 64 {
+		return nil, errors.New("invalid public key")
+	}
+
+	acc := Account{}
+	acc.PublicKey = publicKey
+	acc.Address = addressFromPublicKey(publicKey)
+	return &acc, nil
+}
+
 func (acc *Account) SetPrivateKey(privateKey []byte) {
 	acc.PrivateKey = privateKey
 	acc.PublicKey = privateToPublicKey(privateKey)
@@ -153,11 +171,20 @@ func (acc *Account) GetAddressString() string {
 }
 
 func (acc *Account) ToKey(password string, opts *KeyOptions) (*Key, error) {
+	cipher := utils.GetStringWithDefault(opts.Cipher, "aes-128-ctr")
+	spec, ok := keyCiphers[cipher]
+	if !ok {
+		return nil, &KeyError{"crypto.cipher", cipher + " is not supported"}
+	}
+
 	salt := utils.GetBytesWithDefault(opts.Salt, utils.RandomCSPRNG(32))
-	iv := utils.GetBytesWithDefault(opts.Iv, utils.RandomCSPRNG(16))
+	iv := utils.GetBytesWithDefault(opts.Iv, utils.RandomCSPRNG(spec.ivLength))
 	kdf := utils.GetStringWithDefault(opts.Kdf, "scrypt")
 
-	dklen := utils.GetIntWithDefault(opts.Dklen, 32)
+	dklen := utils.GetIntWithDefault(opts.Dklen, spec.dklen)
+	if err := spec.checkDklen(dklen); err != nil {
+		return nil, err
+	}
 	kdfparams := KdfParams{Dklen: dklen, Salt: hex.EncodeToString(salt)}
 
 	var derivedKey []byte
@@ -172,19 +199,27 @@ func (acc *Account) ToKey(password string, opts *KeyOptions) (*Key, error) {
 		kdfparams.N = utils.GetIntWithDefault(opts.N, 4096)
 		kdfparams.R = utils.GetIntWithDefault(opts.R, 8)
 		kdfparams.P = utils.GetIntWithDefault(opts.P, 1)
-		derivedKey, _ = scrypt.Key([]byte(password), salt, kdfparams.N, kdfparams.R, kdfparams.P, kdfparams.Dklen)
+		var err error
+		derivedKey, err = scrypt.Key([]byte(password), salt, kdfparams.N, kdfparams.R, kdfparams.P, kdfparams.Dklen)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		return nil, errors.New("unsupported kdf")
 	}
 
-	cipher := utils.GetStringWithDefault(opts.Cipher, "aes-128-ctr")
+	// keys with leading zero bytes are stored with all 32 bytes
+	privateKey := acc.PrivateKey
+	for len(privateKey) < 32 {
+		privateKey = append([]byte{0x00}, privateKey...)
+	}
 
-	ciphertext, err := utils.OpensslEncrypt(acc.PrivateKey, cipher, derivedKey[0:16], iv)
+	ciphertext, err := encryptKey(cipher, spec.encryptionKey(derivedKey), iv, privateKey)
 	if err != nil {
 		return nil, err
 	}
 
-	maccontent := append(derivedKey[16:32], ciphertext...)
+	maccontent := append(macKey(derivedKey), ciphertext...)
 	maccontent = append(maccontent, iv...)
 	maccontent = append(maccontent, []byte(cipher)...)
 
@@ -223,6 +258,9 @@ func (acc *Account) ToKeyString(password string, opts *KeyOptions) (string, erro
 	return string(jsonbytes), nil
 }
 
+// FromKey decrypts a keystore json. In strict mode, nonStrict false, every
+// field is validated first with Key.Validate and the address must match the
+// decrypted key. Invalid fields are reported as *KeyError.
 func (acc *Account) FromKey(input string, password string, nonStrict bool) (*Account, error) {
 	key := Key{}
 	err := json.Unmarshal([]byte(input), &key)
@@ -230,16 +268,43 @@ func (acc *Account) FromKey(input string, password string, nonStrict bool) (*Acc
 		return nil, err
 	}
 
+	if !nonStrict {
+		err = key.Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
 	if key.Version != KeyVersion3 && key.Version != KeyCurrentVersion {
 		return nil, errors.New("not supported wallet version")
 	}
 
 	kdfparams := key.Crypto.Kdfparams
+	salt, err := decodeHex("crypto.kdfparams.salt", kdfparams.Salt, nonStrict)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := decodeHex("crypto.cipherparams.iv", key.Crypto.Cipherparams.Iv, nonStrict)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := decodeHex("crypto.ciphertext", key.Crypto.Ciphertext, nonStrict)
+	if err != nil {
+		return nil, err
+	}
+	spec, ok := keyCiphers[key.Crypto.Cipher]
+	if !ok {
+		// non strict mode leaves unknown ciphers to openssl
+		spec = keyCipher{keyLength: 16, dklen: 32}
+	}
+	err = spec.checkDklen(kdfparams.Dklen)
+	if err != nil {
+		return nil, err
+	}
 
 	var derivedKey []byte
 
 	if key.Crypto.Kdf == "scrypt" {
-		derivedKey, err = scrypt.Key([]byte(password), utils.BytesFromHexString(kdfparams.Salt), kdfparams.N, kdfparams.R, kdfparams.P, kdfparams.Dklen)
+		derivedKey, err = scrypt.Key([]byte(password), salt, kdfparams.N, kdfparams.R, kdfparams.P, kdfparams.Dklen)
 		if err != nil {
 			return nil, err
 		}
@@ -247,32 +312,39 @@ func (acc *Account) FromKey(input string, password string, nonStrict bool) (*Acc
 		if kdfparams.Prf != "hmac-sha256" {
 			return nil, errors.New("unsupported parameters to PBKDF2")
 		}
-		derivedKey = pbkdf2.Key([]byte(password), utils.BytesFromHexString(kdfparams.Salt), kdfparams.C, kdfparams.Dklen, sha256.New)
+		if kdfparams.C < 1 {
+			return nil, &KeyError{"crypto.kdfparams.c", fmt.Sprintf("%d is less than 1", kdfparams.C)}
+		}
+		derivedKey = pbkdf2.Key([]byte(password), salt, kdfparams.C, kdfparams.Dklen, sha256.New)
 	} else {
 		return nil, errors.New("unsupported key derivation scheme")
 	}
 
-	ciphertext := utils.BytesFromHexString(key.Crypto.Ciphertext)
-
-	maccontent := append(derivedKey[16:32], ciphertext...)
+	maccontent := append(macKey(derivedKey), ciphertext...)
 	if key.Version == KeyCurrentVersion {
-		maccontent = append(maccontent, utils.BytesFromHexString(key.Crypto.Cipherparams.Iv)...)
+		maccontent = append(maccontent, iv...)
 		maccontent = append(maccontent, []byte(key.Crypto.Cipher)...)
 	}
 
 	mac := hash.Sha3256(maccontent)
 
-	if hex.EncodeToString(mac) != key.Crypto.Mac {
-		return nil, errors.New("key derivation failed - possibly wrong passphrase")
+	if hex.EncodeToString(mac) != strings.ToLower(key.Crypto.Mac) {
+		return nil, ErrWrongPassphrase
 	}
 
-	seed, _ := utils.OpensslDecrypt(ciphertext, key.Crypto.Cipher, derivedKey[0:16], utils.BytesFromHexString(key.Crypto.Cipherparams.Iv))
+	seed, err := decryptKey(key.Crypto.Cipher, spec.encryptionKey(derivedKey), iv, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyDecryptFailed, err)
+	}
 
 	for len(seed) < 32 {
 		seed = append([]byte{0x00}, seed...)
 	}
 
 	acc.SetPrivateKey(seed)
+	if !nonStrict && acc.GetAddressString() != key.Address {
+		return nil, &KeyError{"address", "does not match the decrypted key"}
+	}
 	return acc, nil
 }
 
@@ -2,7 +2,10 @@ package account
 
 import (
 	"testing"
+	"encoding/json"
+	"errors"
 	"math/big"
+	"strings"
 	"github.com/satori/go.uuid"
 )
 
@@ -90,3 +93,152 @@ func TestAccount_FromKey(t *testing.T) {
 	}
 
 }
+
+func TestAccount_FromKeyStrict(t *testing.T) {
+	keyjson := `{"version":4,"id":"0c560f8c-4c0e-4584-9a26-fb57d1dfd0e5","address":"n1LfrjZzXDCcHhNV2r6F6dUS5Zxi7P8xC45","crypto":{"ciphertext":"f49c508fea24f211b708eced749e57756af046207e896f4fb5332d664d33d531","cipherparams":{"iv":"e90bd4e307153dc61632ce428606620c"},"cipher":"aes-128-ctr","kdf":"scrypt","kdfparams":{"dklen":32,"salt":"acded3c4c3f7655cbcd38eea82fdc939251354f21c14cd7faec05c73aa9f70e3","n":4096,"r":8,"p":1,"c":0,"prf":""},"mac":"b87684d55dda7a4f76dcf3f30c98ac42f1958e6e4a9b80d63276aeb6c41a93d7","machash":"sha3256"}}`
+
+	_, err := NewAccount().FromKey(keyjson, "password0192837465DlK", false)
+	if err != nil {
+		t.Error("TestAccount_FromKeyStrict failed:", err)
+	}
+
+	_, err = NewAccount().FromKey(keyjson, "wrong", false)
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Error("TestAccount_FromKeyStrict wrong passphrase:", err)
+	}
+
+	invalid := map[string]string{
+		"version":                strings.Replace(keyjson, `"version":4`, `"version":2`, 1),
+		"id":                     strings.Replace(keyjson, `"0c560f8c-4c0e-4584-9a26-fb57d1dfd0e5"`, `"0c560f8c"`, 1),
+		"address":                strings.Replace(keyjson, "n1LfrjZzXDCcHhNV2r6F6dUS5Zxi7P8xC45", "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17", 1),
+		"crypto.cipher":          strings.Replace(keyjson, `"aes-128-ctr"`, `"des"`, 1),
+		"crypto.cipherparams.iv": strings.Replace(keyjson, `"e90bd4e307153dc61632ce428606620c"`, `"e90bd4e307153dc61632ce42860662"`, 1),
+		"crypto.kdfparams.n":     strings.Replace(keyjson, `"n":4096`, `"n":4000`, 1),
+		"crypto.kdfparams.dklen": strings.Replace(keyjson, `"dklen":32`, `"dklen":16`, 1),
+		"crypto.mac":             strings.Replace(keyjson, `"b87684d55dda`, `"x87684d55dda`, 1),
+	}
+	for field, input := range invalid {
+		_, err := NewAccount().FromKey(input, "password0192837465DlK", false)
+		keyErr, ok := err.(*KeyError)
+		if !ok || keyErr.Field != field || !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("TestAccount_FromKeyStrict %s: %v", field, err)
+		}
+	}
+
+	// a short dklen is an error in non strict mode too, not a panic
+	_, err = NewAccount().FromKey(invalid["crypto.kdfparams.dklen"], "password0192837465DlK", true)
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Error("TestAccount_FromKeyStrict dklen:", err)
+	}
+}
+
+func TestAccount_FromKeyLeadingZeros(t *testing.T) {
+	acc := NewAccount()
+	salt := make([]byte, 32)
+	iv := make([]byte, 16)
+	salt[31], iv[15] = 1, 1
+
+	keyjson, err := acc.ToKeyString("passphrase", &KeyOptions{Salt: salt, Iv: iv, N: 1024})
+	if err != nil {
+		t.Fatal("TestAccount_FromKeyLeadingZeros failed:", err)
+	}
+	for _, nonStrict := range []bool{false, true} {
+		fromAcc, err := NewAccount().FromKey(keyjson, "passphrase", nonStrict)
+		if err != nil || fromAcc.GetAddressString() != acc.GetAddressString() {
+			t.Errorf("TestAccount_FromKeyLeadingZeros nonStrict %v failed: %v", nonStrict, err)
+		}
+	}
+}
+
+func TestAccount_ToKeyCiphers(t *testing.T) {
+	acc := NewAccount()
+	for _, cipher := range []string{"aes-128-ctr", "aes-128-cbc", "aes-256-ctr", "aes-256-gcm"} {
+		keyjson, err := acc.ToKeyString("passphrase", &KeyOptions{N: 1024, Cipher: cipher})
+		if err != nil {
+			t.Fatalf("TestAccount_ToKeyCiphers %s failed: %s", cipher, err)
+		}
+
+		fromAcc, err := NewAccount().FromKey(keyjson, "passphrase", false)
+		if err != nil || fromAcc.GetPrivateKeyString() != acc.GetPrivateKeyString() {
+			t.Errorf("TestAccount_ToKeyCiphers %s failed: %v", cipher, err)
+		}
+		if _, err := NewAccount().FromKey(keyjson, "wrong", false); !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("TestAccount_ToKeyCiphers %s wrong passphrase: %v", cipher, err)
+		}
+	}
+
+	if _, err := acc.ToKeyString("passphrase", &KeyOptions{Cipher: "des-ede3-cbc"}); !errors.Is(err, ErrInvalidKey) {
+		t.Error("TestAccount_ToKeyCiphers accepted an unsupported cipher:", err)
+	}
+	if _, err := acc.ToKeyString("passphrase", &KeyOptions{Cipher: "aes-256-ctr", Dklen: 32}); !errors.Is(err, ErrInvalidKey) {
+		t.Error("TestAccount_ToKeyCiphers accepted a derived key too short for aes-256:", err)
+	}
+}
+
+func TestReencryptKey(t *testing.T) {
+	acc := NewAccount()
+	keyjson, _ := acc.ToKeyString("passphrase", &KeyOptions{N: 1024})
+
+	profile, err := KeyProfile("light")
+	if err != nil {
+		t.Fatal("TestReencryptKey failed:", err)
+	}
+	profile.Cipher = "aes-256-gcm"
+	upgraded, err := ReencryptKey(keyjson, "passphrase", "new", profile)
+	if err != nil {
+		t.Fatal("TestReencryptKey failed:", err)
+	}
+
+	key := Key{}
+	json.Unmarshal([]byte(upgraded), &key)
+	oldKey := Key{}
+	json.Unmarshal([]byte(keyjson), &oldKey)
+	if key.Crypto.Cipher != "aes-256-gcm" || key.Crypto.Kdfparams.N != KeyProfileLight.N || key.Id != oldKey.Id {
+		t.Errorf("TestReencryptKey upgraded key %+v", key)
+	}
+
+	fromAcc, err := NewAccount().FromKey(upgraded, "new", false)
+	if err != nil || fromAcc.GetAddressString() != acc.GetAddressString() {
+		t.Error("TestReencryptKey failed to decrypt the upgraded key:", err)
+	}
+
+	if _, err := KeyProfile("unknown"); err == nil {
+		t.Error("TestReencryptKey returned an unknown profile")
+	}
+}
+
+func TestAccount_KeyDklen(t *testing.T) {
+	acc := NewAccount()
+	for _, cipher := range []string{"aes-128-ctr", "aes-256-gcm"} {
+		// a derived key longer than the cipher uses is valid in both modes
+		key, err := acc.ToKey("passphrase", &KeyOptions{Cipher: cipher, Dklen: maxKeyDklen, N: 1024})
+		if err != nil {
+			t.Fatalf("TestAccount_KeyDklen %s failed: %v", cipher, err)
+		}
+		if err := key.Validate(); err != nil {
+			t.Errorf("TestAccount_KeyDklen %s validate: %v", cipher, err)
+		}
+		keyjson, _ := json.Marshal(key)
+		for _, nonStrict := range []bool{false, true} {
+			fromAcc, err := NewAccount().FromKey(string(keyjson), "passphrase", nonStrict)
+			if err != nil || fromAcc.GetPrivateKeyString() != acc.GetPrivateKeyString() {
+				t.Errorf("TestAccount_KeyDklen %s non strict %v: %v", cipher, nonStrict, err)
+			}
+		}
+
+		// too long, or too short for the cipher, is invalid in all three
+		for _, dklen := range []int{maxKeyDklen + 1, keyCiphers[cipher].dklen - 1} {
+			if _, err := acc.ToKey("passphrase", &KeyOptions{Cipher: cipher, Dklen: dklen, N: 1024}); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("TestAccount_KeyDklen %s ToKey accepted dklen %d: %v", cipher, dklen, err)
+			}
+			key.Crypto.Kdfparams.Dklen = dklen
+			if err := key.Validate(); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("TestAccount_KeyDklen %s Validate accepted dklen %d: %v", cipher, dklen, err)
+			}
+			keyjson, _ := json.Marshal(key)
+			if _, err := NewAccount().FromKey(string(keyjson), "passphrase", true); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("TestAccount_KeyDklen %s FromKey accepted dklen %d: %v", cipher, dklen, err)
+			}
+		}
+	}
+}
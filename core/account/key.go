@@ -0,0 +1,274 @@
+package account
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/vigozhang/neb-go/utils"
+)
+
+const (
+	// keystore field lengths in bytes
+	keySaltLength = 32
+	keyMacLength  = 32
+
+	maxScryptN  = 1 << 20
+	maxPbkdf2C  = 1 << 24
+	maxScryptRP = 1 << 30
+	maxKeyDklen = 128
+)
+
+var (
+	// ErrInvalidKey the keystore json has an invalid field, see KeyError
+	ErrInvalidKey = errors.New("invalid keystore")
+
+	// ErrWrongPassphrase the mac does not match, most likely the passphrase is wrong
+	ErrWrongPassphrase = errors.New("key derivation failed - possibly wrong passphrase")
+
+	// ErrKeyDecryptFailed the ciphertext can not be decrypted
+	ErrKeyDecryptFailed = errors.New("keystore decryption failed")
+)
+
+// keyCipher the lengths in bytes of a keystore cipher. The derived key is
+// split into the encryption key, bytes 0 to 16 for aes-128 and 32 to 64 for
+// aes-256, and the mac key, bytes 16 to 32.
+type keyCipher struct {
+	keyLength        int
+	ivLength         int
+	dklen            int
+	ciphertextLength int
+}
+
+// keyCiphers the ciphers keystores are encrypted with, the ciphertext of cbc
+// has a block of padding and the one of gcm the 16 bytes tag.
+var keyCiphers = map[string]keyCipher{
+	"aes-128-ctr": {keyLength: 16, ivLength: 16, dklen: 32, ciphertextLength: 32},
+	"aes-128-cbc": {keyLength: 16, ivLength: 16, dklen: 32, ciphertextLength: 48},
+	"aes-256-ctr": {keyLength: 32, ivLength: 16, dklen: 64, ciphertextLength: 32},
+	"aes-256-gcm": {keyLength: 32, ivLength: 12, dklen: 64, ciphertextLength: 48},
+}
+
+// checkDklen the derived key must hold the encryption and mac keys, keystores
+// of other wallets may derive more bytes than the cipher uses.
+func (spec keyCipher) checkDklen(dklen int) error {
+	if dklen < spec.dklen || dklen > maxKeyDklen {
+		return &KeyError{"crypto.kdfparams.dklen", fmt.Sprintf("%d is not between %d and %d", dklen, spec.dklen, maxKeyDklen)}
+	}
+	return nil
+}
+
+func (spec keyCipher) encryptionKey(derivedKey []byte) []byte {
+	if spec.keyLength == 16 {
+		return derivedKey[0:16]
+	}
+	return derivedKey[32 : 32+spec.keyLength]
+}
+
+// macKey returns a copy of the mac key, so appending to it leaves the
+// derived key alone.
+func macKey(derivedKey []byte) []byte {
+	return append([]byte{}, derivedKey[16:32]...)
+}
+
+// encryptKey encrypts the private key, with openssl except for gcm which
+// openssl only supports with a separate tag.
+func encryptKey(cipherName string, key []byte, iv []byte, plaintext []byte) ([]byte, error) {
+	if cipherName == "aes-256-gcm" {
+		aead, err := newGCM(key, iv)
+		if err != nil {
+			return nil, err
+		}
+		return aead.Seal(nil, iv, plaintext, nil), nil
+	}
+	return utils.OpensslEncrypt(plaintext, cipherName, key, iv)
+}
+
+func decryptKey(cipherName string, key []byte, iv []byte, ciphertext []byte) ([]byte, error) {
+	if cipherName == "aes-256-gcm" {
+		aead, err := newGCM(key, iv)
+		if err != nil {
+			return nil, err
+		}
+		return aead.Open(nil, iv, ciphertext, nil)
+	}
+	return utils.OpensslDecrypt(ciphertext, cipherName, key, iv)
+}
+
+func newGCM(key []byte, iv []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, len(iv))
+}
+
+var uuidRegexp = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+// KDF profiles for ToKey and ReencryptKey, from fast to unlock to hard to
+// brute force. Scrypt needs 128 * N * R bytes of memory: 4MB for light,
+// 256MB for standard and 1GB for paranoid.
+var (
+	// KeyProfileLight the ToKey defaults, for tests and short lived accounts
+	KeyProfileLight = KeyOptions{Kdf: "scrypt", N: 1 << 12, R: 8, P: 1, Cipher: "aes-128-ctr"}
+
+	// KeyProfileStandard the scrypt parameters of the ethereum keystores
+	KeyProfileStandard = KeyOptions{Kdf: "scrypt", N: 1 << 18, R: 8, P: 1, Cipher: "aes-128-ctr"}
+
+	// KeyProfileParanoid the highest scrypt N strict mode accepts and aes-256
+	KeyProfileParanoid = KeyOptions{Kdf: "scrypt", N: maxScryptN, R: 8, P: 1, Cipher: "aes-256-gcm"}
+)
+
+// KeyProfile returns a copy of the options of the light, standard or paranoid
+// profile.
+func KeyProfile(name string) (*KeyOptions, error) {
+	var options KeyOptions
+	switch name {
+	case "light":
+		options = KeyProfileLight
+	case "standard":
+		options = KeyProfileStandard
+	case "paranoid":
+		options = KeyProfileParanoid
+	default:
+		return nil, errors.New("unknown key profile " + name)
+	}
+	return &options, nil
+}
+
+// ReencryptKey decrypts a keystore json with passphrase and encrypts it again
+// with newPassphrase and opts, e.g. to upgrade it to a stronger profile. The
+// keystore is read in non strict mode, so legacy keyfiles can be upgraded.
+// The key id is kept.
+func ReencryptKey(keyjson string, passphrase string, newPassphrase string, opts *KeyOptions) (string, error) {
+	acc, err := NewAccount().FromKey(keyjson, passphrase, true)
+	if err != nil {
+		return "", err
+	}
+
+	var options KeyOptions
+	if opts != nil {
+		options = *opts
+	}
+	options.Salt = nil
+	options.Iv = nil
+	key := Key{}
+	json.Unmarshal([]byte(keyjson), &key)
+	if options.Uuid == nil && uuidRegexp.MatchString(key.Id) {
+		options.Uuid, _ = hex.DecodeString(strings.Replace(key.Id, "-", "", -1))
+	}
+	return acc.ToKeyString(newPassphrase, &options)
+}
+
+// KeyError reports the keystore field that failed the validation, it matches
+// ErrInvalidKey with errors.Is.
+type KeyError struct {
+	Field  string
+	Reason string
+}
+
+func (e *KeyError) Error() string {
+	return "invalid keystore " + e.Field + ": " + e.Reason
+}
+
+func (e *KeyError) Is(target error) bool {
+	return target == ErrInvalidKey
+}
+
+// Validate checks every field of the keystore, as FromKey does in strict
+// mode. It does not need the passphrase.
+func (key *Key) Validate() error {
+	if key.Version != KeyVersion3 && key.Version != KeyCurrentVersion {
+		return &KeyError{"version", fmt.Sprintf("%d is not supported", key.Version)}
+	}
+	if !uuidRegexp.MatchString(key.Id) {
+		return &KeyError{"id", "is not a uuid"}
+	}
+	if !IsValidAddress(key.Address) {
+		return &KeyError{"address", "is not a valid address"}
+	}
+
+	crypto := key.Crypto
+	spec, ok := keyCiphers[crypto.Cipher]
+	if !ok {
+		return &KeyError{"crypto.cipher", crypto.Cipher + " is not supported"}
+	}
+	err := checkHex("crypto.ciphertext", crypto.Ciphertext, spec.ciphertextLength)
+	if err != nil {
+		return err
+	}
+	err = checkHex("crypto.cipherparams.iv", crypto.Cipherparams.Iv, spec.ivLength)
+	if err != nil {
+		return err
+	}
+	err = checkHex("crypto.mac", crypto.Mac, keyMacLength)
+	if err != nil {
+		return err
+	}
+	if crypto.Machash != "sha3256" && !(key.Version == KeyVersion3 && crypto.Machash == "") {
+		return &KeyError{"crypto.machash", crypto.Machash + " is not supported"}
+	}
+
+	params := crypto.Kdfparams
+	err = checkHex("crypto.kdfparams.salt", params.Salt, keySaltLength)
+	if err != nil {
+		return err
+	}
+	err = spec.checkDklen(params.Dklen)
+	if err != nil {
+		return err
+	}
+
+	switch crypto.Kdf {
+	case "scrypt":
+		if params.N < 2 || params.N > maxScryptN || params.N&(params.N-1) != 0 {
+			return &KeyError{"crypto.kdfparams.n", fmt.Sprintf("%d is not a power of 2 up to %d", params.N, maxScryptN)}
+		}
+		if params.R < 1 || params.P < 1 || params.R*params.P >= maxScryptRP {
+			return &KeyError{"crypto.kdfparams.r", fmt.Sprintf("r %d and p %d are out of range", params.R, params.P)}
+		}
+	case "pbkdf2":
+		if params.C < 1 || params.C > maxPbkdf2C {
+			return &KeyError{"crypto.kdfparams.c", fmt.Sprintf("%d is not between 1 and %d", params.C, maxPbkdf2C)}
+		}
+		if params.Prf != "hmac-sha256" {
+			return &KeyError{"crypto.kdfparams.prf", params.Prf + " is not supported"}
+		}
+	default:
+		return &KeyError{"crypto.kdf", crypto.Kdf + " is not supported"}
+	}
+	return nil
+}
+
+func checkHex(field string, value string, length int) error {
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		return &KeyError{field, "is not hex"}
+	}
+	if len(data) != length {
+		return &KeyError{field, fmt.Sprintf("has %d bytes, not %d", len(data), length)}
+	}
+	return nil
+}
+
+// decodeHex decodes a hex field of the keystore, keeping its leading zero
+// bytes. In non strict mode values hex.DecodeString rejects, e.g. of odd
+// length, are read as a number.
+func decodeHex(field string, value string, nonStrict bool) ([]byte, error) {
+	data, err := hex.DecodeString(value)
+	if err == nil {
+		return data, nil
+	}
+	if nonStrict {
+		if number, ok := new(big.Int).SetString(value, 16); ok {
+			return number.Bytes(), nil
+		}
+	}
+	return nil, &KeyError{field, "is not hex"}
+}
@@ -0,0 +1,156 @@
+// Package contract binds the functions of a deployed NVM contract to typed Go
+// methods. Bind generates the binding from an interface description of the
+// contract, the generated code calls the readonly functions with Api.Call and
+// sends the other ones as signed call transactions through a BoundContract.
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The argument and return types of an interface description. A type followed
+// by [] is an array, e.g. string[], arrays of arrays are objects.
+const (
+	TypeString  = "string"
+	TypeAddress = "address"
+	TypeBool    = "bool"
+	// TypeInt and TypeUint json numbers of 32 bits, which the contract reads
+	// as doubles without losing precision. Larger integers are bigint.
+	TypeInt    = "int"
+	TypeUint   = "uint"
+	TypeNumber = "number"
+	// TypeBigInt a BigNumber of the contract, sent and returned as a decimal
+	// string.
+	TypeBigInt = "bigint"
+	// TypeObject any json value, e.g. an object.
+	TypeObject = "object"
+)
+
+// goTypes maps the interface types to the Go types of the binding.
+var goTypes = map[string]string{
+	TypeString:  "string",
+	TypeAddress: "string",
+	TypeBool:    "bool",
+	TypeInt:     "int32",
+	TypeUint:    "uint32",
+	TypeNumber:  "float64",
+	TypeBigInt:  "*big.Int",
+	TypeObject:  "json.RawMessage",
+}
+
+var (
+	// ErrInvalidABI the interface description can not be bound
+	ErrInvalidABI = errors.New("invalid contract interface")
+)
+
+// ABI is the interface description of a contract, e.g.
+//
+//	{"functions": [
+//		{"name": "balanceOf", "args": [{"name": "owner", "type": "address"}], "returns": "bigint", "readonly": true},
+//		{"name": "transfer", "args": [{"name": "to", "type": "address"}, {"name": "value", "type": "bigint"}]}
+//	]}
+type ABI struct {
+	Functions []Function `json:"functions"`
+}
+
+// Function is a function of the contract.
+type Function struct {
+	Name string `json:"name"`
+	Args []Arg  `json:"args,omitempty"`
+	// Returns the type of the result, none when empty.
+	Returns string `json:"returns,omitempty"`
+	// Payable the function accepts a value, only for transactions.
+	Payable bool `json:"payable,omitempty"`
+	// Readonly the function is called with Api.Call instead of a
+	// transaction.
+	Readonly bool `json:"readonly,omitempty"`
+}
+
+// Arg is an argument of a function.
+type Arg struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+var (
+	// functionName the functions starting with _ are private to the contract
+	functionName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	argName      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ParseABI parses and checks an interface description.
+func ParseABI(data []byte) (*ABI, error) {
+	abi := new(ABI)
+	err := json.Unmarshal(data, abi)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidABI, err)
+	}
+	err = abi.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return abi, nil
+}
+
+// Validate checks the names and types of the functions, and that the Go names
+// of the functions are distinct. BoundContract is reserved for the embedded
+// field of the binding.
+func (abi *ABI) Validate() error {
+	goNames := make(map[string]string)
+	for _, fn := range abi.Functions {
+		if !functionName.MatchString(fn.Name) || exported(fn.Name) == "BoundContract" {
+			return fmt.Errorf("%w: invalid function name %q", ErrInvalidABI, fn.Name)
+		}
+		if other, ok := goNames[exported(fn.Name)]; ok {
+			return fmt.Errorf("%w: functions %s and %s have the same Go name", ErrInvalidABI, other, fn.Name)
+		}
+		goNames[exported(fn.Name)] = fn.Name
+
+		if fn.Readonly && fn.Payable {
+			return fmt.Errorf("%w: readonly function %s can not be payable", ErrInvalidABI, fn.Name)
+		}
+		if fn.Returns != "" {
+			if _, err := goType(fn.Returns); err != nil {
+				return fmt.Errorf("%w: result of %s: %v", ErrInvalidABI, fn.Name, err)
+			}
+		}
+
+		args := make(map[string]bool)
+		for _, arg := range fn.Args {
+			if !argName.MatchString(arg.Name) {
+				return fmt.Errorf("%w: invalid argument name %q of %s", ErrInvalidABI, arg.Name, fn.Name)
+			}
+			if args[arg.Name] {
+				return fmt.Errorf("%w: duplicated argument %s of %s", ErrInvalidABI, arg.Name, fn.Name)
+			}
+			args[arg.Name] = true
+			if _, err := goType(arg.Type); err != nil {
+				return fmt.Errorf("%w: argument %s of %s: %v", ErrInvalidABI, arg.Name, fn.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// goType returns the Go type of an interface type, the arrays have one
+// dimension.
+func goType(t string) (string, error) {
+	elem := strings.TrimSuffix(t, "[]")
+	goType, ok := goTypes[elem]
+	if !ok {
+		return "", fmt.Errorf("unknown type %q", t)
+	}
+	if elem != t {
+		return "[]" + goType, nil
+	}
+	return goType, nil
+}
+
+// exported returns name starting with an upper case letter.
+func exported(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
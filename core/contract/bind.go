@@ -0,0 +1,166 @@
+package contract
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// reservedArgs are the names used by the generated methods, the arguments
+// with these names, a keyword or a predeclared name like string get an Arg
+// suffix.
+var reservedArgs = map[string]bool{
+	"c": true, "ctx": true, "opts": true, "value": true, "out": true, "err": true,
+	"big": true, "context": true, "contract": true, "json": true, "rpc": true, "transaction": true,
+}
+
+// argNames returns the Go names of args, unique among them once renamed.
+func argNames(args []Arg) []string {
+	used := make(map[string]bool)
+	for _, arg := range args {
+		used[arg.Name] = true
+	}
+
+	names := make([]string, len(args))
+	for i, arg := range args {
+		name := arg.Name
+		if token.IsKeyword(name) || reservedArgs[name] || types.Universe.Lookup(name) != nil {
+			name += "Arg"
+			for n := 2; used[name]; n++ {
+				name = arg.Name + "Arg" + strconv.Itoa(n)
+			}
+			used[name] = true
+		}
+		names[i] = name
+	}
+	return names
+}
+
+type bindData struct {
+	Package   string
+	Type      string
+	Imports   []string
+	Functions []bindFunction
+}
+
+type bindFunction struct {
+	Function
+	GoName     string
+	GoArgs     []bindArg
+	ReturnType string
+}
+
+type bindArg struct {
+	Name string
+	Type string
+}
+
+// Bind generates the Go binding of the contract described by abi: a type
+// named typeName in package pkg, whose readonly methods go through Api.Call
+// and the other ones send a signed call transaction.
+func Bind(abi *ABI, pkg string, typeName string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) || token.IsKeyword(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+	if !token.IsIdentifier(typeName) || !token.IsExported(typeName) {
+		return nil, fmt.Errorf("invalid type name %q", typeName)
+	}
+	if err := abi.Validate(); err != nil {
+		return nil, err
+	}
+
+	data := &bindData{Package: pkg, Type: typeName}
+	imports := map[string]bool{"context": true, "github.com/vigozhang/neb-go/core/contract": true, "github.com/vigozhang/neb-go/core/rpc": true}
+	for _, fn := range abi.Functions {
+		bound := bindFunction{Function: fn, GoName: exported(fn.Name)}
+		names := argNames(fn.Args)
+		for i, arg := range fn.Args {
+			argType, _ := goType(arg.Type)
+			bound.GoArgs = append(bound.GoArgs, bindArg{Name: names[i], Type: argType})
+		}
+		if fn.Readonly && fn.Returns != "" {
+			bound.ReturnType, _ = goType(fn.Returns)
+		}
+		if !fn.Readonly {
+			imports["github.com/vigozhang/neb-go/core/transaction"] = true
+		}
+		if fn.Payable {
+			imports["math/big"] = true
+		}
+		for _, t := range append([]string{bound.ReturnType}, argTypes(bound.GoArgs)...) {
+			if strings.Contains(t, "big.Int") {
+				imports["math/big"] = true
+			}
+			if strings.Contains(t, "json.RawMessage") {
+				imports["encoding/json"] = true
+			}
+		}
+		data.Functions = append(data.Functions, bound)
+	}
+	for _, path := range []string{"context", "encoding/json", "math/big", "", "github.com/vigozhang/neb-go/core/contract", "github.com/vigozhang/neb-go/core/rpc", "github.com/vigozhang/neb-go/core/transaction"} {
+		if path == "" || imports[path] {
+			data.Imports = append(data.Imports, path)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := bindTemplate.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+func argTypes(args []bindArg) []string {
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = arg.Type
+	}
+	return types
+}
+
+var bindTemplate = template.Must(template.New("bind").Parse(`// Code generated by neb bind. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	{{if .}}"{{.}}"{{end}}
+{{- end}}
+)
+
+// {{.Type}} is the binding of the contract functions.
+type {{.Type}} struct {
+	*contract.BoundContract
+}
+
+// New{{.Type}} binds the contract deployed at address.
+func New{{.Type}}(address string, api *rpc.Api) *{{.Type}} {
+	return &{{.Type}}{contract.NewBoundContract(address, api)}
+}
+{{range .Functions}}
+{{- if .Readonly}}
+// {{.GoName}} calls the readonly function {{.Name}}.
+func (c *{{$.Type}}) {{.GoName}}(ctx context.Context, opts *contract.CallOpts{{range .GoArgs}}, {{.Name}} {{.Type}}{{end}}) {{if .ReturnType}}({{.ReturnType}}, error){{else}}error{{end}} {
+{{- if .ReturnType}}
+	var out {{.ReturnType}}
+	err := c.BoundContract.Call(ctx, opts, &out, "{{.Name}}"{{range .GoArgs}}, {{.Name}}{{end}})
+	return out, err
+{{- else}}
+	return c.BoundContract.Call(ctx, opts, nil, "{{.Name}}"{{range .GoArgs}}, {{.Name}}{{end}})
+{{- end}}
+}
+{{else}}
+// {{.GoName}} sends a transaction calling {{.Name}}{{if .Payable}} with value{{end}}.
+{{- if .Returns}}
+// Its {{.Returns}} result is the execute result of the receipt.{{end}}
+func (c *{{$.Type}}) {{.GoName}}(ctx context.Context, opts *contract.TransactOpts{{if .Payable}}, value *big.Int{{end}}{{range .GoArgs}}, {{.Name}} {{.Type}}{{end}}) (*transaction.Transaction, error) {
+	return c.BoundContract.Transact(ctx, opts, {{if .Payable}}value{{else}}nil{{end}}, "{{.Name}}"{{range .GoArgs}}, {{.Name}}{{end}})
+}
+{{end}}
+{{- end}}`))
@@ -0,0 +1,167 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden bindings of testdata")
+
+func TestBind_Golden(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/token.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	abi, err := ParseABI(data)
+	if err != nil {
+		t.Fatal("TestBind_Golden parse failed:", err)
+	}
+	src, err := Bind(abi, "token", "Token")
+	if err != nil {
+		t.Fatal("TestBind_Golden bind failed:", err)
+	}
+
+	if *update {
+		if err := ioutil.WriteFile("testdata/token.go.golden", src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := ioutil.ReadFile("testdata/token.go.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, golden) {
+		t.Errorf("TestBind_Golden generated:\n%s", src)
+	}
+}
+
+// bindStubs are the declarations of the packages a binding uses, to type
+// check it without building them. The assignments below keep them in step.
+var bindStubs = map[string]string{
+	"github.com/vigozhang/neb-go/core/rpc":         `package rpc; type Api struct{}`,
+	"github.com/vigozhang/neb-go/core/transaction": `package transaction; type Transaction struct{}`,
+	"github.com/vigozhang/neb-go/core/contract": `package contract
+import (
+	"context"
+	"math/big"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+type CallOpts struct{}
+type TransactOpts struct{}
+type BoundContract struct{}
+func NewBoundContract(address string, api *rpc.Api) *BoundContract { return nil }
+func (c *BoundContract) Call(ctx context.Context, opts *CallOpts, out interface{}, function string, args ...interface{}) error { return nil }
+func (c *BoundContract) Transact(ctx context.Context, opts *TransactOpts, value *big.Int, function string, args ...interface{}) (*transaction.Transaction, error) { return nil, nil }`,
+}
+
+var _ func(string, *rpc.Api) *BoundContract = NewBoundContract
+var _ func(*BoundContract, context.Context, *CallOpts, interface{}, string, ...interface{}) error = (*BoundContract).Call
+var _ func(*BoundContract, context.Context, *TransactOpts, *big.Int, string, ...interface{}) (*transaction.Transaction, error) = (*BoundContract).Transact
+
+type stubImporter struct {
+	fset     *token.FileSet
+	std      types.Importer
+	packages map[string]*types.Package
+}
+
+func (imp *stubImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := imp.packages[path]; ok {
+		return pkg, nil
+	}
+	src, ok := bindStubs[path]
+	if !ok {
+		return imp.std.Import(path)
+	}
+	pkg, err := typeCheck(imp, path, src)
+	imp.packages[path] = pkg
+	return pkg, err
+}
+
+func typeCheck(imp *stubImporter, path string, src string) (*types.Package, error) {
+	file, err := parser.ParseFile(imp.fset, path+".go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	conf := types.Config{Importer: imp}
+	return conf.Check(path, imp.fset, []*ast.File{file}, nil)
+}
+
+// checkBinding type checks the binding generated from abi.
+func checkBinding(t *testing.T, abi *ABI) {
+	src, err := Bind(abi, "token", "Token")
+	if err != nil {
+		t.Fatal("checkBinding bind failed:", err)
+	}
+	fset := token.NewFileSet()
+	imp := &stubImporter{fset: fset, std: importer.ForCompiler(fset, "source", nil), packages: make(map[string]*types.Package)}
+	if _, err := typeCheck(imp, "token", string(src)); err != nil {
+		t.Errorf("checkBinding %v:\n%s", err, src)
+	}
+}
+
+func TestBind_TypeCheck(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/token.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	abi, err := ParseABI(data)
+	if err != nil {
+		t.Fatal("TestBind_TypeCheck parse failed:", err)
+	}
+	checkBinding(t, abi)
+
+	abi, err = ParseABI([]byte(`{"functions": [
+		{"name": "get", "readonly": true, "returns": "string", "args": [
+			{"name": "string", "type": "string"}, {"name": "int", "type": "int"}, {"name": "len", "type": "uint"},
+			{"name": "nil", "type": "bool"}, {"name": "type", "type": "object"}, {"name": "out", "type": "bigint"}]},
+		{"name": "deposit", "payable": true, "args": [
+			{"name": "value", "type": "bigint"}, {"name": "valueArg", "type": "string"}, {"name": "valueArg2", "type": "string"},
+			{"name": "error", "type": "address"}, {"name": "big", "type": "number"}]}
+	]}`))
+	if err != nil {
+		t.Fatal("TestBind_TypeCheck parse failed:", err)
+	}
+	checkBinding(t, abi)
+}
+
+func TestBind_Names(t *testing.T) {
+	abi := &ABI{Functions: []Function{{Name: "get", Readonly: true}}}
+	for _, names := range [][2]string{{"token", "token"}, {"func", "Token"}, {"token", "Token Type"}} {
+		if _, err := Bind(abi, names[0], names[1]); err == nil {
+			t.Errorf("TestBind_Names bound %v", names)
+		}
+	}
+}
+
+func TestParseABI_Invalid(t *testing.T) {
+	tests := []string{
+		`{"functions": [{"name": "_private"}]}`,
+		`{"functions": [{"name": "get"}, {"name": "Get"}]}`,
+		`{"functions": [{"name": "boundContract"}]}`,
+		`{"functions": [{"name": "get", "readonly": true, "payable": true}]}`,
+		`{"functions": [{"name": "get", "returns": "map"}]}`,
+		`{"functions": [{"name": "get", "args": [{"name": "a", "type": "string[][]"}]}]}`,
+		`{"functions": [{"name": "get", "args": [{"name": "a", "type": "string"}, {"name": "a", "type": "int"}]}]}`,
+		`{"functions": [{"name": "get", "args": [{"name": "a-b", "type": "string"}]}]}`,
+		`{"functions": {}}`,
+	}
+	for _, test := range tests {
+		if _, err := ParseABI([]byte(test)); !errors.Is(err, ErrInvalidABI) {
+			t.Errorf("TestParseABI_Invalid %s: %v", test, err)
+		}
+	}
+}
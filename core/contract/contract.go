@@ -0,0 +1,225 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+// ExecuteError is returned when a readonly call fails in the contract.
+type ExecuteError struct {
+	Function string
+	// Message the execute error of the call result.
+	Message string
+}
+
+func (e *ExecuteError) Error() string {
+	return fmt.Sprintf("%s: execute error: %s", e.Function, e.Message)
+}
+
+// CallOpts are the options of a readonly call, nil for the defaults.
+type CallOpts struct {
+	// From the caller of the function, the contract address when empty.
+	From string
+}
+
+// TransactOpts are the options of a transaction calling a function. The
+// fields that are zero are resolved by the TransactionBuilder.
+type TransactOpts struct {
+	// From the account sending the transaction, it needs a private key
+	// unless a Signer is set. With a Signer it defaults to the signer
+	// address.
+	From *account.Account
+	// Signer signs the transaction instead of the private key of From.
+	Signer   transaction.Signer
+	Nonce    uint64
+	GasPrice *big.Int
+	GasLimit *big.Int
+	// NoSend returns the signed transaction without sending it.
+	NoSend bool
+}
+
+// BoundContract calls the functions of a deployed contract, it is embedded in
+// the generated bindings.
+type BoundContract struct {
+	Address string
+	Api     *rpc.Api
+	// Builder builds the transactions, e.g. set its Nonces to send
+	// concurrently from one account.
+	Builder *rpc.TransactionBuilder
+}
+
+func NewBoundContract(address string, api *rpc.Api) *BoundContract {
+	return &BoundContract{Address: address, Api: api, Builder: rpc.NewTransactionBuilder(api)}
+}
+
+// Call calls a readonly function with Api.Call and decodes its result into
+// out, which may be nil when the function returns nothing.
+func (c *BoundContract) Call(ctx context.Context, opts *CallOpts, out interface{}, function string, args ...interface{}) error {
+	packed, err := PackArgs(args...)
+	if err != nil {
+		return err
+	}
+	from := c.Address
+	if opts != nil && opts.From != "" {
+		from = opts.From
+	}
+
+	req := rpc.TransactionRequest{
+		From:     from,
+		To:       c.Address,
+		Value:    "0",
+		Contract: &rpc.ContractRequest{Function: function, Args: packed},
+	}
+	resp, err := c.Api.CallWithContext(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Result == nil {
+		return errors.New(function + ": empty call result")
+	}
+	if resp.Result.ExecuteErr != "" {
+		return &ExecuteError{Function: function, Message: resp.Result.ExecuteErr}
+	}
+	if out == nil {
+		return nil
+	}
+	err = UnpackResult(resp.Result.Result, out)
+	if err != nil {
+		return fmt.Errorf("%s: invalid result %s: %v", function, resp.Result.Result, err)
+	}
+	return nil
+}
+
+// Transact builds and signs a transaction calling function with value, nil
+// for none, and sends it unless opts.NoSend is set. The transaction is
+// returned once the node accepted it, the receipt can be awaited with
+// Api.WaitForReceipt.
+func (c *BoundContract) Transact(ctx context.Context, opts *TransactOpts, value *big.Int, function string, args ...interface{}) (*transaction.Transaction, error) {
+	if opts == nil {
+		return nil, errors.New(function + ": transact options are required")
+	}
+	packed, err := PackArgs(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := *c.Builder
+	if opts.Signer != nil {
+		builder.Signer = opts.Signer
+	}
+	from := opts.From
+	if from == nil && builder.Signer != nil {
+		from, err = account.FromAddress(builder.Signer.Address())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	txOpts := transaction.TransactionOptions{
+		From:     from,
+		To:       c.Address,
+		Value:    value,
+		Nonce:    opts.Nonce,
+		GasPrice: opts.GasPrice,
+		GasLimit: opts.GasLimit,
+		Contract: &transaction.Contract{Function: function, Args: packed},
+	}
+	if opts.NoSend {
+		return builder.Build(ctx, txOpts)
+	}
+	tx, _, err := builder.Send(ctx, txOpts)
+	return tx, err
+}
+
+// PackArgs encodes the arguments of a call as the json array of the call
+// payload, empty without arguments. Big integers are sent as decimal strings.
+func PackArgs(args ...interface{}) (string, error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case *big.Int:
+			values[i] = bigString(arg)
+		case []*big.Int:
+			strs := make([]*string, len(arg))
+			for j, n := range arg {
+				strs[j] = bigString(n)
+			}
+			values[i] = strs
+		default:
+			values[i] = arg
+		}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// bigString returns n as a decimal string, nil for null.
+func bigString(n *big.Int) *string {
+	if n == nil {
+		return nil
+	}
+	str := n.String()
+	return &str
+}
+
+// UnpackResult decodes the json result of a call into out. Big integers are
+// read from strings or numbers.
+func UnpackResult(result string, out interface{}) error {
+	switch out := out.(type) {
+	case **big.Int:
+		n, err := parseBig(json.RawMessage(result))
+		if err != nil {
+			return err
+		}
+		*out = n
+		return nil
+	case *[]*big.Int:
+		var raws []json.RawMessage
+		err := json.Unmarshal([]byte(result), &raws)
+		if err != nil {
+			return err
+		}
+		values := make([]*big.Int, len(raws))
+		for i, raw := range raws {
+			values[i], err = parseBig(raw)
+			if err != nil {
+				return err
+			}
+		}
+		*out = values
+		return nil
+	default:
+		return json.Unmarshal([]byte(result), out)
+	}
+}
+
+func parseBig(raw json.RawMessage) (*big.Int, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		var number json.Number
+		if err := json.Unmarshal(raw, &number); err != nil {
+			return nil, err
+		}
+		text = number.String()
+	}
+	n, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, errors.New("invalid integer " + strconv.Quote(text))
+	}
+	return n, nil
+}
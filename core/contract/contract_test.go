@@ -0,0 +1,133 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc/rpctest"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+const testContract = "n1sLnoc7j57YfzAVP8tJ3yK5a2i56QrTDdK"
+
+func newTestContract(t *testing.T) (*rpctest.Node, *BoundContract, *[]*rpctest.ContractCall) {
+	node := rpctest.NewNode(rpctest.Options{AutoMine: true})
+	t.Cleanup(node.Close)
+
+	var calls []*rpctest.ContractCall
+	node.RegisterContract(testContract, func(call *rpctest.ContractCall) (string, error) {
+		calls = append(calls, call)
+		switch call.Function {
+		case "balanceOf":
+			return `"123456789012345678901234"`, nil
+		case "fail":
+			return "", errors.New("fail is not allowed")
+		}
+		return "true", nil
+	})
+	return node, NewBoundContract(testContract, node.Neb().Api), &calls
+}
+
+func TestBoundContract_Call(t *testing.T) {
+	_, contract, calls := newTestContract(t)
+	ctx := context.Background()
+
+	var balance *big.Int
+	err := contract.Call(ctx, nil, &balance, "balanceOf", "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17")
+	if err != nil || balance.String() != "123456789012345678901234" {
+		t.Errorf("TestBoundContract_Call balance %s: %v", balance, err)
+	}
+	call := (*calls)[0]
+	if call.From != testContract || call.Args != `["n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17"]` {
+		t.Errorf("TestBoundContract_Call call %+v", call)
+	}
+
+	from := account.NewAccount().GetAddressString()
+	if err := contract.Call(ctx, &CallOpts{From: from}, nil, "get"); err != nil || (*calls)[1].From != from || (*calls)[1].Args != "" {
+		t.Errorf("TestBoundContract_Call from %+v: %v", (*calls)[1], err)
+	}
+
+	var executeErr *ExecuteError
+	err = contract.Call(ctx, nil, nil, "fail")
+	if !errors.As(err, &executeErr) || executeErr.Function != "fail" || executeErr.Message != "fail is not allowed" {
+		t.Errorf("TestBoundContract_Call execute error %v", err)
+	}
+
+	var name string
+	if err := contract.Call(ctx, nil, &name, "get"); err == nil {
+		t.Error("TestBoundContract_Call decoded a bool into a string")
+	}
+}
+
+func TestBoundContract_Transact(t *testing.T) {
+	node, contract, calls := newTestContract(t)
+	ctx := context.Background()
+	acc := account.NewAccount()
+	node.SetBalance(acc.GetAddressString(), big.NewInt(1e18))
+
+	tx, err := contract.Transact(ctx, &TransactOpts{From: acc}, big.NewInt(7), "deposit", "memo", big.NewInt(3))
+	if err != nil {
+		t.Fatal("TestBoundContract_Transact failed:", err)
+	}
+	call := (*calls)[len(*calls)-1]
+	if call.Function != "deposit" || call.Args != `["memo","3"]` || call.Value.Int64() != 7 || tx.Data.Type != transaction.TxPayloadCallType {
+		t.Errorf("TestBoundContract_Transact call %+v", call)
+	}
+	if node.Balance(testContract).Int64() != 7 {
+		t.Errorf("TestBoundContract_Transact balance %s", node.Balance(testContract))
+	}
+
+	sent := len(*calls)
+	tx, err = contract.Transact(ctx, &TransactOpts{From: acc, Nonce: 9, GasLimit: big.NewInt(30000), NoSend: true}, nil, "transfer")
+	if err != nil || tx.Nonce != 9 || tx.GasLimit.Int64() != 30000 || len(*calls) != sent {
+		t.Errorf("TestBoundContract_Transact no send %+v: %v", tx, err)
+	}
+	if err := tx.Verify(); err != nil {
+		t.Error("TestBoundContract_Transact signature:", err)
+	}
+
+	tx, err = contract.Transact(ctx, &TransactOpts{Signer: transaction.NewAccountSigner(acc), NoSend: true}, nil, "transfer")
+	if err != nil || tx.From.GetAddressString() != acc.GetAddressString() {
+		t.Errorf("TestBoundContract_Transact signer %+v: %v", tx, err)
+	}
+
+	if _, err := contract.Transact(ctx, nil, nil, "transfer"); err == nil {
+		t.Error("TestBoundContract_Transact sent without options")
+	}
+}
+
+func TestPackArgs(t *testing.T) {
+	args, err := PackArgs("a", int64(1), true, big.NewInt(2), []*big.Int{big.NewInt(3), nil}, []string{"b"})
+	if err != nil || args != `["a",1,true,"2",["3",null],["b"]]` {
+		t.Errorf("TestPackArgs %s: %v", args, err)
+	}
+	if args, err := PackArgs(); err != nil || args != "" {
+		t.Errorf("TestPackArgs no arguments %q: %v", args, err)
+	}
+}
+
+func TestUnpackResult(t *testing.T) {
+	var n *big.Int
+	if err := UnpackResult("42", &n); err != nil || n.Int64() != 42 {
+		t.Errorf("TestUnpackResult number %s: %v", n, err)
+	}
+	var values []*big.Int
+	if err := UnpackResult(`["1", 2]`, &values); err != nil || !reflect.DeepEqual(values, []*big.Int{big.NewInt(1), big.NewInt(2)}) {
+		t.Errorf("TestUnpackResult array %v: %v", values, err)
+	}
+	if err := UnpackResult(`"1.5"`, &n); err == nil {
+		t.Error("TestUnpackResult decoded a decimal")
+	}
+	var names []string
+	if err := UnpackResult(`["a","b"]`, &names); err != nil || len(names) != 2 {
+		t.Errorf("TestUnpackResult strings %v: %v", names, err)
+	}
+	var count int32
+	if err := UnpackResult("9007199254740993", &count); err == nil {
+		t.Errorf("TestUnpackResult decoded an int beyond 32 bits into %d", count)
+	}
+}
@@ -0,0 +1,409 @@
+// Package keystore manages a directory of keyfiles in the layout of the node
+// keydir, one keystore json per UTC--<time>--<address> file, and keeps the
+// unlocked accounts in memory until they are locked again.
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils/secp256k1"
+)
+
+// DefaultUnlockDuration the unlock duration when none is given, the same
+// default the node uses.
+const DefaultUnlockDuration = 300 * time.Second
+
+var (
+	// ErrAccountNotFound no keyfile in the directory has the address
+	ErrAccountNotFound = errors.New("account not found in keystore")
+
+	// ErrAccountExists a keyfile of the address is already in the directory
+	ErrAccountExists = errors.New("account already exists in keystore")
+
+	// ErrAccountLocked the account is not unlocked
+	ErrAccountLocked = errors.New("account is locked")
+)
+
+type Keystore struct {
+	dir     string
+	options account.KeyOptions
+
+	mu       sync.Mutex
+	unlocked map[string]*unlockedAccount
+}
+
+type unlockedAccount struct {
+	account *account.Account
+	timer   *time.Timer
+}
+
+// NewKeystore opens the keystore of dir, creating the directory if needed.
+// options are the encryption options of the keyfiles it writes, nil for the
+// ToKey defaults.
+func NewKeystore(dir string, options *account.KeyOptions) (*Keystore, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &Keystore{dir: dir, unlocked: make(map[string]*unlockedAccount)}
+	if options != nil {
+		ks.options = *options
+	}
+	return ks, nil
+}
+
+func (ks *Keystore) Dir() string {
+	return ks.dir
+}
+
+// Accounts returns the addresses of the keyfiles in the directory, sorted.
+// Files that are not keystore json are skipped.
+func (ks *Keystore) Accounts() ([]string, error) {
+	files, err := ks.keyfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(files))
+	for address := range files {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+// keyfiles maps the addresses of the directory to their keyfile paths.
+func (ks *Keystore) keyfiles() (map[string]string, error) {
+	infos, err := ioutil.ReadDir(ks.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string)
+	for _, info := range infos {
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(ks.dir, info.Name())
+		key, err := readKey(path)
+		if err != nil {
+			continue
+		}
+		files[key.Address] = path
+	}
+	return files, nil
+}
+
+func readKey(path string) (*account.Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := account.Key{}
+	err = json.Unmarshal(data, &key)
+	if err != nil {
+		return nil, err
+	}
+	if !account.IsValidAddress(key.Address) {
+		return nil, errors.New("invalid keystore address " + key.Address)
+	}
+	return &key, nil
+}
+
+func (ks *Keystore) keyfile(address string) (string, error) {
+	files, err := ks.keyfiles()
+	if err != nil {
+		return "", err
+	}
+	path, ok := files[address]
+	if !ok {
+		return "", ErrAccountNotFound
+	}
+	return path, nil
+}
+
+// NewAccount creates an account encrypted with passphrase, like
+// Admin.NewAccount on the node, and returns its address.
+func (ks *Keystore) NewAccount(passphrase string) (string, error) {
+	return ks.store(account.NewAccount(), passphrase)
+}
+
+// Import adds a keystore json, which must decrypt with passphrase.
+func (ks *Keystore) Import(keyjson string, passphrase string) (string, error) {
+	acc, err := account.NewAccount().FromKey(keyjson, passphrase, false)
+	if err != nil {
+		return "", err
+	}
+
+	address := acc.GetAddressString()
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	_, err = ks.keyfile(address)
+	if err == nil {
+		return "", ErrAccountExists
+	}
+	err = writeFileAtomic(filepath.Join(ks.dir, keyFileName(address, time.Now())), []byte(keyjson))
+	if err != nil {
+		return "", err
+	}
+	return address, nil
+}
+
+// ImportPrivateKey adds the account of privateKey encrypted with passphrase.
+func (ks *Keystore) ImportPrivateKey(privateKey []byte, passphrase string) (string, error) {
+	if !secp256k1.SeckeyVerify(privateKey) {
+		return "", errors.New("invalid private key")
+	}
+
+	acc := account.NewAccount()
+	acc.SetPrivateKey(privateKey)
+	return ks.store(acc, passphrase)
+}
+
+func (ks *Keystore) store(acc *account.Account, passphrase string) (string, error) {
+	keyjson, err := acc.ToKeyString(passphrase, ks.keyOptions())
+	if err != nil {
+		return "", err
+	}
+
+	address := acc.GetAddressString()
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	_, err = ks.keyfile(address)
+	if err == nil {
+		return "", ErrAccountExists
+	}
+	err = writeFileAtomic(filepath.Join(ks.dir, keyFileName(address, time.Now())), []byte(keyjson))
+	if err != nil {
+		return "", err
+	}
+	return address, nil
+}
+
+// keyOptions returns a copy of the options, ToKey must not reuse a salt or iv.
+func (ks *Keystore) keyOptions() *account.KeyOptions {
+	options := ks.options
+	options.Salt = nil
+	options.Iv = nil
+	options.Uuid = nil
+	return &options
+}
+
+// Export returns the keystore json of address.
+func (ks *Keystore) Export(address string) (string, error) {
+	path, err := ks.keyfile(address)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Update re-encrypts the keyfile of address with newPassphrase. The keyfile
+// is replaced atomically, it is never left half written.
+func (ks *Keystore) Update(address string, passphrase string, newPassphrase string) error {
+	return ks.reencrypt(address, passphrase, newPassphrase, ks.keyOptions())
+}
+
+// Upgrade re-encrypts the keyfile of address with options, e.g.
+// account.KeyProfileStandard, keeping its passphrase.
+func (ks *Keystore) Upgrade(address string, passphrase string, options *account.KeyOptions) error {
+	return ks.reencrypt(address, passphrase, passphrase, options)
+}
+
+func (ks *Keystore) reencrypt(address string, passphrase string, newPassphrase string, options *account.KeyOptions) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	path, err := ks.keyfile(address)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	keyjson, err := account.ReencryptKey(string(data), passphrase, newPassphrase, options)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(keyjson))
+}
+
+// Delete removes the keyfile of address, passphrase must decrypt it.
+func (ks *Keystore) Delete(address string, passphrase string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	path, _, err := ks.decrypt(address, passphrase)
+	if err != nil {
+		return err
+	}
+	ks.lock(address)
+	return os.Remove(path)
+}
+
+func (ks *Keystore) decrypt(address string, passphrase string) (string, *account.Account, error) {
+	path, err := ks.keyfile(address)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	acc, err := account.NewAccount().FromKey(string(data), passphrase, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, acc, nil
+}
+
+// Unlock decrypts the account of address and keeps it in memory for
+// duration, DefaultUnlockDuration when 0, like Admin.UnlockAccount.
+func (ks *Keystore) Unlock(address string, passphrase string, duration time.Duration) error {
+	if duration <= 0 {
+		duration = DefaultUnlockDuration
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	_, acc, err := ks.decrypt(address, passphrase)
+	if err != nil {
+		return err
+	}
+
+	ks.lock(address)
+	unlocked := &unlockedAccount{account: acc}
+	unlocked.timer = time.AfterFunc(duration, func() {
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+
+		if ks.unlocked[address] == unlocked {
+			ks.lock(address)
+		}
+	})
+	ks.unlocked[address] = unlocked
+	return nil
+}
+
+// Lock drops the unlocked account of address from memory.
+func (ks *Keystore) Lock(address string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.lock(address)
+}
+
+func (ks *Keystore) lock(address string) {
+	unlocked, ok := ks.unlocked[address]
+	if !ok {
+		return
+	}
+	unlocked.timer.Stop()
+	privateKey := unlocked.account.PrivateKey
+	for i := range privateKey {
+		privateKey[i] = 0
+	}
+	delete(ks.unlocked, address)
+}
+
+// Account returns a copy of the unlocked account of address, locking the
+// account does not clear the key of the copy.
+func (ks *Keystore) Account(address string) (*account.Account, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	unlocked, ok := ks.unlocked[address]
+	if !ok {
+		return nil, ErrAccountLocked
+	}
+	return &account.Account{
+		PrivateKey: append([]byte(nil), unlocked.account.PrivateKey...),
+		PublicKey:  unlocked.account.PublicKey,
+		Address:    unlocked.account.Address,
+	}, nil
+}
+
+// Signer returns a transaction.Signer for the account of address, which signs
+// while the account is unlocked.
+func (ks *Keystore) Signer(address string) transaction.Signer {
+	return &keystoreSigner{keystore: ks, address: address}
+}
+
+type keystoreSigner struct {
+	keystore *Keystore
+	address  string
+}
+
+func (signer *keystoreSigner) Address() string {
+	return signer.address
+}
+
+func (signer *keystoreSigner) SignHash(hash []byte) ([]byte, error) {
+	ks := signer.keystore
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	unlocked, ok := ks.unlocked[signer.address]
+	if !ok {
+		return nil, ErrAccountLocked
+	}
+	return secp256k1.Sign(hash, unlocked.account.GetPrivateKey())
+}
+
+// keyFileName returns the node keyfile name, UTC--<ISO 8601 time>--<address>.
+func keyFileName(address string, t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("UTC--%04d-%02d-%02dT%02d-%02d-%02d.%09dZ--%s",
+		t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), address)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path.
+func writeFileAtomic(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0600)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package keystore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+func newTestKeystore(t *testing.T) *Keystore {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	ks, err := NewKeystore(dir, &account.KeyOptions{N: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func TestKeystore_NewAccount(t *testing.T) {
+	ks := newTestKeystore(t)
+
+	address, err := ks.NewAccount("passphrase")
+	if err != nil {
+		t.Fatal("TestKeystore_NewAccount failed:", err)
+	}
+	// files that are not keystore json are skipped
+	ioutil.WriteFile(filepath.Join(ks.Dir(), "README"), []byte("not a key"), 0600)
+
+	addresses, err := ks.Accounts()
+	if err != nil || len(addresses) != 1 || addresses[0] != address {
+		t.Fatalf("TestKeystore_NewAccount accounts %v: %v", addresses, err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(ks.Dir(), "UTC--*--"+address))
+	if len(files) != 1 {
+		t.Error("TestKeystore_NewAccount keyfile name is not in the node layout")
+	}
+
+	keyjson, err := ks.Export(address)
+	if err != nil {
+		t.Fatal("TestKeystore_NewAccount export failed:", err)
+	}
+	other := newTestKeystore(t)
+	imported, err := other.Import(keyjson, "passphrase")
+	if err != nil || imported != address {
+		t.Errorf("TestKeystore_NewAccount import %s failed: %v", imported, err)
+	}
+	if _, err := other.Import(keyjson, "passphrase"); err != ErrAccountExists {
+		t.Error("TestKeystore_NewAccount imported an account twice:", err)
+	}
+	if _, err := other.Import(keyjson, "wrong"); err == nil {
+		t.Error("TestKeystore_NewAccount imported with a wrong passphrase")
+	}
+}
+
+func TestKeystore_Update(t *testing.T) {
+	ks := newTestKeystore(t)
+	acc := account.NewAccount()
+	address, err := ks.ImportPrivateKey(acc.GetPrivateKey(), "passphrase")
+	if err != nil || address != acc.GetAddressString() {
+		t.Fatalf("TestKeystore_Update import %s failed: %v", address, err)
+	}
+
+	if err := ks.Update(address, "wrong", "new"); err == nil {
+		t.Error("TestKeystore_Update updated with a wrong passphrase")
+	}
+	if err := ks.Update(address, "passphrase", "new"); err != nil {
+		t.Fatal("TestKeystore_Update failed:", err)
+	}
+	if err := ks.Unlock(address, "passphrase", 0); err == nil {
+		t.Error("TestKeystore_Update the old passphrase still unlocks")
+	}
+	if err := ks.Unlock(address, "new", 0); err != nil {
+		t.Error("TestKeystore_Update the new passphrase does not unlock:", err)
+	}
+
+	if err := ks.Upgrade(address, "new", &account.KeyOptions{N: 2048, Cipher: "aes-256-gcm"}); err != nil {
+		t.Fatal("TestKeystore_Update upgrade failed:", err)
+	}
+	keyjson, _ := ks.Export(address)
+	if !strings.Contains(keyjson, `"cipher":"aes-256-gcm"`) || !strings.Contains(keyjson, `"n":2048`) {
+		t.Error("TestKeystore_Update keyfile not upgraded:", keyjson)
+	}
+	if err := ks.Unlock(address, "new", 0); err != nil {
+		t.Error("TestKeystore_Update the upgraded keyfile does not unlock:", err)
+	}
+
+	infos, _ := ioutil.ReadDir(ks.Dir())
+	if len(infos) != 1 || strings.HasPrefix(infos[0].Name(), ".") {
+		t.Errorf("TestKeystore_Update left %d files", len(infos))
+	}
+
+	if err := ks.Delete(address, "new"); err != nil {
+		t.Fatal("TestKeystore_Update delete failed:", err)
+	}
+	if _, err := ks.Account(address); err != ErrAccountLocked {
+		t.Error("TestKeystore_Update deleted account is still unlocked")
+	}
+	if _, err := ks.Export(address); err != ErrAccountNotFound {
+		t.Error("TestKeystore_Update deleted account is still exported:", err)
+	}
+}
+
+func TestKeystore_Unlock(t *testing.T) {
+	ks := newTestKeystore(t)
+	address, _ := ks.NewAccount("passphrase")
+	signer := ks.Signer(address)
+
+	from, _ := account.FromAddress(address)
+	tx := transaction.NewTransaction(transaction.TransactionOptions{
+		ChainID:  1001,
+		From:     from,
+		To:       "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17",
+		Value:    big.NewInt(10),
+		Nonce:    1,
+		GasPrice: big.NewInt(1000000),
+		GasLimit: big.NewInt(20000),
+		Contract: &transaction.Contract{},
+	})
+	if err := tx.SignWith(signer); err != ErrAccountLocked {
+		t.Error("TestKeystore_Unlock signed with a locked account:", err)
+	}
+
+	err := ks.Unlock(address, "passphrase", 100*time.Millisecond)
+	if err != nil {
+		t.Fatal("TestKeystore_Unlock failed:", err)
+	}
+	if err := tx.SignWith(signer); err != nil {
+		t.Error("TestKeystore_Unlock failed to sign:", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if _, err := ks.Account(address); err != ErrAccountLocked {
+		t.Error("TestKeystore_Unlock account was not locked after the duration")
+	}
+}
+
+func TestKeystore_Account(t *testing.T) {
+	ks := newTestKeystore(t)
+	address, _ := ks.NewAccount("passphrase")
+	if err := ks.Unlock(address, "passphrase", 0); err != nil {
+		t.Fatal("TestKeystore_Account failed:", err)
+	}
+
+	acc, err := ks.Account(address)
+	if err != nil || acc.GetAddressString() != address {
+		t.Fatal("TestKeystore_Account failed:", err)
+	}
+	privateKey := append([]byte(nil), acc.PrivateKey...)
+	ks.Lock(address)
+	if !bytes.Equal(acc.PrivateKey, privateKey) || bytes.Equal(privateKey, make([]byte, len(privateKey))) {
+		t.Error("TestKeystore_Account locking cleared the returned key")
+	}
+}
@@ -1,27 +1,32 @@
 package rpc
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/vigozhang/neb-go/utils/httprequest"
 )
 
 type Admin struct {
-	HttpRequest *httprequest.HttpRequest
+	HttpRequest httprequest.Transport
 }
 
 func NewAdmin(neb *Neb) *Admin {
 	return &Admin{neb.HttpRequest}
 }
 
-func (admin *Admin) SetRequest(request *httprequest.HttpRequest) {
+func (admin *Admin) SetRequest(request httprequest.Transport) {
 	admin.HttpRequest = request
 }
 
 func (admin *Admin) NodeInfo() (*NodeInfoResponse, error) {
-	resp, err := admin.HttpRequest.Get("/admin/nodeinfo", nil)
+	return admin.NodeInfoWithContext(context.Background())
+}
+
+func (admin *Admin) NodeInfoWithContext(ctx context.Context) (*NodeInfoResponse, error) {
+	resp, err := admin.HttpRequest.GetWithContext(ctx, "/admin/nodeinfo", nil)
 	if err != nil {
 		logError("NodeInfo", string(resp), err)
-		return nil, err
+		return nil, transportError("NodeInfo", err)
 	}
 
 	var response NodeInfoResponse
@@ -30,14 +35,22 @@ func (admin *Admin) NodeInfo() (*NodeInfoResponse, error) {
 		logError("NodeInfo", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("NodeInfo", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) Accounts() (*AccountsResponse, error) {
-	resp, err := admin.HttpRequest.Get("/admin/accounts", nil)
+	return admin.AccountsWithContext(context.Background())
+}
+
+func (admin *Admin) AccountsWithContext(ctx context.Context) (*AccountsResponse, error) {
+	resp, err := admin.HttpRequest.GetWithContext(ctx, "/admin/accounts", nil)
 	if err != nil {
 		logError("Accounts", string(resp), err)
-		return nil, err
+		return nil, transportError("Accounts", err)
 	}
 
 	var response AccountsResponse
@@ -46,14 +59,22 @@ func (admin *Admin) Accounts() (*AccountsResponse, error) {
 		logError("Accounts", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("Accounts", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) NewAccount(req NewAccountRequest) (*NewAccountResponse, error) {
-	resp, err := admin.HttpRequest.Post("/admin/account/new", req)
+	return admin.NewAccountWithContext(context.Background(), req)
+}
+
+func (admin *Admin) NewAccountWithContext(ctx context.Context, req NewAccountRequest) (*NewAccountResponse, error) {
+	resp, err := admin.HttpRequest.PostWithContext(ctx, "/admin/account/new", req)
 	if err != nil {
 		logError("NewAccount", string(resp), err)
-		return nil, err
+		return nil, transportError("NewAccount", err)
 	}
 
 	var response NewAccountResponse
@@ -62,14 +83,22 @@ func (admin *Admin) NewAccount(req NewAccountRequest) (*NewAccountResponse, erro
 		logError("NewAccount", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("NewAccount", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) UnlockAccount(req UnlockAccountRequest) (*UnlockAccountResponse, error) {
-	resp, err := admin.HttpRequest.Post("/admin/account/unlock", req)
+	return admin.UnlockAccountWithContext(context.Background(), req)
+}
+
+func (admin *Admin) UnlockAccountWithContext(ctx context.Context, req UnlockAccountRequest) (*UnlockAccountResponse, error) {
+	resp, err := admin.HttpRequest.PostWithContext(ctx, "/admin/account/unlock", req)
 	if err != nil {
 		logError("UnlockAccount", string(resp), err)
-		return nil, err
+		return nil, transportError("UnlockAccount", err)
 	}
 
 	var response UnlockAccountResponse
@@ -78,14 +107,22 @@ func (admin *Admin) UnlockAccount(req UnlockAccountRequest) (*UnlockAccountRespo
 		logError("UnlockAccount", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("UnlockAccount", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) LockAccount(req LockAccountRequest) (*LockAccountResponse, error) {
-	resp, err := admin.HttpRequest.Post("/admin/account/lock", req)
+	return admin.LockAccountWithContext(context.Background(), req)
+}
+
+func (admin *Admin) LockAccountWithContext(ctx context.Context, req LockAccountRequest) (*LockAccountResponse, error) {
+	resp, err := admin.HttpRequest.PostWithContext(ctx, "/admin/account/lock", req)
 	if err != nil {
 		logError("LockAccount", string(resp), err)
-		return nil, err
+		return nil, transportError("LockAccount", err)
 	}
 
 	var response LockAccountResponse
@@ -94,14 +131,22 @@ func (admin *Admin) LockAccount(req LockAccountRequest) (*LockAccountResponse, e
 		logError("LockAccount", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("LockAccount", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) SendTransaction(req TransactionRequest) (*SendTransactionResponse, error) {
-	resp, err := admin.HttpRequest.Post("/admin/transaction", req)
+	return admin.SendTransactionWithContext(context.Background(), req)
+}
+
+func (admin *Admin) SendTransactionWithContext(ctx context.Context, req TransactionRequest) (*SendTransactionResponse, error) {
+	resp, err := admin.HttpRequest.PostWithContext(ctx, "/admin/transaction", req)
 	if err != nil {
 		logError("SendTransaction", string(resp), err)
-		return nil, err
+		return nil, transportError("SendTransaction", err)
 	}
 
 	var response SendTransactionResponse
@@ -110,14 +155,22 @@ func (admin *Admin) SendTransaction(req TransactionRequest) (*SendTransactionRes
 		logError("SendTransaction", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("SendTransaction", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) SignHash(req SignHashRequest) (*SignHashResponse, error) {
-	resp, err := admin.HttpRequest.Post("/admin/sign/hash", req)
+	return admin.SignHashWithContext(context.Background(), req)
+}
+
+func (admin *Admin) SignHashWithContext(ctx context.Context, req SignHashRequest) (*SignHashResponse, error) {
+	resp, err := admin.HttpRequest.PostWithContext(ctx, "/admin/sign/hash", req)
 	if err != nil {
 		logError("SignHash", string(resp), err)
-		return nil, err
+		return nil, transportError("SignHash", err)
 	}
 
 	var response SignHashResponse
@@ -126,14 +179,22 @@ func (admin *Admin) SignHash(req SignHashRequest) (*SignHashResponse, error) {
 		logError("SignHash", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("SignHash", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) SignTransactionWithPassphrase(req SignTransactionPassphraseRequest) (*SignTransactionPassphraseResponse, error) {
-	resp, err := admin.HttpRequest.Post("/admin/sign", req)
+	return admin.SignTransactionWithPassphraseWithContext(context.Background(), req)
+}
+
+func (admin *Admin) SignTransactionWithPassphraseWithContext(ctx context.Context, req SignTransactionPassphraseRequest) (*SignTransactionPassphraseResponse, error) {
+	resp, err := admin.HttpRequest.PostWithContext(ctx, "/admin/sign", req)
 	if err != nil {
 		logError("SignTransactionWithPassphrase", string(resp), err)
-		return nil, err
+		return nil, transportError("SignTransactionWithPassphrase", err)
 	}
 
 	var response SignTransactionPassphraseResponse
@@ -142,14 +203,22 @@ func (admin *Admin) SignTransactionWithPassphrase(req SignTransactionPassphraseR
 		logError("SignTransactionWithPassphrase", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("SignTransactionWithPassphrase", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) SendTransactionWithPassphrase(req SendTransactionPassphraseRequest) (*SendTransactionResponse, error) {
-	resp, err := admin.HttpRequest.Post("/admin/transactionWithPassphrase", req)
+	return admin.SendTransactionWithPassphraseWithContext(context.Background(), req)
+}
+
+func (admin *Admin) SendTransactionWithPassphraseWithContext(ctx context.Context, req SendTransactionPassphraseRequest) (*SendTransactionResponse, error) {
+	resp, err := admin.HttpRequest.PostWithContext(ctx, "/admin/transactionWithPassphrase", req)
 	if err != nil {
 		logError("SendTransactionWithPassphrase", string(resp), err)
-		return nil, err
+		return nil, transportError("SendTransactionWithPassphrase", err)
 	}
 
 	var response SendTransactionResponse
@@ -158,14 +227,22 @@ func (admin *Admin) SendTransactionWithPassphrase(req SendTransactionPassphraseR
 		logError("SendTransactionWithPassphrase", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("SendTransactionWithPassphrase", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) StartPprof(req PprofRequest) (*PprofResponse, error) {
-	resp, err := admin.HttpRequest.Post("/admin/pprof", req)
+	return admin.StartPprofWithContext(context.Background(), req)
+}
+
+func (admin *Admin) StartPprofWithContext(ctx context.Context, req PprofRequest) (*PprofResponse, error) {
+	resp, err := admin.HttpRequest.PostWithContext(ctx, "/admin/pprof", req)
 	if err != nil {
 		logError("StartPprof", string(resp), err)
-		return nil, err
+		return nil, transportError("StartPprof", err)
 	}
 
 	var response PprofResponse
@@ -174,14 +251,22 @@ func (admin *Admin) StartPprof(req PprofRequest) (*PprofResponse, error) {
 		logError("StartPprof", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("StartPprof", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (admin *Admin) GetConfig() (*GetConfigResponse, error) {
-	resp, err := admin.HttpRequest.Get("/admin/getConfig", nil)
+	return admin.GetConfigWithContext(context.Background())
+}
+
+func (admin *Admin) GetConfigWithContext(ctx context.Context) (*GetConfigResponse, error) {
+	resp, err := admin.HttpRequest.GetWithContext(ctx, "/admin/getConfig", nil)
 	if err != nil {
 		logError("GetConfig", string(resp), err)
-		return nil, err
+		return nil, transportError("GetConfig", err)
 	}
 
 	var response GetConfigResponse
@@ -190,5 +275,9 @@ func (admin *Admin) GetConfig() (*GetConfigResponse, error) {
 		logError("GetConfig", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GetConfig", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
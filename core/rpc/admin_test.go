@@ -1,175 +1,192 @@
-package rpc
+package rpc_test
 
 import (
 	"testing"
-	"github.com/vigozhang/neb-go/utils/httprequest"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/rpc/rpctest"
 	"github.com/vigozhang/neb-go/utils"
 )
 
-var requestlocal = httprequest.NewHttpRequest(httprequest.LocalNet, httprequest.APIVersion1)
-var neblocal = NewNeb(requestlocal)
-var admin = neblocal.Admin
-
-const TestLocalAddress = "n1KkgcnqQAapJPSrevvAbC1Ze9SuiAVtYwD"
+// unlockTestAccount unlocks acc on the node for the sends and signs without
+// passphrase.
+func unlockTestAccount(t *testing.T, neb *rpc.Neb, acc *account.Account) {
+	_, err := neb.Admin.UnlockAccount(rpc.UnlockAccountRequest{
+		Address:    acc.GetAddressString(),
+		Passphrase: TestPassphrase,
+		Duration:   100000000000,
+	})
+	if err != nil {
+		t.Fatal("unlockTestAccount failed:", err)
+	}
+}
 
 func TestAdmin_NodeInfo(t *testing.T) {
-	resp, err := admin.NodeInfo()
-	if err != nil {
-		t.Error("TestAdmin_NodeInfo failed")
+	_, neb, _ := newTestNeb(t)
+	resp, err := neb.Admin.NodeInfo()
+	if err != nil || resp.Result.ChainId != rpctest.DefaultChainID {
+		t.Error("TestAdmin_NodeInfo failed:", err)
 	} else {
 		t.Log("TestAdmin_NodeInfo Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestAdmin_Accounts(t *testing.T) {
-	resp, err := admin.Accounts()
-	if err != nil {
-		t.Error("TestAdmin_Accounts failed")
+	_, neb, acc := newTestNeb(t)
+	resp, err := neb.Admin.Accounts()
+	if err != nil || len(resp.Result.Addresses) != 1 || resp.Result.Addresses[0] != acc.GetAddressString() {
+		t.Error("TestAdmin_Accounts failed:", err)
 	} else {
 		t.Log("TestAdmin_Accounts Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestAdmin_NewAccount(t *testing.T) {
-	// n1KkgcnqQAapJPSrevvAbC1Ze9SuiAVtYwD
-	req := NewAccountRequest{
-		Passphrase: "123456",
+	_, neb, _ := newTestNeb(t)
+	req := rpc.NewAccountRequest{
+		Passphrase: TestPassphrase,
 	}
-	resp, err := admin.NewAccount(req)
-	if err != nil {
-		t.Error("TestAdmin_NewAccount failed")
+	resp, err := neb.Admin.NewAccount(req)
+	if err != nil || !account.IsValidAddress(resp.Result.Address) {
+		t.Error("TestAdmin_NewAccount failed:", err)
 	} else {
 		t.Log("TestAdmin_NewAccount Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestAdmin_UnlockAccount(t *testing.T) {
-	req := UnlockAccountRequest{
-		Address:    TestLocalAddress,
-		Passphrase: "123456",
+	_, neb, acc := newTestNeb(t)
+	req := rpc.UnlockAccountRequest{
+		Address:    acc.GetAddressString(),
+		Passphrase: TestPassphrase,
 		// The unit is ns
 		Duration: 100000000000,
 	}
-	resp, err := admin.UnlockAccount(req)
-	if err != nil {
-		t.Error("TestAdmin_UnlockAccount failed")
+	resp, err := neb.Admin.UnlockAccount(req)
+	if err != nil || !resp.Result.Result {
+		t.Error("TestAdmin_UnlockAccount failed:", err)
 	} else {
 		t.Log("TestAdmin_UnlockAccount Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestAdmin_LockAccount(t *testing.T) {
-	req := LockAccountRequest{
-		Address: TestLocalAddress,
-	}
-	resp, err := admin.LockAccount(req)
-	if err != nil {
-		t.Error("TestAdmin_LockAccount failed")
+	_, neb, acc := newTestNeb(t)
+	unlockTestAccount(t, neb, acc)
+	req := rpc.LockAccountRequest{
+		Address: acc.GetAddressString(),
+	}
+	resp, err := neb.Admin.LockAccount(req)
+	if err != nil || !resp.Result.Result {
+		t.Error("TestAdmin_LockAccount failed:", err)
 	} else {
 		t.Log("TestAdmin_LockAccount Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestAdmin_SendTransaction(t *testing.T) {
-	//contract := ContractRequest{
-	//	Function: "getCreatedList",
-	//	Args:     string(utils.EncodeToJsonBytes([]string{TestNetAccountAddress})),
-	//}
-
-	req := TransactionRequest{
-		From:     TestLocalAddress,
-		To:       "n1GmkKH6nBMw4rrjt16RrJ9WcgvKUtAZP1s",
+	node, neb, acc := newTestNeb(t)
+	unlockTestAccount(t, neb, acc)
+
+	req := rpc.TransactionRequest{
+		From:     acc.GetAddressString(),
+		To:       TestToAddress,
 		Value:    "100",
-		Nonce:    3,
+		Nonce:    1,
 		GasPrice: "1000000",
 		GasLimit: "2000000",
-		//Contract: &contract,
 	}
 
-	resp, err := admin.SendTransaction(req)
-	if err != nil {
-		t.Error("TestAdmin_SendTransaction failed")
+	resp, err := neb.Admin.SendTransaction(req)
+	if err != nil || node.Balance(TestToAddress).Int64() != 100 {
+		t.Error("TestAdmin_SendTransaction failed:", err)
 	} else {
 		t.Log("TestAdmin_SendTransaction Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestAdmin_SignHash(t *testing.T) {
-	req := SignHashRequest{
-		Address: TestLocalAddress,
+	_, neb, acc := newTestNeb(t)
+	unlockTestAccount(t, neb, acc)
+	req := rpc.SignHashRequest{
+		Address: acc.GetAddressString(),
 		Hash:    "W+rOKNqs/tlvz02ez77yIYMCOr2EubpuNh5LvmwceI0=",
 		Alg:     1,
 	}
 
-	resp, err := admin.SignHash(req)
-	if err != nil {
-		t.Error("TestAdmin_SignHash failed")
+	resp, err := neb.Admin.SignHash(req)
+	if err != nil || len(resp.Result.Data) != 65 {
+		t.Error("TestAdmin_SignHash failed:", err)
 	} else {
 		t.Log("TestAdmin_SignHash Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestAdmin_SignTransactionWithPassphrase(t *testing.T) {
-	tx := TransactionRequest{
-		From:     TestLocalAddress,
-		To:       "n1GmkKH6nBMw4rrjt16RrJ9WcgvKUtAZP1s",
+	_, neb, acc := newTestNeb(t)
+	tx := rpc.TransactionRequest{
+		From:     acc.GetAddressString(),
+		To:       TestToAddress,
 		Value:    "100",
 		Nonce:    3,
 		GasPrice: "1000000",
 		GasLimit: "2000000",
 	}
 
-	req := SignTransactionPassphraseRequest{
+	req := rpc.SignTransactionPassphraseRequest{
 		Transaction: &tx,
-		Passphrase:  "123456",
+		Passphrase:  TestPassphrase,
 	}
-	resp, err := admin.SignTransactionWithPassphrase(req)
-	if err != nil {
-		t.Error("TestAdmin_SignTransactionWithPassphrase failed")
+	resp, err := neb.Admin.SignTransactionWithPassphrase(req)
+	if err != nil || len(resp.Result.Data) == 0 {
+		t.Error("TestAdmin_SignTransactionWithPassphrase failed:", err)
 	} else {
 		t.Log("TestAdmin_SignTransactionWithPassphrase Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestAdmin_SendTransactionWithPassphrase(t *testing.T) {
-	tx := TransactionRequest{
-		From:     TestLocalAddress,
-		To:       "n1GmkKH6nBMw4rrjt16RrJ9WcgvKUtAZP1s",
+	node, neb, acc := newTestNeb(t)
+	tx := rpc.TransactionRequest{
+		From:     acc.GetAddressString(),
+		To:       TestToAddress,
 		Value:    "100",
-		Nonce:    3,
+		Nonce:    1,
 		GasPrice: "1000000",
 		GasLimit: "2000000",
 	}
 
-	req := SendTransactionPassphraseRequest{
+	req := rpc.SendTransactionPassphraseRequest{
 		Transaction: &tx,
-		Passphrase:  "123456",
+		Passphrase:  TestPassphrase,
 	}
-	resp, err := admin.SendTransactionWithPassphrase(req)
-	if err != nil {
-		t.Error("TestAdmin_SendTransactionWithPassphrase failed")
+	resp, err := neb.Admin.SendTransactionWithPassphrase(req)
+	if err != nil || node.Balance(TestToAddress).Int64() != 100 {
+		t.Error("TestAdmin_SendTransactionWithPassphrase failed:", err)
 	} else {
 		t.Log("TestAdmin_SendTransactionWithPassphrase Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
-
 }
 
 func TestAdmin_StartPprof(t *testing.T) {
-	req := PprofRequest{
+	_, neb, _ := newTestNeb(t)
+	req := rpc.PprofRequest{
 		Listen: "0.0.0.0:10086",
 	}
-	resp, err := admin.StartPprof(req)
-	if err != nil {
-		t.Error("TestAdmin_StartPprof failed")
+	resp, err := neb.Admin.StartPprof(req)
+	if err != nil || !resp.Result.Result {
+		t.Error("TestAdmin_StartPprof failed:", err)
 	} else {
 		t.Log("TestAdmin_StartPprof Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestAdmin_GetConfig(t *testing.T) {
-	resp, err := admin.GetConfig()
-	if err != nil {
-		t.Error("TestAdmin_GetConfig failed")
+	_, neb, _ := newTestNeb(t)
+	resp, err := neb.Admin.GetConfig()
+	if err != nil || resp.Result.Config.Chain.ChainId != rpctest.DefaultChainID {
+		t.Error("TestAdmin_GetConfig failed:", err)
 	} else {
 		t.Log("TestAdmin_GetConfig Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
@@ -1,32 +1,47 @@
 package rpc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
-	"bytes"
 	"bufio"
-	"net/http"
+	"io"
 
 	"github.com/vigozhang/neb-go/utils/httprequest"
 )
 
 type Api struct {
-	HttpRequest *httprequest.HttpRequest
+	HttpRequest httprequest.Transport
+	// RetryPolicy applies to the read-only calls and SendRawTransaction,
+	// nil disables retries.
+	RetryPolicy *RetryPolicy
 }
 
 func NewApi(neb *Neb) *Api {
-	return &Api{neb.HttpRequest}
+	policy := DefaultRetryPolicy
+	return &Api{neb.HttpRequest, &policy}
 }
 
-func (api *Api) SetRequest(request *httprequest.HttpRequest) {
+func (api *Api) SetRequest(request httprequest.Transport) {
 	api.HttpRequest = request
 }
 
+func (api *Api) SetRetryPolicy(policy *RetryPolicy) {
+	api.RetryPolicy = policy
+}
+
 func (api *Api) GetNebState() (*GetNebStateResponse, error) {
-	resp, err := api.HttpRequest.Get("/user/nebstate", nil)
+	return api.GetNebStateWithContext(context.Background())
+}
+
+func (api *Api) GetNebStateWithContext(ctx context.Context) (*GetNebStateResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.GetWithContext(ctx, "/user/nebstate", nil)
+	})
 	if err != nil {
 		logError("GetNebState", string(resp), err)
-		return nil, err
+		return nil, transportError("GetNebState", err)
 	}
 
 	var response GetNebStateResponse
@@ -35,14 +50,24 @@ func (api *Api) GetNebState() (*GetNebStateResponse, error) {
 		logError("GetNebState", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GetNebState", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) LatestIrreversibleBlock() (*BlockResponse, error) {
-	resp, err := api.HttpRequest.Get("/user/lib", nil)
+	return api.LatestIrreversibleBlockWithContext(context.Background())
+}
+
+func (api *Api) LatestIrreversibleBlockWithContext(ctx context.Context) (*BlockResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.GetWithContext(ctx, "/user/lib", nil)
+	})
 	if err != nil {
 		logError("LatestIrreversibleBlock", string(resp), err)
-		return nil, err
+		return nil, transportError("LatestIrreversibleBlock", err)
 	}
 
 	var response BlockResponse
@@ -51,14 +76,24 @@ func (api *Api) LatestIrreversibleBlock() (*BlockResponse, error) {
 		logError("LatestIrreversibleBlock", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("LatestIrreversibleBlock", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) GetAccountState(req GetAccountStateRequest) (*GetAccountStateResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/accountstate", req)
+	return api.GetAccountStateWithContext(context.Background(), req)
+}
+
+func (api *Api) GetAccountStateWithContext(ctx context.Context, req GetAccountStateRequest) (*GetAccountStateResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.PostWithContext(ctx, "/user/accountstate", req)
+	})
 	if err != nil {
 		logError("GetAccountState", string(resp), err)
-		return nil, err
+		return nil, transportError("GetAccountState", err)
 	}
 
 	var response GetAccountStateResponse
@@ -67,14 +102,24 @@ func (api *Api) GetAccountState(req GetAccountStateRequest) (*GetAccountStateRes
 		logError("GetAccountState", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GetAccountState", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) Call(req TransactionRequest) (*CallResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/call", req)
+	return api.CallWithContext(context.Background(), req)
+}
+
+func (api *Api) CallWithContext(ctx context.Context, req TransactionRequest) (*CallResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.PostWithContext(ctx, "/user/call", req)
+	})
 	if err != nil {
 		logError("Call", string(resp), err)
-		return nil, err
+		return nil, transportError("Call", err)
 	}
 
 	var response CallResponse
@@ -83,13 +128,32 @@ func (api *Api) Call(req TransactionRequest) (*CallResponse, error) {
 		logError("Call", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("Call", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) SendRawTransaction(req SendRawTransactionRequest) (*SendTransactionResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/rawtransaction", req)
+	return api.SendRawTransactionWithContext(context.Background(), req)
+}
+
+// SendRawTransactionWithContext is retried with the api RetryPolicy. Resending
+// a signed transaction is safe: when an earlier attempt reached the node the
+// duplicated transaction error is turned into a successful response.
+func (api *Api) SendRawTransactionWithContext(ctx context.Context, req SendRawTransactionRequest) (*SendTransactionResponse, error) {
+	attempts := 0
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		attempts++
+		return api.HttpRequest.PostWithContext(ctx, "/user/rawtransaction", req)
+	})
 	if err != nil {
 		logError("SendRawTransaction", string(resp), err)
+		err = transportError("SendRawTransaction", err)
+		if attempts > 1 && errors.Is(err, ErrDuplicatedTransaction) {
+			return duplicatedTransactionResponse(req)
+		}
 		return nil, err
 	}
 
@@ -99,14 +163,27 @@ func (api *Api) SendRawTransaction(req SendRawTransactionRequest) (*SendTransact
 		logError("SendRawTransaction", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("SendRawTransaction", response.Error)
+	if err != nil {
+		if attempts > 1 && errors.Is(err, ErrDuplicatedTransaction) {
+			return duplicatedTransactionResponse(req)
+		}
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) GetBlockByHash(req GetBlockByHashRequest) (*BlockResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/getBlockByHash", req)
+	return api.GetBlockByHashWithContext(context.Background(), req)
+}
+
+func (api *Api) GetBlockByHashWithContext(ctx context.Context, req GetBlockByHashRequest) (*BlockResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.PostWithContext(ctx, "/user/getBlockByHash", req)
+	})
 	if err != nil {
 		logError("GetBlockByHash", string(resp), err)
-		return nil, err
+		return nil, transportError("GetBlockByHash", err)
 	}
 
 	var response BlockResponse
@@ -115,14 +192,24 @@ func (api *Api) GetBlockByHash(req GetBlockByHashRequest) (*BlockResponse, error
 		logError("GetBlockByHash", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GetBlockByHash", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) GetBlockByHeight(req GetBlockByHeightRequest) (*BlockResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/getBlockByHeight", req)
+	return api.GetBlockByHeightWithContext(context.Background(), req)
+}
+
+func (api *Api) GetBlockByHeightWithContext(ctx context.Context, req GetBlockByHeightRequest) (*BlockResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.PostWithContext(ctx, "/user/getBlockByHeight", req)
+	})
 	if err != nil {
 		logError("GetBlockByHeight", string(resp), err)
-		return nil, err
+		return nil, transportError("GetBlockByHeight", err)
 	}
 
 	var response BlockResponse
@@ -131,14 +218,24 @@ func (api *Api) GetBlockByHeight(req GetBlockByHeightRequest) (*BlockResponse, e
 		logError("GetBlockByHeight", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GetBlockByHeight", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) GetTransactionReceipt(req HashRequest) (*TransactionResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/getTransactionReceipt", req)
+	return api.GetTransactionReceiptWithContext(context.Background(), req)
+}
+
+func (api *Api) GetTransactionReceiptWithContext(ctx context.Context, req HashRequest) (*TransactionResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.PostWithContext(ctx, "/user/getTransactionReceipt", req)
+	})
 	if err != nil {
 		logError("GetTransactionReceipt", string(resp), err)
-		return nil, err
+		return nil, transportError("GetTransactionReceipt", err)
 	}
 
 	var response TransactionResponse
@@ -147,14 +244,24 @@ func (api *Api) GetTransactionReceipt(req HashRequest) (*TransactionResponse, er
 		logError("GetTransactionReceipt", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GetTransactionReceipt", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) GetTransactionByContract(req GetTransactionByContractRequest) (*TransactionResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/getTransactionByContract", req)
+	return api.GetTransactionByContractWithContext(context.Background(), req)
+}
+
+func (api *Api) GetTransactionByContractWithContext(ctx context.Context, req GetTransactionByContractRequest) (*TransactionResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.PostWithContext(ctx, "/user/getTransactionByContract", req)
+	})
 	if err != nil {
 		logError("GetTransactionByContract", string(resp), err)
-		return nil, err
+		return nil, transportError("GetTransactionByContract", err)
 	}
 
 	var response TransactionResponse
@@ -163,23 +270,38 @@ func (api *Api) GetTransactionByContract(req GetTransactionByContractRequest) (*
 		logError("GetTransactionByContract", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GetTransactionByContract", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) Subscribe(req SubscribeRequest, callback func(response *SubscribeResponse)) (error) {
-	err := postStreamForSubscribe(api.HttpRequest, "/user/subscribe", req, callback)
+	return api.SubscribeWithContext(context.Background(), req, callback)
+}
+
+// SubscribeWithContext is like Subscribe but returns once ctx is cancelled.
+func (api *Api) SubscribeWithContext(ctx context.Context, req SubscribeRequest, callback func(response *SubscribeResponse)) (error) {
+	err := postStreamForSubscribe(ctx, api.HttpRequest, "/user/subscribe", req, callback)
 	if err != nil {
 		log.Printf("Subscribe error:%s", err)
-		return err
+		return transportError("Subscribe", err)
 	}
 	return nil
 }
 
 func (api *Api) GasPrice() (*GasPriceResponse, error) {
-	resp, err := api.HttpRequest.Get("/user/getGasPrice", nil)
+	return api.GasPriceWithContext(context.Background())
+}
+
+func (api *Api) GasPriceWithContext(ctx context.Context) (*GasPriceResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.GetWithContext(ctx, "/user/getGasPrice", nil)
+	})
 	if err != nil {
 		logError("GasPrice", string(resp), err)
-		return nil, err
+		return nil, transportError("GasPrice", err)
 	}
 
 	var response GasPriceResponse
@@ -188,14 +310,24 @@ func (api *Api) GasPrice() (*GasPriceResponse, error) {
 		logError("GasPrice", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GasPrice", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) EstimateGas(req TransactionRequest) (*GasResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/estimateGas", req)
+	return api.EstimateGasWithContext(context.Background(), req)
+}
+
+func (api *Api) EstimateGasWithContext(ctx context.Context, req TransactionRequest) (*GasResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.PostWithContext(ctx, "/user/estimateGas", req)
+	})
 	if err != nil {
 		logError("EstimateGas", string(resp), err)
-		return nil, err
+		return nil, transportError("EstimateGas", err)
 	}
 
 	var response GasResponse
@@ -204,14 +336,24 @@ func (api *Api) EstimateGas(req TransactionRequest) (*GasResponse, error) {
 		logError("EstimateGas", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("EstimateGas", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) GetEventsByHash(req HashRequest) (*EventsResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/getEventsByHash", req)
+	return api.GetEventsByHashWithContext(context.Background(), req)
+}
+
+func (api *Api) GetEventsByHashWithContext(ctx context.Context, req HashRequest) (*EventsResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.PostWithContext(ctx, "/user/getEventsByHash", req)
+	})
 	if err != nil {
 		logError("GetEventsByHash", string(resp), err)
-		return nil, err
+		return nil, transportError("GetEventsByHash", err)
 	}
 
 	var response EventsResponse
@@ -220,14 +362,24 @@ func (api *Api) GetEventsByHash(req HashRequest) (*EventsResponse, error) {
 		logError("GetEventsByHash", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GetEventsByHash", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
 func (api *Api) GetDynasty(req ByBlockHeightRequest) (*GetDynastyResponse, error) {
-	resp, err := api.HttpRequest.Post("/user/dynasty", req)
+	return api.GetDynastyWithContext(context.Background(), req)
+}
+
+func (api *Api) GetDynastyWithContext(ctx context.Context, req ByBlockHeightRequest) (*GetDynastyResponse, error) {
+	resp, err := api.RetryPolicy.do(ctx, func() ([]byte, error) {
+		return api.HttpRequest.PostWithContext(ctx, "/user/dynasty", req)
+	})
 	if err != nil {
 		logError("GetDynasty", string(resp), err)
-		return nil, err
+		return nil, transportError("GetDynasty", err)
 	}
 
 	var response GetDynastyResponse
@@ -236,6 +388,10 @@ func (api *Api) GetDynasty(req ByBlockHeightRequest) (*GetDynastyResponse, error
 		logError("GetDynasty", string(resp), err)
 		return nil, err
 	}
+	err = newNodeError("GetDynasty", response.Error)
+	if err != nil {
+		return nil, err
+	}
 	return &response, nil
 }
 
@@ -243,44 +399,33 @@ func logError(method string, resp string, err error) {
 	log.Printf("%s error:%s, response is %s", method, err, resp)
 }
 
-func postStreamForSubscribe(req *httprequest.HttpRequest, api string, reqBody interface{}, callback func(response *SubscribeResponse)) (error) {
-	url := req.CreateUrl(api)
-	contentType := "application/json"
-
-	jsonReqBody, err := json.Marshal(reqBody)
-
-	if err != nil {
-		return err
-	}
-
-	response, err := http.Post(url, contentType, bytes.NewBuffer(jsonReqBody))
-	defer response.Body.Close()
-
+func postStreamForSubscribe(ctx context.Context, req httprequest.Transport, api string, reqBody interface{}, callback func(response *SubscribeResponse)) (error) {
+	stream, err := req.OpenStreamWithContext(ctx, api, reqBody)
 	if err != nil {
 		return err
 	}
+	defer stream.Close()
 
-	reader := bufio.NewReader(response.Body)
+	reader := bufio.NewReader(stream)
 	for {
 		line, err := reader.ReadBytes('\n')
 
 		if err != nil {
-			break
+			if err == io.EOF || ctx.Err() != nil {
+				break
+			}
+			return err
 		}
 
 		var resp SubscribeResponse
 		err = json.Unmarshal(line, &resp)
 		if err != nil {
-			log.Printf("%s error:%s, response is %s", "Subscribe", err, resp)
+			log.Printf("%s error:%s, response is %s", "Subscribe", err, line)
 			break
 		}
 
 		callback(&resp)
 	}
 
-	if err != nil {
-		return err
-	}
-
 	return nil
 }
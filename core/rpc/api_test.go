@@ -1,62 +1,80 @@
-package rpc
+package rpc_test
 
 import (
-	"testing"
-	"log"
+	"context"
 	"math/big"
+	"testing"
+	"time"
 
-	"github.com/vigozhang/neb-go/utils/httprequest"
-	"github.com/vigozhang/neb-go/utils"
-	"github.com/vigozhang/neb-go/core/transaction"
 	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/rpc/rpctest"
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils"
 )
 
-var requesttest = httprequest.NewHttpRequest(httprequest.TestNet, httprequest.APIVersion1)
-var nebtest = NewNeb(requesttest)
-var api = nebtest.Api
+func TestApi_GetNebState(t *testing.T) {
+	neb, _ := newApiNeb(t)
+	resp, err := neb.Api.GetNebState()
+	if err != nil || resp.Result.ChainId != rpctest.DefaultChainID {
+		t.Error("TestApi_GetNebState failed:", err)
+	} else {
+		t.Log("TestApi_GetNebState Resp:", string(utils.EncodeToJsonBytes(resp)))
+	}
+}
 
-const TestNetAccountAddress = "n1UHqTFvng8vXbcoWxYECwc4shXKnrcXwdz"
-const TestNetContractAddress = "n1f5rgBtVKVEjxPBwrDcaV8H8QqxniFyhPk"
+func TestApi_GetNebStateWithContext(t *testing.T) {
+	neb, _ := newApiNeb(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-func TestApi_GetNebState(t *testing.T) {
-	resp, err := api.GetNebState()
+	resp, err := neb.Api.GetNebStateWithContext(ctx)
 	if err != nil {
-		t.Error("TestApi_GetNebState failed")
+		t.Error("TestApi_GetNebStateWithContext failed")
 	} else {
-		t.Log("TestApi_GetNebState Resp:", string(utils.EncodeToJsonBytes(resp)))
+		t.Log("TestApi_GetNebStateWithContext Resp:", string(utils.EncodeToJsonBytes(resp)))
+	}
+
+	cancel()
+	_, err = neb.Api.GetNebStateWithContext(ctx)
+	if err == nil {
+		t.Error("TestApi_GetNebStateWithContext should fail with a cancelled context")
 	}
 }
 
 func TestApi_LatestIrreversibleBlock(t *testing.T) {
-	resp, err := api.LatestIrreversibleBlock()
-	if err != nil {
-		t.Error("TestApi_LatestIrreversibleBlock failed")
+	neb, _ := newApiNeb(t)
+	resp, err := neb.Api.LatestIrreversibleBlock()
+	if err != nil || resp.Result.Height != 1 {
+		t.Error("TestApi_LatestIrreversibleBlock failed:", err)
 	} else {
 		t.Log("TestApi_LatestIrreversibleBlock Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestApi_GetAccountState(t *testing.T) {
-	req := GetAccountStateRequest{
-		Address: TestNetAccountAddress,
+	neb, acc := newApiNeb(t)
+	req := rpc.GetAccountStateRequest{
+		Address: acc.GetAddressString(),
 	}
-	resp, err := api.GetAccountState(req)
-	if err != nil {
-		t.Error("TestApi_GetAccountState failed")
+	resp, err := neb.Api.GetAccountState(req)
+	if err != nil || resp.Result.Balance != "1000000000000000000" {
+		t.Error("TestApi_GetAccountState failed:", err)
 	} else {
 		t.Log("TestApi_GetAccountState Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestApi_Call(t *testing.T) {
-	contract := ContractRequest{
+	neb, acc := newApiNeb(t)
+	contract := rpc.ContractRequest{
 		Function: "getCreatedList",
-		Args:     string(utils.EncodeToJsonBytes([]string{TestNetAccountAddress})),
+		Args:     string(utils.EncodeToJsonBytes([]string{acc.GetAddressString()})),
 	}
 
-	req := TransactionRequest{
-		From:     TestNetAccountAddress,
-		To:       TestNetContractAddress,
+	req := rpc.TransactionRequest{
+		From:     acc.GetAddressString(),
+		To:       TestContractAddress,
 		Value:    "0",
 		Nonce:    3,
 		GasPrice: "1000000",
@@ -64,106 +82,152 @@ func TestApi_Call(t *testing.T) {
 		Contract: &contract,
 	}
 
-	resp, err := api.Call(req)
-	if err != nil {
-		t.Error("TestApi_Call failed")
+	resp, err := neb.Api.Call(req)
+	if err != nil || resp.Result.Result != "[]" {
+		t.Error("TestApi_Call failed:", err)
 	} else {
 		t.Log("TestApi_Call Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
-
 }
 
 func TestApi_SendRawTransaction(t *testing.T) {
-	acc := loadAccount()
+	neb, acc := newApiNeb(t)
 
 	contract := transaction.Contract{
 		Function: "getCreatedList",
-		Args:     string(utils.EncodeToJsonBytes([]string{"n1UHqTFvng8vXbcoWxYECwc4shXKnrcXwdz"})),
+		Args:     string(utils.EncodeToJsonBytes([]string{acc.GetAddressString()})),
 	}
 
 	txOpts := transaction.TransactionOptions{
-		ChainID:  1001,
+		ChainID:  rpctest.DefaultChainID,
 		From:     acc,
-		To:       "n1f5rgBtVKVEjxPBwrDcaV8H8QqxniFyhPk",
+		To:       TestContractAddress,
 		Value:    big.NewInt(0),
-		Nonce:    6,
+		Nonce:    1,
 		GasPrice: big.NewInt(1000000),
 		GasLimit: big.NewInt(2000000),
 		Contract: &contract,
 	}
 
 	tx := transaction.NewTransaction(txOpts)
+	tx.Timestamp = TestTimestamp
 	tx.SignTransaction()
 	raw, _ := tx.ToProtoString()
 
-	req := SendRawTransactionRequest{
+	req := rpc.SendRawTransactionRequest{
 		Data: raw,
 	}
 
-	resp, err := api.SendRawTransaction(req)
+	resp, err := neb.Api.SendRawTransaction(req)
+	if err == nil {
+		var state *rpc.GetAccountStateResponse
+		state, err = neb.Api.GetAccountState(rpc.GetAccountStateRequest{Address: acc.GetAddressString()})
+		if err == nil && state.Result.Nonce != 1 {
+			t.Error("TestApi_SendRawTransaction nonce", state.Result.Nonce)
+		}
+	}
 	if err != nil {
-		t.Error("TestApi_SendRawTransaction failed")
+		t.Error("TestApi_SendRawTransaction failed:", err)
 	} else {
 		t.Log("TestApi_SendRawTransaction Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
-
 }
 
 func TestApi_GetBlockByHash(t *testing.T) {
-	testNetBlockHash := "8371d448bdc86218a38c9e89c17c8665a4bbb0bebb0dcbf094712447a3d8fcf1"
-	req := GetBlockByHashRequest{
-		Hash: testNetBlockHash,
-	}
-	resp, err := api.GetBlockByHash(req)
+	neb, _ := newApiNeb(t)
+	block, err := neb.Api.LatestIrreversibleBlock()
 	if err != nil {
-		t.Error("TestApi_GetBlockByHash failed")
+		t.Fatal("TestApi_GetBlockByHash failed:", err)
+	}
+	req := rpc.GetBlockByHashRequest{
+		Hash: block.Result.Hash,
+	}
+	resp, err := neb.Api.GetBlockByHash(req)
+	if err != nil || resp.Result.Height != 1 {
+		t.Error("TestApi_GetBlockByHash failed:", err)
 	} else {
 		t.Log("TestApi_GetBlockByHash Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestApi_GetBlockByHeight(t *testing.T) {
-	testNetBlockHeight := uint64(377161)
-	req := GetBlockByHeightRequest{
-		Height: testNetBlockHeight,
+	neb, acc := newApiNeb(t)
+	hash := sendApiTransaction(t, neb, acc, 1)
+	req := rpc.GetBlockByHeightRequest{
+		Height:              2,
+		FullFillTransaction: true,
 	}
-	resp, err := api.GetBlockByHeight(req)
-	if err != nil {
-		t.Error("TestApi_GetBlockByHeight failed")
+	resp, err := neb.Api.GetBlockByHeight(req)
+	if err != nil || len(resp.Result.Transactions) != 1 || resp.Result.Transactions[0].Hash != hash {
+		t.Error("TestApi_GetBlockByHeight failed:", err)
 	} else {
 		t.Log("TestApi_GetBlockByHeight Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestApi_Subscribe(t *testing.T) {
-	req := SubscribeRequest{
+	neb, acc := newApiNeb(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx := transaction.NewTransaction(transaction.TransactionOptions{
+		ChainID:  rpctest.DefaultChainID,
+		From:     acc,
+		To:       TestToAddress,
+		Value:    big.NewInt(0),
+		Nonce:    1,
+		GasPrice: big.NewInt(rpctest.DefaultGasPrice),
+		GasLimit: big.NewInt(20000),
+		Contract: &transaction.Contract{},
+	})
+	tx.Timestamp = TestTimestamp
+	tx.SignTransaction()
+	raw, _ := tx.ToProtoString()
+
+	sent := make(chan error, 1)
+	go func() {
+		// give the stream the time to open before the transaction is sent
+		time.Sleep(100 * time.Millisecond)
+		_, err := neb.Api.SendRawTransaction(rpc.SendRawTransactionRequest{Data: raw})
+		sent <- err
+	}()
+
+	req := rpc.SubscribeRequest{
 		Topics: []string{"chain.pendingTransaction", "chain.sendTransaction", "chain.linkBlock"},
 	}
-
-	err := api.Subscribe(req, subscribeCallback)
-	if err != nil {
-		t.Error("TestApi_Subscribe failed")
+	var topics []string
+	err := neb.Api.SubscribeWithContext(ctx, req, func(line *rpc.SubscribeResponse) {
+		topics = append(topics, line.Result.Topic)
+		cancel()
+	})
+	if err != nil || len(topics) == 0 || topics[0] != "chain.pendingTransaction" {
+		t.Errorf("TestApi_Subscribe failed %v: %v", topics, err)
+	}
+	if err := <-sent; err != nil {
+		t.Error("TestApi_Subscribe send failed:", err)
 	}
 }
 
 func TestApi_GasPrice(t *testing.T) {
-	resp, err := api.GasPrice()
-	if err != nil {
-		t.Error("TestApi_GasPrice failed")
+	neb, _ := newApiNeb(t)
+	resp, err := neb.Api.GasPrice()
+	if err != nil || resp.Result.GasPrice != "1000000" {
+		t.Error("TestApi_GasPrice failed:", err)
 	} else {
 		t.Log("TestApi_GasPrice Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestApi_EstimateGas(t *testing.T) {
-	contract := ContractRequest{
+	neb, acc := newApiNeb(t)
+	contract := rpc.ContractRequest{
 		Function: "getCreatedList",
-		Args:     string(utils.EncodeToJsonBytes([]string{TestNetAccountAddress})),
+		Args:     string(utils.EncodeToJsonBytes([]string{acc.GetAddressString()})),
 	}
 
-	req := TransactionRequest{
-		From:     TestNetAccountAddress,
-		To:       TestNetContractAddress,
+	req := rpc.TransactionRequest{
+		From:     acc.GetAddressString(),
+		To:       TestContractAddress,
 		Value:    "0",
 		Nonce:    3,
 		GasPrice: "1000000",
@@ -171,64 +235,53 @@ func TestApi_EstimateGas(t *testing.T) {
 		Contract: &contract,
 	}
 
-	resp, err := api.EstimateGas(req)
-	if err != nil {
-		t.Error("TestApi_EstimateGas failed")
+	resp, err := neb.Api.EstimateGas(req)
+	if err != nil || resp.Result.Err != "" {
+		t.Error("TestApi_EstimateGas failed:", err)
 	} else {
 		t.Log("TestApi_EstimateGas Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
-
 }
 
 func TestApi_GetEventsByHash(t *testing.T) {
-	testNetTxHash := "0b4239206842b6ec2fd12a94f3370946f9246b2660d54c721202ba22c42ad146"
-	req := HashRequest{
-		Hash: testNetTxHash,
+	neb, acc := newApiNeb(t)
+	req := rpc.HashRequest{
+		Hash: sendApiTransaction(t, neb, acc, 1),
 	}
-	resp, err := api.GetEventsByHash(req)
-	if err != nil {
-		t.Error("TestApi_GetEventsByHash failed")
+	resp, err := neb.Api.GetEventsByHash(req)
+	if err != nil || len(resp.Result.Events) != 1 {
+		t.Error("TestApi_GetEventsByHash failed:", err)
 	} else {
 		t.Log("TestApi_GetEventsByHash Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
 
 func TestApi_GetDynasty(t *testing.T) {
-	testNetBlockHeight := uint64(377161)
-	req := ByBlockHeightRequest{
-		Height: testNetBlockHeight,
-	}
-	resp, err := api.GetDynasty(req)
+	neb, _ := newApiNeb(t)
+	state, err := neb.Api.GetNebState()
 	if err != nil {
-		t.Error("TestApi_GetDynasty failed")
+		t.Fatal("TestApi_GetDynasty failed:", err)
+	}
+	req := rpc.ByBlockHeightRequest{
+		Height: state.Result.Height,
+	}
+	resp, err := neb.Api.GetDynasty(req)
+	if err != nil || len(resp.Result.Miners) != 1 || !account.IsValidAddress(resp.Result.Miners[0]) {
+		t.Error("TestApi_GetDynasty failed:", err)
 	} else {
 		t.Log("TestApi_GetDynasty Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
-
 }
 
 func TestApi_GetTransactionReceipt(t *testing.T) {
-	testNetTxHash := "0b4239206842b6ec2fd12a94f3370946f9246b2660d54c721202ba22c42ad146"
-	req := HashRequest{
-		Hash: testNetTxHash,
+	neb, acc := newApiNeb(t)
+	req := rpc.HashRequest{
+		Hash: sendApiTransaction(t, neb, acc, 1),
 	}
-	resp, err := api.GetTransactionReceipt(req)
-	if err != nil {
-		t.Error("TestApi_GetTransactionReceipt failed")
+	resp, err := neb.Api.GetTransactionReceipt(req)
+	if err != nil || resp.Result.Status != rpc.TransactionStatusSuccess {
+		t.Error("TestApi_GetTransactionReceipt failed:", err)
 	} else {
 		t.Log("TestApi_GetTransactionReceipt Resp:", string(utils.EncodeToJsonBytes(resp)))
 	}
 }
-
-func loadAccount() *account.Account {
-	acc := account.NewAccount()
-	// keyjson
-	keyjson := ""
-	loadAcc, _ := acc.FromKey(keyjson, "passphrase", true)
-	return loadAcc
-}
-
-func subscribeCallback(line *SubscribeResponse) {
-	log.Println(line.Result.Topic)
-	log.Println(line.Result.Data)
-}
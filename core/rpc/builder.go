@@ -0,0 +1,224 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+const (
+	// DefaultGasMultiplier the safety margin applied to estimated gas
+	DefaultGasMultiplier = 1.2
+
+	// MaxGasLimit the highest gas limit the chain accepts
+	MaxGasLimit = 50000000000
+)
+
+// TransactionBuilder fills in the fields of a transaction that depend on the
+// chain state and signs it.
+type TransactionBuilder struct {
+	Api *Api
+	// GasMultiplier scales the estimated gas into the gas limit,
+	// DefaultGasMultiplier when 0.
+	GasMultiplier float64
+	// Nonces reserves the nonces when set, so transactions of one account can
+	// be built and sent concurrently.
+	Nonces *NonceManager
+	// Signer signs the transactions when set, e.g. an AdminSigner, so the from
+	// account does not need a private key. It must sign for the from address.
+	Signer transaction.Signer
+}
+
+func NewTransactionBuilder(api *Api) *TransactionBuilder {
+	return &TransactionBuilder{Api: api, GasMultiplier: DefaultGasMultiplier}
+}
+
+// Build resolves the zero fields of opts from the node and returns the signed
+// transaction:
+//
+//   - ChainID from GetNebState
+//   - Nonce as the account nonce + 1 from GetAccountState, or from Nonces
+//   - GasPrice from GasPrice
+//   - GasLimit from EstimateGas times GasMultiplier
+//
+// A nil Value sends nothing and a nil Contract and Payload build a binary
+// transfer.
+func (builder *TransactionBuilder) Build(ctx context.Context, opts transaction.TransactionOptions) (*transaction.Transaction, error) {
+	if opts.From == nil || (opts.From.GetPrivateKey() == nil && builder.Signer == nil) {
+		return nil, errors.New("transaction from account needs a private key or a signer")
+	}
+
+	reserved := false
+	if opts.Nonce == 0 && builder.Nonces != nil {
+		nonce, err := builder.Nonces.Next(ctx, opts.From.GetAddressString())
+		if err != nil {
+			return nil, err
+		}
+		opts.Nonce = nonce
+		reserved = true
+	}
+
+	tx, err := builder.build(ctx, opts)
+	if err != nil && reserved {
+		builder.Nonces.Release(opts.From.GetAddressString(), opts.Nonce)
+	}
+	return tx, err
+}
+
+func (builder *TransactionBuilder) build(ctx context.Context, opts transaction.TransactionOptions) (*transaction.Transaction, error) {
+	if opts.Value == nil {
+		opts.Value = big.NewInt(0)
+	}
+	if opts.Contract == nil {
+		opts.Contract = &transaction.Contract{}
+	}
+
+	if opts.ChainID == 0 {
+		resp, err := builder.Api.GetNebStateWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Result == nil {
+			return nil, emptyResult("GetNebState")
+		}
+		opts.ChainID = resp.Result.ChainId
+	}
+
+	if opts.Nonce == 0 {
+		req := GetAccountStateRequest{Address: opts.From.GetAddressString()}
+		resp, err := builder.Api.GetAccountStateWithContext(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Result == nil {
+			return nil, emptyResult("GetAccountState")
+		}
+		opts.Nonce = resp.Result.Nonce + 1
+	}
+
+	if opts.GasPrice == nil {
+		resp, err := builder.Api.GasPriceWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Result == nil {
+			return nil, emptyResult("GasPrice")
+		}
+		gasPrice, ok := new(big.Int).SetString(resp.Result.GasPrice, 10)
+		if !ok {
+			return nil, errors.New("invalid gas price " + resp.Result.GasPrice)
+		}
+		opts.GasPrice = gasPrice
+	}
+
+	if opts.GasLimit == nil {
+		gasLimit, err := builder.estimateGasLimit(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		opts.GasLimit = gasLimit
+	}
+
+	tx := transaction.NewTransaction(opts)
+	var err error
+	if builder.Signer != nil {
+		err = tx.SignWith(builder.Signer)
+	} else {
+		err = tx.SignTransaction()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Send builds the transaction and submits it with SendRawTransaction. With
+// Nonces set the reserved nonce is confirmed, or failed when sending fails.
+func (builder *TransactionBuilder) Send(ctx context.Context, opts transaction.TransactionOptions) (*transaction.Transaction, *SendTransactionResponse, error) {
+	reserved := opts.Nonce == 0 && builder.Nonces != nil
+	tx, err := builder.Build(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := builder.send(ctx, tx)
+	if reserved {
+		address := tx.From.GetAddressString()
+		if err != nil {
+			builder.Nonces.Fail(ctx, address, tx.Nonce, err)
+		} else {
+			builder.Nonces.Confirm(address, tx.Nonce)
+		}
+	}
+	if err != nil {
+		return tx, nil, err
+	}
+	return tx, resp, nil
+}
+
+func (builder *TransactionBuilder) send(ctx context.Context, tx *transaction.Transaction) (*SendTransactionResponse, error) {
+	raw, err := tx.ToProtoString()
+	if err != nil {
+		return nil, err
+	}
+	return builder.Api.SendRawTransactionWithContext(ctx, SendRawTransactionRequest{Data: raw})
+}
+
+func (builder *TransactionBuilder) estimateGasLimit(ctx context.Context, opts transaction.TransactionOptions) (*big.Int, error) {
+	contract := opts.Contract
+	switch payload := opts.Payload.(type) {
+	case nil:
+	case *transaction.TransactionBinaryPayload:
+		contract = &transaction.Contract{Binary: payload.Data}
+	case *transaction.TransactionCallPayload:
+		contract = &transaction.Contract{Function: payload.Function, Args: payload.Args}
+	case *transaction.TransactionDeployPayload:
+		contract = &transaction.Contract{Source: payload.Source, SourceType: payload.SourceType, Args: payload.Args}
+	default:
+		return nil, errors.New("can not estimate the gas of " + payload.Type() + " transactions, set GasLimit")
+	}
+
+	req := TransactionRequest{
+		From:     opts.From.GetAddressString(),
+		To:       opts.To,
+		Value:    opts.Value.String(),
+		Nonce:    opts.Nonce,
+		GasPrice: opts.GasPrice.String(),
+		Binary:   contract.Binary,
+	}
+	if len(contract.Source) > 0 || len(contract.Function) > 0 {
+		req.Contract = &ContractRequest{
+			Source:     contract.Source,
+			SourceType: contract.SourceType,
+			Function:   contract.Function,
+			Args:       contract.Args,
+		}
+	}
+
+	resp, err := builder.Api.EstimateGasWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Result == nil {
+		return nil, emptyResult("EstimateGas")
+	}
+	if resp.Result.Err != "" {
+		return nil, errors.New("estimate gas: " + resp.Result.Err)
+	}
+
+	gas, ok := new(big.Float).SetString(resp.Result.Gas)
+	if !ok {
+		return nil, errors.New("invalid estimated gas " + resp.Result.Gas)
+	}
+
+	multiplier := builder.GasMultiplier
+	if multiplier == 0 {
+		multiplier = DefaultGasMultiplier
+	}
+	gasLimit, _ := gas.Mul(gas, big.NewFloat(multiplier)).Float64()
+	gasLimit = math.Min(math.Ceil(gasLimit), MaxGasLimit)
+	return big.NewInt(int64(gasLimit)), nil
+}
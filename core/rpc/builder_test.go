@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"testing"
+	"context"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+// routeTransport answers every api path with a fixed body and records the
+// request bodies it received.
+type routeTransport struct {
+	bodies   map[string]string
+	requests map[string]interface{}
+}
+
+func newRouteTransport(bodies map[string]string) *routeTransport {
+	return &routeTransport{bodies: bodies, requests: make(map[string]interface{})}
+}
+
+func (r *routeTransport) GetWithContext(ctx context.Context, api string, params map[string]string) ([]byte, error) {
+	return []byte(r.bodies[api]), nil
+}
+
+func (r *routeTransport) PostWithContext(ctx context.Context, api string, reqBody interface{}) ([]byte, error) {
+	r.requests[api] = reqBody
+	return []byte(r.bodies[api]), nil
+}
+
+func (r *routeTransport) OpenStreamWithContext(ctx context.Context, api string, reqBody interface{}) (io.ReadCloser, error) {
+	return nil, io.EOF
+}
+
+func newBuilderTestAccount() *account.Account {
+	acc := account.NewAccount()
+	priv, _ := big.NewInt(0).SetString("ac3773e06ae74c0fa566b0e421d4e391333f31aef90b383f0c0e83e4873609d6", 16)
+	acc.SetPrivateKey(priv.Bytes())
+	return acc
+}
+
+func TestTransactionBuilder_Send(t *testing.T) {
+	transport := newRouteTransport(map[string]string{
+		"/user/nebstate":       `{"result":{"chain_id":1001,"height":"100"}}`,
+		"/user/accountstate":   `{"result":{"balance":"1000","nonce":"6","type":87}}`,
+		"/user/getGasPrice":    `{"result":{"gas_price":"2000000"}}`,
+		"/user/estimateGas":    `{"result":{"gas":"20000","err":""}}`,
+		"/user/rawtransaction": `{"result":{"txhash":"ok"}}`,
+	})
+	builder := NewTransactionBuilder(NewNeb(transport).Api)
+
+	opts := transaction.TransactionOptions{
+		From:  newBuilderTestAccount(),
+		To:    "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17",
+		Value: big.NewInt(10),
+	}
+	tx, resp, err := builder.Send(context.Background(), opts)
+	if err != nil {
+		t.Fatal("TestTransactionBuilder_Send failed:", err)
+	}
+
+	if tx.ChainID != 1001 || tx.Nonce != 7 || tx.GasPrice.Int64() != 2000000 || tx.GasLimit.Int64() != 24000 {
+		t.Errorf("TestTransactionBuilder_Send unexpected transaction chain %d nonce %d gas price %s gas limit %s",
+			tx.ChainID, tx.Nonce, tx.GasPrice, tx.GasLimit)
+	}
+	if tx.Sign == nil || resp.Result.Txhash != "ok" {
+		t.Error("TestTransactionBuilder_Send transaction was not signed and sent")
+	}
+
+	sent := transport.requests["/user/rawtransaction"].(SendRawTransactionRequest)
+	decoded, err := new(transaction.Transaction).FromProto(sent.Data)
+	if err != nil || decoded.Nonce != 7 {
+		t.Errorf("TestTransactionBuilder_Send sent an unexpected transaction, %v", err)
+	}
+}
+
+func TestTransactionBuilder_EstimateError(t *testing.T) {
+	transport := newRouteTransport(map[string]string{
+		"/user/estimateGas": `{"result":{"gas":"20000","err":"Call: contract failed"}}`,
+	})
+	builder := NewTransactionBuilder(NewNeb(transport).Api)
+
+	opts := transaction.TransactionOptions{
+		ChainID:  1001,
+		From:     newBuilderTestAccount(),
+		To:       "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17",
+		Nonce:    1,
+		GasPrice: big.NewInt(1000000),
+		Contract: &transaction.Contract{Function: "save", Args: "[0]"},
+	}
+	_, err := builder.Build(context.Background(), opts)
+	if err == nil {
+		t.Error("TestTransactionBuilder_EstimateError should fail when execution fails")
+	}
+}
+
+func TestTransactionBuilder_EmptyResult(t *testing.T) {
+	bodies := map[string]string{
+		"/user/nebstate":     `{"result":{"chain_id":1001,"height":"100"}}`,
+		"/user/accountstate": `{"result":{"balance":"1000","nonce":"6","type":87}}`,
+		"/user/getGasPrice":  `{"result":{"gas_price":"2000000"}}`,
+		"/user/estimateGas":  `{"result":{"gas":"20000","err":""}}`,
+	}
+	for api := range bodies {
+		routes := make(map[string]string)
+		for route, body := range bodies {
+			routes[route] = body
+		}
+		routes[api] = `{"result":null}`
+		builder := NewTransactionBuilder(NewNeb(newRouteTransport(routes)).Api)
+
+		opts := transaction.TransactionOptions{From: newBuilderTestAccount(), To: "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17"}
+		if _, err := builder.Build(context.Background(), opts); !errors.Is(err, ErrEmptyResult) {
+			t.Errorf("TestTransactionBuilder_EmptyResult %s: %v", api, err)
+		}
+	}
+}
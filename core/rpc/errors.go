@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+var (
+	// ErrNonceTooLow the transaction nonce is not bigger than the account nonce
+	ErrNonceTooLow = errors.New("nonce is too low")
+
+	// ErrNonceTooHigh the transaction nonce is too far ahead of the account nonce
+	ErrNonceTooHigh = errors.New("nonce is too high")
+
+	// ErrBelowGasPrice the transaction gas price is below the node's lowest gas price
+	ErrBelowGasPrice = errors.New("below the gas price")
+
+	// ErrTransactionNotFound the transaction is unknown to the node
+	ErrTransactionNotFound = errors.New("transaction not found")
+
+	// ErrContractCheckFailed the contract of the transaction could not be checked
+	ErrContractCheckFailed = errors.New("contract check failed")
+
+	// ErrDuplicatedTransaction the transaction is already known to the node
+	ErrDuplicatedTransaction = errors.New("duplicated transaction")
+
+	// ErrEmptyResult the node answered without an error nor a result
+	ErrEmptyResult = errors.New("empty result")
+
+	// ErrNoBlockHeight the receipt does not tell the block of the transaction,
+	// its confirmations can not be counted
+	ErrNoBlockHeight = errors.New("receipt without block height")
+)
+
+// nodeErrorMessages maps the sentinel errors to the messages the node returns
+// for them.
+var nodeErrorMessages = map[error][]string{
+	ErrNonceTooLow: {
+		"nonce is too low",
+		"transaction's nonce is invalid, should bigger than the from's nonce",
+		"cannot accept a transaction with smaller nonce",
+	},
+	ErrNonceTooHigh: {
+		"cannot accept a transaction with too bigger nonce",
+	},
+	ErrBelowGasPrice: {
+		"below the gas price",
+	},
+	ErrTransactionNotFound: {
+		"transaction not found",
+	},
+	ErrContractCheckFailed: {
+		"contract check failed",
+	},
+	ErrDuplicatedTransaction: {
+		"duplicated transaction",
+	},
+}
+
+// NodeError is returned when the node answers a call with an error, either as
+// a non-2xx status or as the error field of the response payload.
+type NodeError struct {
+	// Method the Api or Admin method that failed.
+	Method string
+	// StatusCode the http status of the response.
+	StatusCode int
+	// Message the error message returned by the node.
+	Message string
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("%s: node error (status %d): %s", e.Method, e.StatusCode, e.Message)
+}
+
+// Is reports whether the node message matches one of the sentinel errors, so
+// callers can use errors.Is(err, rpc.ErrNonceTooLow).
+func (e *NodeError) Is(target error) bool {
+	for _, message := range nodeErrorMessages[target] {
+		if strings.Contains(e.Message, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// newNodeError returns a *NodeError for a non-empty error field of a response.
+func newNodeError(method string, message string) error {
+	if message == "" {
+		return nil
+	}
+	return &NodeError{Method: method, StatusCode: http.StatusOK, Message: message}
+}
+
+// emptyResult is the error of a response of method without a result.
+func emptyResult(method string) error {
+	return fmt.Errorf("%s: %w", method, ErrEmptyResult)
+}
+
+// transportError converts a non-2xx status returned by the transport into a
+// *NodeError; other errors are returned unchanged.
+func transportError(method string, err error) error {
+	var statusErr *httprequest.StatusError
+	if !errors.As(err, &statusErr) {
+		return err
+	}
+
+	var payload struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(statusErr.Body))
+	if json.Unmarshal(statusErr.Body, &payload) == nil && payload.Error != "" {
+		message = payload.Error
+	}
+	if message == "" {
+		message = http.StatusText(statusErr.StatusCode)
+	}
+	return &NodeError{Method: method, StatusCode: statusErr.StatusCode, Message: message}
+}
@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"testing"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+type stubTransport struct {
+	body []byte
+	err  error
+}
+
+func (s *stubTransport) GetWithContext(ctx context.Context, api string, params map[string]string) ([]byte, error) {
+	return s.body, s.err
+}
+
+func (s *stubTransport) PostWithContext(ctx context.Context, api string, reqBody interface{}) ([]byte, error) {
+	return s.body, s.err
+}
+
+func (s *stubTransport) OpenStreamWithContext(ctx context.Context, api string, reqBody interface{}) (io.ReadCloser, error) {
+	return nil, s.err
+}
+
+func TestNodeError_StatusError(t *testing.T) {
+	body := []byte(`{"error":"transaction's nonce is invalid, should bigger than the from's nonce"}`)
+	stub := &stubTransport{body, &httprequest.StatusError{StatusCode: http.StatusBadRequest, Body: body}}
+	neb := NewNeb(stub)
+
+	_, err := neb.Api.SendRawTransaction(SendRawTransactionRequest{Data: "raw"})
+
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) {
+		t.Fatalf("TestNodeError_StatusError expected *NodeError, got %v", err)
+	}
+	if nodeErr.Method != "SendRawTransaction" || nodeErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("TestNodeError_StatusError unexpected error %+v", nodeErr)
+	}
+	if !errors.Is(err, ErrNonceTooLow) {
+		t.Error("TestNodeError_StatusError should match ErrNonceTooLow")
+	}
+	if errors.Is(err, ErrBelowGasPrice) {
+		t.Error("TestNodeError_StatusError should not match ErrBelowGasPrice")
+	}
+}
+
+func TestNodeError_ResponseError(t *testing.T) {
+	stub := &stubTransport{body: []byte(`{"error":"transaction not found"}`)}
+	neb := NewNeb(stub)
+
+	resp, err := neb.Api.GetTransactionReceipt(HashRequest{Hash: "00"})
+	if resp != nil {
+		t.Error("TestNodeError_ResponseError expected nil response")
+	}
+	if !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("TestNodeError_ResponseError should match ErrTransactionNotFound, got %v", err)
+	}
+}
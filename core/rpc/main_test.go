@@ -0,0 +1,131 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/rpc/rpctest"
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+// The api tests replay the node responses of testdata/api, so they run
+// without a network. Run them with -record to send their requests to an
+// rpctest node again and rewrite the golden files. The admin tests run
+// against an rpctest node started by each test.
+var record = flag.Bool("record", false, "record the responses of an rpctest node into testdata/api")
+
+const (
+	TestContractAddress = "n1f5rgBtVKVEjxPBwrDcaV8H8QqxniFyhPk"
+	TestToAddress       = "n1GmkKH6nBMw4rrjt16RrJ9WcgvKUtAZP1s"
+	TestPassphrase      = "123456"
+	// TestPrivateKey the key of the api tests account, the replayed requests
+	// hold its address and signatures
+	TestPrivateKey = "4dbc1ee89cb356d380f6b2c0ef183d3e9a2d6a205d6d72a58a98fcd73979ed0d"
+	// TestTimestamp the timestamp of the api tests transactions
+	TestTimestamp = 1600000000
+
+	recordStreamLines = 3
+)
+
+// newApiNeb returns a client replaying the golden file of the test and the
+// account of TestPrivateKey. With -record the client sends the requests to
+// a node started by startTestNode and the golden file is written when the
+// test ends.
+func newApiNeb(t *testing.T) (*rpc.Neb, *account.Account) {
+	acc := new(account.Account)
+	privateKey, _ := hex.DecodeString(TestPrivateKey)
+	acc.SetPrivateKey(privateKey)
+	path := filepath.Join("testdata", "api", t.Name()+".json")
+
+	if *record {
+		recorder := httprequest.NewRecorder(startTestNode(t, acc).Request(), path)
+		recorder.StreamLines = recordStreamLines
+		t.Cleanup(func() {
+			if err := recorder.Save(); err != nil {
+				t.Error("save cassette failed:", err)
+			}
+		})
+		return rpc.NewNeb(recorder), acc
+	}
+
+	replayer, err := httprequest.NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, interaction := range replayer.Unused() {
+			t.Errorf("recorded request not sent: %s %s", interaction.Api, interaction.Request)
+		}
+	})
+	return rpc.NewNeb(replayer), acc
+}
+
+// sendApiTransaction sends a transfer of acc with nonce and TestTimestamp, so
+// that its request is the recorded one, and returns its hash.
+func sendApiTransaction(t *testing.T, neb *rpc.Neb, acc *account.Account, nonce uint64) string {
+	tx := transaction.NewTransaction(transaction.TransactionOptions{
+		ChainID:  rpctest.DefaultChainID,
+		From:     acc,
+		To:       TestToAddress,
+		Value:    big.NewInt(100),
+		Nonce:    nonce,
+		GasPrice: big.NewInt(rpctest.DefaultGasPrice),
+		GasLimit: big.NewInt(20000),
+		Contract: &transaction.Contract{},
+	})
+	tx.Timestamp = TestTimestamp
+	if err := tx.SignTransaction(); err != nil {
+		t.Fatal("sendApiTransaction failed:", err)
+	}
+	raw, _ := tx.ToProtoString()
+
+	resp, err := neb.Api.SendRawTransaction(rpc.SendRawTransactionRequest{Data: raw})
+	if err != nil {
+		t.Fatal("sendApiTransaction failed:", err)
+	}
+	return resp.Result.Txhash
+}
+
+// newTestNeb starts a node with startTestNode for a new account.
+func newTestNeb(t *testing.T) (*rpctest.Node, *rpc.Neb, *account.Account) {
+	acc := account.NewAccount()
+	node := startTestNode(t, acc)
+	return node, node.Neb(), acc
+}
+
+// startTestNode starts a node whose contract at TestContractAddress answers
+// every function with an empty list, with a funded acc the node holds the
+// key of under TestPassphrase.
+func startTestNode(t *testing.T, acc *account.Account) *rpctest.Node {
+	node := rpctest.NewNode(rpctest.Options{
+		AutoMine: true,
+		Balances: map[string]*big.Int{acc.GetAddressString(): big.NewInt(1e18)},
+	})
+	t.Cleanup(node.Close)
+
+	node.AddAccount(acc, TestPassphrase)
+	node.RegisterContract(TestContractAddress, func(call *rpctest.ContractCall) (string, error) {
+		return "[]", nil
+	})
+	return node
+}
+
+// sendTestTransaction sends a transfer from acc and returns its hash.
+func sendTestTransaction(t *testing.T, neb *rpc.Neb, acc *account.Account) string {
+	_, resp, err := rpc.NewTransactionBuilder(neb.Api).Send(context.Background(), transaction.TransactionOptions{
+		From:  acc,
+		To:    TestToAddress,
+		Value: big.NewInt(100),
+	})
+	if err != nil {
+		t.Fatal("sendTestTransaction failed:", err)
+	}
+	return resp.Result.Txhash
+}
@@ -5,19 +5,21 @@ import (
 )
 
 type Neb struct {
-	HttpRequest *httprequest.HttpRequest
+	HttpRequest httprequest.Transport
 	Api         *Api
 	Admin       *Admin
 }
 
-func NewNeb(request *httprequest.HttpRequest) *Neb {
+// NewNeb creates a client that talks to a node through request, usually an
+// *httprequest.HttpRequest.
+func NewNeb(request httprequest.Transport) *Neb {
 	neb := &Neb{HttpRequest: request}
 	neb.Api = NewApi(neb)
 	neb.Admin = NewAdmin(neb)
 	return neb
 }
 
-func (neb *Neb) SetRequest(request *httprequest.HttpRequest) {
+func (neb *Neb) SetRequest(request httprequest.Transport) {
 	neb.HttpRequest = request
 	neb.Api.SetRequest(request)
 	neb.Admin.SetRequest(request)
@@ -0,0 +1,196 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// NonceManager hands out nonces for accounts that send many transactions in
+// parallel. The first reservation of an address syncs with GetAccountState,
+// the following ones are counted locally until Reconcile.
+type NonceManager struct {
+	api *Api
+
+	mu       sync.Mutex
+	accounts map[string]*accountNonces
+}
+
+type accountNonces struct {
+	mu sync.Mutex
+	// next the nonce handed out when there are no gaps, 0 until synced.
+	next uint64
+	// pending the reserved nonces that are not confirmed yet.
+	pending map[uint64]bool
+	// gaps the released nonces below next, reused first.
+	gaps []uint64
+}
+
+func NewNonceManager(api *Api) *NonceManager {
+	return &NonceManager{api: api, accounts: make(map[string]*accountNonces)}
+}
+
+func (manager *NonceManager) account(address string) *accountNonces {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	nonces, ok := manager.accounts[address]
+	if !ok {
+		nonces = &accountNonces{pending: make(map[uint64]bool)}
+		manager.accounts[address] = nonces
+	}
+	return nonces
+}
+
+// Next reserves the next nonce of address. The caller must Confirm it once
+// the transaction is accepted, or Release or Fail it otherwise.
+func (manager *NonceManager) Next(ctx context.Context, address string) (uint64, error) {
+	nonces := manager.account(address)
+	nonces.mu.Lock()
+	defer nonces.mu.Unlock()
+
+	if nonces.next == 0 {
+		err := manager.sync(ctx, address, nonces)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var nonce uint64
+	if len(nonces.gaps) > 0 {
+		nonce = nonces.gaps[0]
+		nonces.gaps = nonces.gaps[1:]
+	} else {
+		nonce = nonces.next
+		nonces.next++
+	}
+	nonces.pending[nonce] = true
+	return nonce, nil
+}
+
+// Confirm marks a reserved nonce as used by an accepted transaction.
+func (manager *NonceManager) Confirm(address string, nonce uint64) {
+	nonces := manager.account(address)
+	nonces.mu.Lock()
+	defer nonces.mu.Unlock()
+
+	delete(nonces.pending, nonce)
+}
+
+// Release gives back a reserved nonce whose transaction was not sent, so the
+// next reservation fills the gap.
+func (manager *NonceManager) Release(address string, nonce uint64) {
+	nonces := manager.account(address)
+	nonces.mu.Lock()
+	defer nonces.mu.Unlock()
+
+	if !nonces.pending[nonce] {
+		return
+	}
+	delete(nonces.pending, nonce)
+	if nonce+1 == nonces.next {
+		nonces.next--
+		return
+	}
+	nonces.gaps = append(nonces.gaps, nonce)
+	sort.Slice(nonces.gaps, func(i, j int) bool { return nonces.gaps[i] < nonces.gaps[j] })
+}
+
+// Fail handles the error of sending the transaction with a reserved nonce:
+//
+//   - nonce errors resync the address with the chain
+//   - a duplicated transaction was accepted before, the nonce is confirmed
+//   - other rejections by the node release the nonce
+//   - errors that do not tell whether the node accepted the transaction,
+//     e.g. timeouts, keep the nonce reserved and only drop the reservations
+//     the chain has used since, see Refresh
+func (manager *NonceManager) Fail(ctx context.Context, address string, nonce uint64, err error) error {
+	switch {
+	case errors.Is(err, ErrNonceTooLow) || errors.Is(err, ErrNonceTooHigh):
+		return manager.Reconcile(ctx, address)
+	case errors.Is(err, ErrDuplicatedTransaction):
+		manager.Confirm(address, nonce)
+		return nil
+	case rejected(err):
+		manager.Release(address, nonce)
+		return nil
+	default:
+		return manager.Refresh(ctx, address)
+	}
+}
+
+// rejected reports whether err is known to be a rejection of the transaction
+// by the node, as opposed to a failure to reach it.
+func rejected(err error) bool {
+	if errors.Is(err, ErrBelowGasPrice) || errors.Is(err, ErrContractCheckFailed) {
+		return true
+	}
+	var nodeErr *NodeError
+	return errors.As(err, &nodeErr) && nodeErr.StatusCode < http.StatusInternalServerError
+}
+
+// Reconcile resyncs address with the chain: the next nonce becomes the
+// account nonce + 1 and all reservations and gaps are dropped. Transactions
+// still in flight with older reservations have to be rebuilt if they fail.
+func (manager *NonceManager) Reconcile(ctx context.Context, address string) error {
+	nonces := manager.account(address)
+	nonces.mu.Lock()
+	defer nonces.mu.Unlock()
+
+	return manager.sync(ctx, address, nonces)
+}
+
+// Refresh catches address up with the chain without dropping the nonces in
+// flight: the reservations and gaps up to the account nonce are dropped, the
+// next nonce is never lowered.
+func (manager *NonceManager) Refresh(ctx context.Context, address string) error {
+	nonces := manager.account(address)
+	nonces.mu.Lock()
+	defer nonces.mu.Unlock()
+
+	chainNonce, err := manager.chainNonce(ctx, address)
+	if err != nil {
+		return err
+	}
+	if nonces.next <= chainNonce {
+		nonces.next = chainNonce + 1
+	}
+	for nonce := range nonces.pending {
+		if nonce <= chainNonce {
+			delete(nonces.pending, nonce)
+		}
+	}
+	gaps := nonces.gaps[:0]
+	for _, nonce := range nonces.gaps {
+		if nonce > chainNonce {
+			gaps = append(gaps, nonce)
+		}
+	}
+	nonces.gaps = gaps
+	return nil
+}
+
+func (manager *NonceManager) chainNonce(ctx context.Context, address string) (uint64, error) {
+	resp, err := manager.api.GetAccountStateWithContext(ctx, GetAccountStateRequest{Address: address})
+	if err != nil {
+		return 0, err
+	}
+	if resp.Result == nil {
+		return 0, emptyResult("GetAccountState")
+	}
+	return resp.Result.Nonce, nil
+}
+
+func (manager *NonceManager) sync(ctx context.Context, address string, nonces *accountNonces) error {
+	chainNonce, err := manager.chainNonce(ctx, address)
+	if err != nil {
+		return err
+	}
+
+	nonces.next = chainNonce + 1
+	nonces.pending = make(map[uint64]bool)
+	nonces.gaps = nil
+	return nil
+}
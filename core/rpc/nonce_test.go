@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"testing"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+const nonceTestAddress = "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17"
+
+func TestNonceManager_Concurrent(t *testing.T) {
+	transport := newRouteTransport(map[string]string{
+		"/user/accountstate": `{"result":{"nonce":"10"}}`,
+	})
+	manager := NewNonceManager(NewNeb(transport).Api)
+
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := manager.Next(context.Background(), nonceTestAddress)
+			if err != nil {
+				t.Error("TestNonceManager_Concurrent failed:", err)
+				return
+			}
+			mu.Lock()
+			seen[nonce] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for nonce := uint64(11); nonce <= 60; nonce++ {
+		if !seen[nonce] {
+			t.Errorf("TestNonceManager_Concurrent nonce %d was not reserved", nonce)
+		}
+	}
+}
+
+func TestNonceManager_ReleaseAndReconcile(t *testing.T) {
+	transport := newRouteTransport(map[string]string{
+		"/user/accountstate": `{"result":{"nonce":"3"}}`,
+	})
+	manager := NewNonceManager(NewNeb(transport).Api)
+	ctx := context.Background()
+
+	first, _ := manager.Next(ctx, nonceTestAddress)
+	second, _ := manager.Next(ctx, nonceTestAddress)
+	third, _ := manager.Next(ctx, nonceTestAddress)
+	if first != 4 || second != 5 || third != 6 {
+		t.Fatalf("TestNonceManager_ReleaseAndReconcile unexpected nonces %d %d %d", first, second, third)
+	}
+
+	manager.Confirm(nonceTestAddress, first)
+	manager.Fail(ctx, nonceTestAddress, second, ErrBelowGasPrice)
+	if nonce, _ := manager.Next(ctx, nonceTestAddress); nonce != second {
+		t.Errorf("TestNonceManager_ReleaseAndReconcile expected released nonce %d, got %d", second, nonce)
+	}
+	if nonce, _ := manager.Next(ctx, nonceTestAddress); nonce != 7 {
+		t.Errorf("TestNonceManager_ReleaseAndReconcile expected nonce 7, got %d", nonce)
+	}
+
+	transport.bodies["/user/accountstate"] = `{"result":{"nonce":"8"}}`
+	manager.Fail(ctx, nonceTestAddress, third, &NodeError{Message: "transaction's nonce is invalid, should bigger than the from's nonce"})
+	if nonce, _ := manager.Next(ctx, nonceTestAddress); nonce != 9 {
+		t.Errorf("TestNonceManager_ReleaseAndReconcile expected reconciled nonce 9, got %d", nonce)
+	}
+}
+
+func TestNonceManager_FailAmbiguous(t *testing.T) {
+	transport := newRouteTransport(map[string]string{
+		"/user/accountstate": `{"result":{"nonce":"3"}}`,
+	})
+	manager := NewNonceManager(NewNeb(transport).Api)
+	ctx := context.Background()
+
+	first, _ := manager.Next(ctx, nonceTestAddress)
+	second, _ := manager.Next(ctx, nonceTestAddress)
+
+	// the node may have accepted the transaction of a timeout, and second is
+	// still in flight: neither may be handed out again
+	transport.bodies["/user/accountstate"] = `{"result":{"nonce":"4"}}`
+	manager.Fail(ctx, nonceTestAddress, first, context.DeadlineExceeded)
+	third, _ := manager.Next(ctx, nonceTestAddress)
+	if first != 4 || second != 5 || third != 6 {
+		t.Errorf("TestNonceManager_FailAmbiguous expected nonces 4 5 6, got %d %d %d", first, second, third)
+	}
+
+	// a refresh drops the reservations the chain used and keeps the others
+	transport.bodies["/user/accountstate"] = `{"result":{"nonce":"5"}}`
+	manager.Fail(ctx, nonceTestAddress, third, &NodeError{StatusCode: http.StatusBadGateway, Message: "bad gateway"})
+	if nonce, _ := manager.Next(ctx, nonceTestAddress); nonce != 7 {
+		t.Errorf("TestNonceManager_FailAmbiguous expected nonce 7 with 6 in flight, got %d", nonce)
+	}
+	manager.Release(nonceTestAddress, third)
+	if nonce, _ := manager.Next(ctx, nonceTestAddress); nonce != third {
+		t.Errorf("TestNonceManager_FailAmbiguous expected the kept reservation %d to be released, got %d", third, nonce)
+	}
+
+	transport.bodies["/user/accountstate"] = `{"result":null}`
+	if err := manager.Fail(ctx, nonceTestAddress, third, errors.New("connection reset")); !errors.Is(err, ErrEmptyResult) {
+		t.Errorf("TestNonceManager_FailAmbiguous refresh error %v", err)
+	}
+	if nonce, _ := manager.Next(ctx, nonceTestAddress); nonce == third {
+		t.Errorf("TestNonceManager_FailAmbiguous handed out nonce %d twice", nonce)
+	}
+
+	manager.Fail(ctx, nonceTestAddress, third, &NodeError{StatusCode: http.StatusOK, Message: "duplicated transaction"})
+	if nonce, _ := manager.Next(ctx, nonceTestAddress); nonce == third {
+		t.Errorf("TestNonceManager_FailAmbiguous reused the nonce %d of a duplicated transaction", nonce)
+	}
+}
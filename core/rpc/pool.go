@@ -0,0 +1,248 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+// ErrNoNodes the pool has no node to send the request to
+var ErrNoNodes = errors.New("no nodes in pool")
+
+type PoolOptions struct {
+	// HealthCheckInterval the delay between two health checks in Run, 10s by default.
+	HealthCheckInterval time.Duration
+	// MaxHeightLag how many blocks a node may be behind the highest node and
+	// still be healthy, 5 by default.
+	MaxHeightLag uint64
+}
+
+// NodeStatus is the health of a pool node as seen by the last check or request.
+type NodeStatus struct {
+	Transport httprequest.Transport
+	// Healthy the node answered, is synchronized and does not lag behind.
+	Healthy bool
+	// Synchronized the sync status reported by the node.
+	Synchronized bool
+	// Height the tail block height reported by the node.
+	Height uint64
+	// Lag how many blocks the node is behind the highest node of the pool.
+	Lag uint64
+	// Latency the duration of the last health check.
+	Latency time.Duration
+	// Err the error of the last health check or failed request.
+	Err error
+	// CheckedAt when the node was last checked.
+	CheckedAt time.Time
+}
+
+// Pool is a Transport that spreads requests over several nodes. User requests
+// go to the healthiest node and fail over to the next one on network errors
+// and 5xx statuses; admin requests always go to the first node because
+// accounts are local to a node. Use it with NewNeb to keep the Api and Admin
+// surface:
+//
+//	pool := rpc.NewPoolFromHosts([]string{host1, host2}, httprequest.APIVersion1, rpc.PoolOptions{})
+//	go pool.Run(ctx)
+//	neb := rpc.NewNeb(pool)
+type Pool struct {
+	options PoolOptions
+
+	mu    sync.RWMutex
+	nodes []*NodeStatus
+}
+
+var _ httprequest.Transport = (*Pool)(nil)
+
+func NewPool(transports []httprequest.Transport, options PoolOptions) *Pool {
+	if options.HealthCheckInterval <= 0 {
+		options.HealthCheckInterval = 10 * time.Second
+	}
+	if options.MaxHeightLag == 0 {
+		options.MaxHeightLag = 5
+	}
+
+	pool := &Pool{options: options}
+	for _, transport := range transports {
+		// nodes are assumed healthy until the first check says otherwise
+		pool.nodes = append(pool.nodes, &NodeStatus{Transport: transport, Healthy: true})
+	}
+	return pool
+}
+
+func NewPoolFromHosts(hosts []string, apiVersion string, options PoolOptions) *Pool {
+	var transports []httprequest.Transport
+	for _, host := range hosts {
+		transports = append(transports, httprequest.NewHttpRequest(host, apiVersion))
+	}
+	return NewPool(transports, options)
+}
+
+// Check queries GetNebState on every node concurrently and updates their status.
+func (pool *Pool) Check(ctx context.Context) {
+	pool.mu.RLock()
+	nodes := make([]*NodeStatus, len(pool.nodes))
+	copy(nodes, pool.nodes)
+	pool.mu.RUnlock()
+
+	results := make([]NodeStatus, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, transport httprequest.Transport) {
+			defer wg.Done()
+			api := &Api{HttpRequest: transport}
+			start := time.Now()
+			resp, err := api.GetNebStateWithContext(ctx)
+			results[i] = NodeStatus{Transport: transport, Latency: time.Since(start), Err: err, CheckedAt: time.Now()}
+			if err == nil && resp.Result != nil {
+				results[i].Synchronized = resp.Result.Synchronized
+				results[i].Height = resp.Result.Height
+			} else if err == nil {
+				results[i].Err = errors.New("empty nebstate result")
+			}
+		}(i, node.Transport)
+	}
+	wg.Wait()
+
+	var highest uint64
+	for _, result := range results {
+		if result.Err == nil && result.Height > highest {
+			highest = result.Height
+		}
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for i, result := range results {
+		result.Lag = highest - result.Height
+		result.Healthy = result.Err == nil && result.Synchronized && result.Lag <= pool.options.MaxHeightLag
+		*nodes[i] = result
+	}
+}
+
+// Run checks the nodes every HealthCheckInterval until ctx is done.
+func (pool *Pool) Run(ctx context.Context) {
+	ticker := time.NewTicker(pool.options.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		pool.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns a snapshot of the nodes, in pool order.
+func (pool *Pool) Status() []NodeStatus {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	status := make([]NodeStatus, len(pool.nodes))
+	for i, node := range pool.nodes {
+		status[i] = *node
+	}
+	return status
+}
+
+func (pool *Pool) GetWithContext(ctx context.Context, api string, params map[string]string) ([]byte, error) {
+	var resp []byte
+	err := pool.each(ctx, api, func(transport httprequest.Transport) (err error) {
+		resp, err = transport.GetWithContext(ctx, api, params)
+		return err
+	})
+	return resp, err
+}
+
+func (pool *Pool) PostWithContext(ctx context.Context, api string, reqBody interface{}) ([]byte, error) {
+	var resp []byte
+	err := pool.each(ctx, api, func(transport httprequest.Transport) (err error) {
+		resp, err = transport.PostWithContext(ctx, api, reqBody)
+		return err
+	})
+	return resp, err
+}
+
+func (pool *Pool) OpenStreamWithContext(ctx context.Context, api string, reqBody interface{}) (io.ReadCloser, error) {
+	var stream io.ReadCloser
+	err := pool.each(ctx, api, func(transport httprequest.Transport) (err error) {
+		stream, err = transport.OpenStreamWithContext(ctx, api, reqBody)
+		return err
+	})
+	return stream, err
+}
+
+// each sends the request to the nodes in routing order until one of them
+// answers or fails with an error that another node would return as well.
+func (pool *Pool) each(ctx context.Context, api string, send func(transport httprequest.Transport) error) error {
+	nodes := pool.route(api)
+	if len(nodes) == 0 {
+		return ErrNoNodes
+	}
+
+	var err error
+	for _, node := range nodes {
+		err = send(node.Transport)
+		if err == nil || !failover(ctx, err) {
+			return err
+		}
+		pool.markFailed(node, err)
+	}
+	return err
+}
+
+// route returns the nodes to try for api: the first node for admin requests,
+// otherwise healthy nodes by lag and latency followed by the unhealthy ones.
+func (pool *Pool) route(api string) []*NodeStatus {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	if len(pool.nodes) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(api, "/admin/") {
+		return pool.nodes[:1]
+	}
+
+	nodes := make([]*NodeStatus, len(pool.nodes))
+	copy(nodes, pool.nodes)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Healthy != nodes[j].Healthy {
+			return nodes[i].Healthy
+		}
+		if nodes[i].Lag != nodes[j].Lag {
+			return nodes[i].Lag < nodes[j].Lag
+		}
+		return nodes[i].Latency < nodes[j].Latency
+	})
+	return nodes
+}
+
+func (pool *Pool) markFailed(node *NodeStatus, err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	node.Healthy = false
+	node.Err = err
+}
+
+// failover reports whether err is a node failure worth trying another node for.
+func failover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *httprequest.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
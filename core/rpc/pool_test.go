@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"testing"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+func newPoolTestNode(name string, height uint64, synchronized bool, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/user/nebstate":
+			fmt.Fprintf(w, `{"result":{"chain_id":1001,"height":"%d","synchronized":%t}}`, height, synchronized)
+		default:
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"result":{"gas_price":"%s"}}`, name)
+		}
+	}))
+}
+
+func TestPool_RoutesToHealthiestNode(t *testing.T) {
+	lagging := newPoolTestNode("lagging", 100, true, http.StatusOK)
+	defer lagging.Close()
+	unsynced := newPoolTestNode("unsynced", 200, false, http.StatusOK)
+	defer unsynced.Close()
+	healthy := newPoolTestNode("healthy", 200, true, http.StatusOK)
+	defer healthy.Close()
+
+	pool := NewPoolFromHosts([]string{lagging.URL, unsynced.URL, healthy.URL}, httprequest.APIVersion1, PoolOptions{})
+	pool.Check(context.Background())
+
+	status := pool.Status()
+	if status[0].Healthy || status[0].Lag != 100 || status[1].Healthy || !status[2].Healthy {
+		t.Fatalf("TestPool_RoutesToHealthiestNode unexpected status %+v", status)
+	}
+
+	resp, err := NewNeb(pool).Api.GasPrice()
+	if err != nil {
+		t.Fatal("TestPool_RoutesToHealthiestNode failed:", err)
+	}
+	if resp.Result.GasPrice != "healthy" {
+		t.Errorf("TestPool_RoutesToHealthiestNode routed to %s", resp.Result.GasPrice)
+	}
+}
+
+func TestPool_FailsOver(t *testing.T) {
+	broken := newPoolTestNode("broken", 200, true, http.StatusServiceUnavailable)
+	defer broken.Close()
+	healthy := newPoolTestNode("healthy", 200, true, http.StatusOK)
+	defer healthy.Close()
+
+	pool := NewPoolFromHosts([]string{broken.URL, healthy.URL}, httprequest.APIVersion1, PoolOptions{})
+	neb := NewNeb(pool)
+	neb.Api.SetRetryPolicy(nil)
+
+	resp, err := neb.Api.GasPrice()
+	if err != nil {
+		t.Fatal("TestPool_FailsOver failed:", err)
+	}
+	if resp.Result.GasPrice != "healthy" {
+		t.Errorf("TestPool_FailsOver routed to %s", resp.Result.GasPrice)
+	}
+	if pool.Status()[0].Healthy {
+		t.Error("TestPool_FailsOver should mark the broken node unhealthy")
+	}
+}
@@ -0,0 +1,160 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+)
+
+const (
+	TransactionStatusFailed  = 0
+	TransactionStatusSuccess = 1
+	TransactionStatusPending = 2
+)
+
+type WaitOptions struct {
+	// PollInterval the delay between two receipt queries, 2s by default.
+	PollInterval time.Duration
+	// Confirmations how many blocks must be linked on top of the transaction
+	// block before returning.
+	Confirmations uint64
+	// Finality waits until the transaction block is irreversible.
+	Finality bool
+}
+
+// Receipt is the outcome of a packed transaction.
+type Receipt struct {
+	Hash string
+	// Status TransactionStatusFailed or TransactionStatusSuccess
+	Status        int32
+	GasUsed       *big.Int
+	ExecuteResult string
+	ExecuteError  string
+	BlockHeight   uint64
+	// Confirmations the number of blocks linked on top of the transaction block
+	// when the wait ended.
+	Confirmations uint64
+	// Final the transaction block is irreversible.
+	Final bool
+	// Transaction the receipt as returned by the node
+	Transaction *TransactionResult
+}
+
+func (receipt *Receipt) Success() bool {
+	return receipt.Status == TransactionStatusSuccess
+}
+
+// WaitForReceipt polls GetTransactionReceipt until the transaction is packed,
+// then until the requested confirmations and finality are reached. Unknown
+// transactions are treated as not yet propagated; use ctx to bound the wait.
+// The receipt is fetched again once the confirmations are reached, a
+// transaction whose block was dropped meanwhile is waited for again.
+func (api *Api) WaitForReceipt(ctx context.Context, hash string, opts WaitOptions) (*Receipt, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+
+	var receipt *Receipt
+	for {
+		if receipt == nil {
+			var err error
+			receipt, err = api.packedReceipt(ctx, hash)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if receipt != nil {
+			done, err := api.checkConfirmations(ctx, receipt, opts)
+			if err != nil {
+				return nil, err
+			}
+			if done && opts.Confirmations == 0 && !opts.Finality {
+				return receipt, nil
+			}
+			if done {
+				current, err := api.packedReceipt(ctx, hash)
+				if err != nil {
+					return nil, err
+				}
+				if current != nil && current.BlockHeight == receipt.BlockHeight {
+					current.Confirmations, current.Final = receipt.Confirmations, receipt.Final
+					return current, nil
+				}
+				// the block was dropped, wait for the transaction to be packed again
+				receipt = current
+			}
+		}
+
+		if !sleep(ctx, opts.PollInterval) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// packedReceipt returns the receipt of a packed transaction, nil while the
+// transaction is unknown or pending.
+func (api *Api) packedReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	resp, err := api.GetTransactionReceiptWithContext(ctx, HashRequest{Hash: hash})
+	if errors.Is(err, ErrTransactionNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Result == nil {
+		return nil, emptyResult("GetTransactionReceipt")
+	}
+	if resp.Result.Status == TransactionStatusPending {
+		return nil, nil
+	}
+	return newReceipt(resp.Result), nil
+}
+
+func (api *Api) checkConfirmations(ctx context.Context, receipt *Receipt, opts WaitOptions) (bool, error) {
+	if (opts.Confirmations > 0 || opts.Finality) && receipt.BlockHeight == 0 {
+		return false, ErrNoBlockHeight
+	}
+	if opts.Confirmations > 0 {
+		resp, err := api.GetNebStateWithContext(ctx)
+		if err != nil {
+			return false, err
+		}
+		if resp.Result == nil {
+			return false, emptyResult("GetNebState")
+		}
+		if resp.Result.Height > receipt.BlockHeight {
+			receipt.Confirmations = resp.Result.Height - receipt.BlockHeight
+		}
+	}
+
+	if opts.Finality {
+		resp, err := api.LatestIrreversibleBlockWithContext(ctx)
+		if err != nil {
+			return false, err
+		}
+		if resp.Result == nil {
+			return false, emptyResult("LatestIrreversibleBlock")
+		}
+		receipt.Final = resp.Result.Height >= receipt.BlockHeight
+	}
+
+	return receipt.Confirmations >= opts.Confirmations && (receipt.Final || !opts.Finality), nil
+}
+
+func newReceipt(result *TransactionResult) *Receipt {
+	gasUsed, ok := new(big.Int).SetString(result.GasUsed, 10)
+	if !ok {
+		gasUsed = big.NewInt(0)
+	}
+	return &Receipt{
+		Hash:          result.Hash,
+		Status:        result.Status,
+		GasUsed:       gasUsed,
+		ExecuteResult: result.ExecuteResult,
+		ExecuteError:  result.ExecuteError,
+		BlockHeight:   result.BlockHeight,
+		Transaction:   result,
+	}
+}
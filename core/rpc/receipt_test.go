@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"testing"
+	"context"
+	"errors"
+	"time"
+)
+
+func TestApi_WaitForReceipt(t *testing.T) {
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{body: []byte(`{"error":"transaction not found"}`)},
+		{body: []byte(`{"result":{"hash":"c1","status":2}}`)},
+		{body: []byte(`{"result":{"hash":"c1","status":1,"gas_used":"20036","execute_result":"\"\"","block_height":"120"}}`)},
+		{body: []byte(`{"result":{"height":"121"}}`)},
+		{body: []byte(`{"result":{"height":"100"}}`)},
+		{body: []byte(`{"result":{"height":"122"}}`)},
+		{body: []byte(`{"result":{"height":"120"}}`)},
+		{body: []byte(`{"result":{"hash":"c1","status":1,"gas_used":"20036","execute_result":"\"\"","block_height":"120"}}`)},
+	}}
+	neb := NewNeb(transport)
+	neb.Api.SetRetryPolicy(nil)
+
+	opts := WaitOptions{PollInterval: time.Millisecond, Confirmations: 2, Finality: true}
+	receipt, err := neb.Api.WaitForReceipt(context.Background(), "c1", opts)
+	if err != nil {
+		t.Fatal("TestApi_WaitForReceipt failed:", err)
+	}
+	if !receipt.Success() || receipt.GasUsed.Int64() != 20036 || receipt.BlockHeight != 120 {
+		t.Errorf("TestApi_WaitForReceipt unexpected receipt %+v", receipt)
+	}
+	if receipt.Confirmations != 2 || !receipt.Final || transport.calls != 8 {
+		t.Errorf("TestApi_WaitForReceipt ended early with %d confirmations, final %t after %d calls",
+			receipt.Confirmations, receipt.Final, transport.calls)
+	}
+}
+
+func TestApi_WaitForReceiptTimeout(t *testing.T) {
+	transport := newRouteTransport(map[string]string{
+		"/user/getTransactionReceipt": `{"result":{"hash":"c1","status":2}}`,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := NewNeb(transport).Api.WaitForReceipt(ctx, "c1", WaitOptions{PollInterval: time.Millisecond})
+	if err != context.DeadlineExceeded {
+		t.Errorf("TestApi_WaitForReceiptTimeout expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestApi_WaitForReceiptEmptyResult(t *testing.T) {
+	receipt := `{"result":{"hash":"c1","status":1,"block_height":"120"}}`
+	tests := []struct {
+		routes map[string]string
+		opts   WaitOptions
+	}{
+		{map[string]string{"/user/getTransactionReceipt": `{"result":null}`}, WaitOptions{}},
+		{map[string]string{"/user/getTransactionReceipt": receipt, "/user/nebstate": `{"result":null}`}, WaitOptions{Confirmations: 1}},
+		{map[string]string{"/user/getTransactionReceipt": receipt, "/user/lib": `{"result":null}`}, WaitOptions{Finality: true}},
+	}
+	for _, test := range tests {
+		test.opts.PollInterval = time.Millisecond
+		_, err := NewNeb(newRouteTransport(test.routes)).Api.WaitForReceipt(context.Background(), "c1", test.opts)
+		if !errors.Is(err, ErrEmptyResult) {
+			t.Errorf("TestApi_WaitForReceiptEmptyResult %+v: %v", test.opts, err)
+		}
+	}
+}
+
+func TestApi_WaitForReceiptReorg(t *testing.T) {
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{body: []byte(`{"result":{"hash":"c1","status":1,"block_height":"120"}}`)},
+		{body: []byte(`{"result":{"height":"120"}}`)},
+		// the block 120 was dropped
+		{body: []byte(`{"error":"transaction not found"}`)},
+		{body: []byte(`{"result":{"hash":"c1","status":2}}`)},
+		{body: []byte(`{"result":{"hash":"c1","status":1,"block_height":"123"}}`)},
+		{body: []byte(`{"result":{"height":"123"}}`)},
+		{body: []byte(`{"result":{"hash":"c1","status":1,"block_height":"123"}}`)},
+	}}
+	neb := NewNeb(transport)
+	neb.Api.SetRetryPolicy(nil)
+
+	opts := WaitOptions{PollInterval: time.Millisecond, Finality: true}
+	receipt, err := neb.Api.WaitForReceipt(context.Background(), "c1", opts)
+	if err != nil || receipt.BlockHeight != 123 || !receipt.Final || transport.calls != 7 {
+		t.Errorf("TestApi_WaitForReceiptReorg receipt %+v after %d calls: %v", receipt, transport.calls, err)
+	}
+}
+
+func TestApi_WaitForReceiptNoBlockHeight(t *testing.T) {
+	transport := newRouteTransport(map[string]string{
+		"/user/getTransactionReceipt": `{"result":{"hash":"c1","status":1}}`,
+		"/user/lib":                   `{"result":{"height":"120"}}`,
+	})
+	api := NewNeb(transport).Api
+
+	_, err := api.WaitForReceipt(context.Background(), "c1", WaitOptions{PollInterval: time.Millisecond, Finality: true})
+	if !errors.Is(err, ErrNoBlockHeight) {
+		t.Errorf("TestApi_WaitForReceiptNoBlockHeight expected ErrNoBlockHeight, got %v", err)
+	}
+	if receipt, err := api.WaitForReceipt(context.Background(), "c1", WaitOptions{PollInterval: time.Millisecond}); err != nil || !receipt.Success() {
+		t.Errorf("TestApi_WaitForReceiptNoBlockHeight without confirmations %+v: %v", receipt, err)
+	}
+}
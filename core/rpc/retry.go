@@ -0,0 +1,131 @@
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+// RetryPolicy controls how failed Api calls are retried. Network errors are
+// always retried, node errors only when their http status is in RetryOnStatus.
+type RetryPolicy struct {
+	// MaxAttempts the total number of attempts, 1 disables retries.
+	MaxAttempts int
+	// InitialBackoff the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every attempt, constant delay when <= 1.
+	Multiplier float64
+	// Jitter randomizes every delay by up to this fraction of it.
+	Jitter float64
+	// RetryOnStatus the http statuses that are worth another attempt.
+	RetryOnStatus []int
+}
+
+// DefaultRetryPolicy is used by the read-only Api calls and SendRawTransaction
+// unless Api.SetRetryPolicy replaces it.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	RetryOnStatus: []int{
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// NoRetryPolicy makes a single attempt per call.
+var NoRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+// do runs call until it succeeds, fails with an error that is not retryable or
+// runs out of attempts. A nil policy makes a single attempt.
+func (policy *RetryPolicy) do(ctx context.Context, call func() ([]byte, error)) ([]byte, error) {
+	if policy == nil {
+		return call()
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := call()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(ctx, err) {
+			return resp, err
+		}
+
+		if !sleep(ctx, policy.backoff(attempt)) {
+			return resp, err
+		}
+	}
+}
+
+// backoff returns the jittered delay to wait after the given failed attempt.
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(policy.InitialBackoff)
+	for i := 1; i < attempt && policy.Multiplier > 1; i++ {
+		backoff *= policy.Multiplier
+		if policy.MaxBackoff > 0 && backoff > float64(policy.MaxBackoff) {
+			backoff = float64(policy.MaxBackoff)
+			break
+		}
+	}
+	return policy.jitter(time.Duration(backoff))
+}
+
+func (policy *RetryPolicy) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *httprequest.StatusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+	for _, status := range policy.RetryOnStatus {
+		if statusErr.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (policy *RetryPolicy) jitter(backoff time.Duration) time.Duration {
+	if policy.Jitter <= 0 || backoff <= 0 {
+		return backoff
+	}
+	delta := (rand.Float64()*2 - 1) * policy.Jitter * float64(backoff)
+	return backoff + time.Duration(delta)
+}
+
+// sleep waits for d and reports false when ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// duplicatedTransactionResponse builds the response of a raw transaction the
+// node already accepted on an earlier attempt. The hash is part of the signed
+// payload, so it is the one the node knows the transaction by.
+func duplicatedTransactionResponse(req SendRawTransactionRequest) (*SendTransactionResponse, error) {
+	tx, err := new(transaction.Transaction).FromProto(req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := SendTransactionResult{Txhash: hex.EncodeToString(tx.Hash)}
+	return &SendTransactionResponse{Result: &result}, nil
+}
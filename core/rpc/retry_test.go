@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"testing"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+type sequenceTransport struct {
+	responses []sequenceResponse
+	calls     int
+}
+
+type sequenceResponse struct {
+	body []byte
+	err  error
+}
+
+func (s *sequenceTransport) next() ([]byte, error) {
+	resp := s.responses[s.calls]
+	s.calls++
+	return resp.body, resp.err
+}
+
+func (s *sequenceTransport) GetWithContext(ctx context.Context, api string, params map[string]string) ([]byte, error) {
+	return s.next()
+}
+
+func (s *sequenceTransport) PostWithContext(ctx context.Context, api string, reqBody interface{}) ([]byte, error) {
+	return s.next()
+}
+
+func (s *sequenceTransport) OpenStreamWithContext(ctx context.Context, api string, reqBody interface{}) (io.ReadCloser, error) {
+	_, err := s.next()
+	return nil, err
+}
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     time.Millisecond,
+	Multiplier:     2,
+	RetryOnStatus:  []int{http.StatusServiceUnavailable},
+}
+
+func TestRetryPolicy_ReadOnlyCall(t *testing.T) {
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{err: errors.New("connection reset by peer")},
+		{err: &httprequest.StatusError{StatusCode: http.StatusServiceUnavailable}},
+		{body: []byte(`{"result":{"gas_price":"1000000"}}`)},
+	}}
+	neb := NewNeb(transport)
+	neb.Api.SetRetryPolicy(&testRetryPolicy)
+
+	resp, err := neb.Api.GasPrice()
+	if err != nil {
+		t.Fatal("TestRetryPolicy_ReadOnlyCall failed:", err)
+	}
+	if resp.Result.GasPrice != "1000000" || transport.calls != 3 {
+		t.Errorf("TestRetryPolicy_ReadOnlyCall unexpected result %+v after %d calls", resp.Result, transport.calls)
+	}
+}
+
+func TestRetryPolicy_NotRetryableStatus(t *testing.T) {
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{err: &httprequest.StatusError{StatusCode: http.StatusBadRequest, Body: []byte(`{"error":"invalid address"}`)}},
+	}}
+	neb := NewNeb(transport)
+	neb.Api.SetRetryPolicy(&testRetryPolicy)
+
+	_, err := neb.Api.GetAccountState(GetAccountStateRequest{Address: "n1"})
+	if err == nil || transport.calls != 1 {
+		t.Errorf("TestRetryPolicy_NotRetryableStatus expected a single failed call, got %d calls, err %v", transport.calls, err)
+	}
+}
+
+func TestRetryPolicy_SendRawTransactionDuplicated(t *testing.T) {
+	raw := "CiAuTz4bhJj54X/doi6EXmIf3f1H1oOil7r2U/nOTGNX9hIaGVdDhNxJ4+OzYNWr2if95MASrtEj0U0nmgYaGhlXf89CeLWgHFjKu9/6tn4KNbelsMDAIIi2IhAAAAAAAAAAAAAAAAAAAAAKKAww3d7p2AU6KAoEY2FsbBIgeyJGdW5jdGlvbiI6InNhdmUiLCJBcmdzIjoiWzBdIn1AAUoQAAAAAAAAAAAAAAAAAA9CQFIQAAAAAAAAAAAAAAAAAB6EgFgBYkGkVEUhcFggQZVmN+2C5c6UPOgqF/pFWTk/g3HKqDBjpRq3Gz/Rtp7znhRTQCYZ2bp6FzX5OaVv90LTuIW3kwaIAA=="
+	transport := &sequenceTransport{responses: []sequenceResponse{
+		{err: errors.New("i/o timeout")},
+		{err: &httprequest.StatusError{StatusCode: http.StatusBadRequest, Body: []byte(`{"error":"duplicated transaction"}`)}},
+	}}
+	neb := NewNeb(transport)
+	neb.Api.SetRetryPolicy(&testRetryPolicy)
+
+	resp, err := neb.Api.SendRawTransaction(SendRawTransactionRequest{Data: raw})
+	if err != nil {
+		t.Fatal("TestRetryPolicy_SendRawTransactionDuplicated failed:", err)
+	}
+	if resp.Result.Txhash != "2e4f3e1b8498f9e17fdda22e845e621fddfd47d683a297baf653f9ce4c6357f6" {
+		t.Errorf("TestRetryPolicy_SendRawTransactionDuplicated unexpected hash %s", resp.Result.Txhash)
+	}
+}
@@ -140,6 +140,8 @@ type TransactionResult struct {
 	ExecuteError string `json:"execute_error,omitempty"`
 	// contract execute result
 	ExecuteResult string `json:"execute_result,omitempty"`
+	// height of the block the transaction is packed in
+	BlockHeight uint64 `json:"block_height,string,omitempty"`
 }
 
 type TransactionResponse struct {
@@ -0,0 +1,282 @@
+package rpctest
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils/secp256k1"
+)
+
+// DefaultUnlockDuration the unlock duration when the request has none, the
+// same default the node uses.
+const DefaultUnlockDuration = 300 * time.Second
+
+// nodeKey is an account of the node keystore. The node keeps the keys
+// decrypted and only compares the passphrases, keystore encryption is
+// covered by the account package.
+type nodeKey struct {
+	account    *account.Account
+	passphrase string
+	unlocked   time.Time
+}
+
+// AddAccount adds acc to the keystore of the node, so the admin api can
+// unlock it with passphrase and sign with it.
+func (node *Node) AddAccount(acc *account.Account, passphrase string) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.keys[acc.GetAddressString()] = &nodeKey{account: acc, passphrase: passphrase}
+}
+
+// key returns the account of address, checked against passphrase when it is
+// given and unlocked otherwise.
+func (node *Node) key(address string, passphrase string) (*account.Account, error) {
+	key, ok := node.keys[address]
+	if !ok {
+		return nil, newNodeError("address not find")
+	}
+	if passphrase != "" {
+		if passphrase != key.passphrase {
+			return nil, newNodeError("could not decrypt key with given passphrase")
+		}
+		return key.account, nil
+	}
+	if !time.Now().Before(key.unlocked) {
+		return nil, newNodeError("key not unlocked")
+	}
+	return key.account, nil
+}
+
+func (node *Node) serveNodeInfo(r *http.Request) (interface{}, error) {
+	return &rpc.NodeInfoResult{
+		Id:              "rpctest",
+		ChainId:         node.options.ChainID,
+		Coinbase:        node.Coinbase(),
+		Synchronized:    true,
+		ProtocolVersion: ProtocolVersion,
+	}, nil
+}
+
+func (node *Node) serveAccounts(r *http.Request) (interface{}, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	addresses := make([]string, 0, len(node.keys))
+	for address := range node.keys {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return &rpc.AccountsResult{Addresses: addresses}, nil
+}
+
+func (node *Node) serveNewAccount(r *http.Request) (interface{}, error) {
+	var req rpc.NewAccountRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Passphrase == "" {
+		return nil, newNodeError("passphrase is empty")
+	}
+
+	acc := account.NewAccount()
+	node.AddAccount(acc, req.Passphrase)
+	return &rpc.NewAccountResult{Address: acc.GetAddressString()}, nil
+}
+
+func (node *Node) serveUnlockAccount(r *http.Request) (interface{}, error) {
+	var req rpc.UnlockAccountRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Passphrase == "" {
+		return nil, newNodeError("passphrase is empty")
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	_, err = node.key(req.Address, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	// the node takes the duration in nanoseconds
+	duration := time.Duration(req.Duration)
+	if duration <= 0 {
+		duration = DefaultUnlockDuration
+	}
+	node.keys[req.Address].unlocked = time.Now().Add(duration)
+	return &rpc.UnlockAccountResult{Result: true}, nil
+}
+
+func (node *Node) serveLockAccount(r *http.Request) (interface{}, error) {
+	var req rpc.LockAccountRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	key, ok := node.keys[req.Address]
+	if !ok {
+		return nil, newNodeError("address not find")
+	}
+	key.unlocked = time.Time{}
+	return &rpc.LockAccountResult{Result: true}, nil
+}
+
+// signTransaction builds the transaction of req and signs it with the key of
+// its from address.
+func (node *Node) signTransaction(req *rpc.TransactionRequest, passphrase string) (*transaction.Transaction, error) {
+	if req == nil {
+		return nil, newNodeError("transaction is missing")
+	}
+
+	acc, err := node.key(req.From, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := node.newTransaction(req, acc)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.SignTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (node *Node) serveSendTransaction(r *http.Request) (interface{}, error) {
+	var req rpc.TransactionRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mining.Lock()
+	defer node.mining.Unlock()
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	tx, err := node.signTransaction(&req, "")
+	if err != nil {
+		return nil, err
+	}
+	return node.submit(tx)
+}
+
+func (node *Node) serveSignHash(r *http.Request) (interface{}, error) {
+	var req rpc.SignHashRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Alg != 0 && req.Alg != transaction.SECP256K1 {
+		return nil, newNodeError(transaction.ErrUnsupportedSignatureAlg.Error())
+	}
+	hash, err := base64.StdEncoding.DecodeString(req.Hash)
+	if err != nil || len(hash) != 32 {
+		return nil, newNodeError("invalid hash " + req.Hash)
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	acc, err := node.key(req.Address, "")
+	if err != nil {
+		return nil, err
+	}
+	sign, err := secp256k1.Sign(hash, acc.GetPrivateKey())
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.SignHashResult{Data: sign}, nil
+}
+
+func (node *Node) serveSignTransaction(r *http.Request) (interface{}, error) {
+	var req rpc.SignTransactionPassphraseRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Passphrase == "" {
+		return nil, newNodeError("passphrase is empty")
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	tx, err := node.signTransaction(req.Transaction, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	data, err := tx.ToProto()
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.SignTransactionPassphraseResult{Data: data}, nil
+}
+
+func (node *Node) serveSendTransactionWithPassphrase(r *http.Request) (interface{}, error) {
+	var req rpc.SendTransactionPassphraseRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Passphrase == "" {
+		return nil, newNodeError("passphrase is empty")
+	}
+
+	node.mining.Lock()
+	defer node.mining.Unlock()
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	tx, err := node.signTransaction(req.Transaction, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return node.submit(tx)
+}
+
+// servePprof accepts the request without starting anything.
+func (node *Node) servePprof(r *http.Request) (interface{}, error) {
+	var req rpc.PprofRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Listen == "" {
+		return nil, newNodeError("listen is empty")
+	}
+	return &rpc.PprofResult{Result: true}, nil
+}
+
+func (node *Node) serveConfig(r *http.Request) (interface{}, error) {
+	return &rpc.GetConfigResult{Config: rpc.Config{
+		Chain: &rpc.ChainConfig{
+			ChainId:          node.options.ChainID,
+			Coinbase:         node.Coinbase(),
+			Miner:            node.Coinbase(),
+			GasPrice:         node.options.GasPrice.String(),
+			GasLimit:         "50000000000",
+			SignatureCiphers: []string{"ECC_SECP256K1"},
+		},
+		Rpc: &rpc.RPCConfig{
+			HttpListen: []string{node.server.Listener.Addr().String()},
+			HttpModule: []string{"api", "admin"},
+		},
+		Misc: &rpc.MiscConfig{DefaultKeystoreFileCiper: "aes-128-ctr"},
+	}}, nil
+}
@@ -0,0 +1,299 @@
+package rpctest
+
+import (
+	"math/big"
+	"net/http"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+// ProtocolVersion the protocol version the node reports.
+const ProtocolVersion = "/neb/1.0.0"
+
+func (node *Node) serveNebState(r *http.Request) (interface{}, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return &rpc.GetNebStateResult{
+		ChainId:         node.options.ChainID,
+		Tail:            node.tail().result.Hash,
+		Lib:             node.lib().result.Hash,
+		Height:          node.tail().result.Height,
+		ProtocolVersion: ProtocolVersion,
+		Synchronized:    true,
+		Version:         "rpctest",
+	}, nil
+}
+
+func (node *Node) serveLatestIrreversibleBlock(r *http.Request) (interface{}, error) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return node.lib().fullResult(false), nil
+}
+
+// serveAccountState returns the state at the tail block, the node keeps no
+// history and ignores the height of the request.
+func (node *Node) serveAccountState(r *http.Request) (interface{}, error) {
+	var req rpc.GetAccountStateRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if !account.IsValidAddress(req.Address) {
+		return nil, newNodeError("address: invalid address format")
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	state := node.account(req.Address)
+	result := &rpc.GetAccountStateResult{
+		Balance: state.balance.String(),
+		Nonce:   state.nonce,
+		Type:    account.NormalType,
+	}
+	if _, ok := node.contracts[req.Address]; ok {
+		result.Type = account.ContractType
+	}
+	return result, nil
+}
+
+func (node *Node) serveCall(r *http.Request) (interface{}, error) {
+	var req rpc.TransactionRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	result, gas, err := node.simulate(&req)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.CallResult{Result: result.result, ExecuteErr: result.executeErr, EstimateGas: gas.String()}, nil
+}
+
+func (node *Node) serveEstimateGas(r *http.Request) (interface{}, error) {
+	var req rpc.TransactionRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	result, gas, err := node.simulate(&req)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.GasResult{Gas: gas.String(), Err: result.executeErr}, nil
+}
+
+type simulation struct {
+	result     string
+	executeErr string
+}
+
+// simulate runs the transaction of req on the tail state without changing
+// it, as /user/call and /user/estimateGas do.
+func (node *Node) simulate(req *rpc.TransactionRequest) (*simulation, *big.Int, error) {
+	from, err := account.FromAddress(req.From)
+	if err != nil {
+		return nil, nil, newNodeError("invalid from address " + req.From)
+	}
+	tx, err := node.newTransaction(req, from)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err := tx.DecodePayload()
+	if err != nil {
+		return nil, nil, newNodeError(err.Error())
+	}
+
+	sim := &simulation{}
+	if node.account(req.From).balance.Cmp(tx.Value) < 0 {
+		sim.executeErr = "insufficient balance"
+	}
+	if call, ok := payload.(*transaction.TransactionCallPayload); ok {
+		contract, ok := node.contracts[req.To]
+		if !ok {
+			return nil, nil, newNodeError("contract check failed")
+		}
+		if contract.call != nil && sim.executeErr == "" {
+			sim.result, err = node.callContract(contract.call, &ContractCall{
+				From:     req.From,
+				Contract: req.To,
+				Value:    new(big.Int).Set(tx.Value),
+				Function: call.Function,
+				Args:     call.Args,
+			})
+			if err != nil {
+				sim.executeErr = err.Error()
+			}
+		}
+	}
+	return sim, transactionGas(tx), nil
+}
+
+// newTransaction builds the unsigned transaction of req, with the node gas
+// price and the highest gas limit when they are not given.
+func (node *Node) newTransaction(req *rpc.TransactionRequest, from *account.Account) (*transaction.Transaction, error) {
+	if !account.IsValidAddress(req.To) {
+		return nil, newNodeError("invalid to address " + req.To)
+	}
+
+	opts := transaction.TransactionOptions{
+		ChainID:  node.options.ChainID,
+		From:     from,
+		To:       req.To,
+		Nonce:    req.Nonce,
+		Contract: &transaction.Contract{Binary: req.Binary},
+	}
+	if req.Contract != nil {
+		opts.Contract.Source = req.Contract.Source
+		opts.Contract.SourceType = req.Contract.SourceType
+		opts.Contract.Function = req.Contract.Function
+		opts.Contract.Args = req.Contract.Args
+	}
+	var err error
+	opts.Value, err = parseAmount("value", req.Value, big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+	opts.GasPrice, err = parseAmount("gas_price", req.GasPrice, node.options.GasPrice)
+	if err != nil {
+		return nil, err
+	}
+	opts.GasLimit, err = parseAmount("gas_limit", req.GasLimit, big.NewInt(rpc.MaxGasLimit))
+	if err != nil {
+		return nil, err
+	}
+	return transaction.NewTransaction(opts), nil
+}
+
+func (node *Node) serveRawTransaction(r *http.Request) (interface{}, error) {
+	var req rpc.SendRawTransactionRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := new(transaction.Transaction).FromProto(req.Data)
+	if err != nil {
+		return nil, newNodeError("invalid transaction data: " + err.Error())
+	}
+
+	node.mining.Lock()
+	defer node.mining.Unlock()
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return node.submit(tx)
+}
+
+func (node *Node) serveBlockByHash(r *http.Request) (interface{}, error) {
+	var req rpc.GetBlockByHashRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	block, ok := node.blockHashes[req.Hash]
+	if !ok {
+		return nil, newNodeError("block not found")
+	}
+	return block.fullResult(req.FullFillTransaction), nil
+}
+
+func (node *Node) serveBlockByHeight(r *http.Request) (interface{}, error) {
+	var req rpc.GetBlockByHeightRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	if req.Height == 0 || req.Height > uint64(len(node.blocks)) {
+		return nil, newNodeError("block not found")
+	}
+	return node.blocks[req.Height-1].fullResult(req.FullFillTransaction), nil
+}
+
+func (node *Node) serveTransactionReceipt(r *http.Request) (interface{}, error) {
+	var req rpc.HashRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	record, ok := node.txs[req.Hash]
+	if !ok {
+		return nil, newNodeError("transaction not found")
+	}
+	return record.result(), nil
+}
+
+func (node *Node) serveTransactionByContract(r *http.Request) (interface{}, error) {
+	var req rpc.GetTransactionByContractRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	contract, ok := node.contracts[req.Address]
+	if !ok || contract.deployTx == nil {
+		return nil, newNodeError("contract not found")
+	}
+	return contract.deployTx.result(), nil
+}
+
+func (node *Node) serveGasPrice(r *http.Request) (interface{}, error) {
+	return &rpc.GasPriceResult{GasPrice: node.options.GasPrice.String()}, nil
+}
+
+func (node *Node) serveEventsByHash(r *http.Request) (interface{}, error) {
+	var req rpc.HashRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	record, ok := node.txs[req.Hash]
+	if !ok {
+		return nil, newNodeError("transaction not found")
+	}
+	if record.status == rpc.TransactionStatusPending {
+		return nil, newNodeError("transaction is pending")
+	}
+	return &rpc.EventsResult{Events: record.events}, nil
+}
+
+// serveDynasty returns the coinbase, the only miner of the node.
+func (node *Node) serveDynasty(r *http.Request) (interface{}, error) {
+	var req rpc.ByBlockHeightRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.GetDynastyResult{Miners: []string{node.Coinbase()}}, nil
+}
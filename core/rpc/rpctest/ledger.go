@@ -0,0 +1,439 @@
+package rpctest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils/base58"
+	"github.com/vigozhang/neb-go/utils/byteutils"
+	"github.com/vigozhang/neb-go/utils/hash"
+)
+
+// ContractCall is a call of a contract function by a transaction or
+// /user/call.
+type ContractCall struct {
+	From     string
+	Contract string
+	Value    *big.Int
+	Function string
+	Args     string
+}
+
+// ContractFunc stubs the functions of a contract. result is returned as the
+// execute result, a non-nil err fails the transaction with err as the
+// execute error. It runs without the lock of the Node and may call its
+// methods, except Mine which waits for the block being packed.
+type ContractFunc func(call *ContractCall) (result string, err error)
+
+type accountState struct {
+	balance *big.Int
+	nonce   uint64
+}
+
+type contractState struct {
+	// deployTx the deploy transaction, nil for contracts registered without
+	// a deploy
+	deployTx *txRecord
+	call     ContractFunc
+}
+
+type txRecord struct {
+	tx   *transaction.Transaction
+	hash string
+
+	status          int32
+	gasUsed         *big.Int
+	executeError    string
+	executeResult   string
+	contractAddress string
+	blockHeight     uint64
+	events          []*rpc.Event
+}
+
+type blockRecord struct {
+	result *rpc.BlockResult
+	txs    []*txRecord
+}
+
+func (node *Node) account(address string) *accountState {
+	state, ok := node.accounts[address]
+	if !ok {
+		state = &accountState{balance: big.NewInt(0)}
+		node.accounts[address] = state
+	}
+	return state
+}
+
+// SetBalance sets the balance of address, in wei.
+func (node *Node) SetBalance(address string, balance *big.Int) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.account(address).balance.Set(balance)
+}
+
+// Balance returns the balance of address, in wei.
+func (node *Node) Balance(address string) *big.Int {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return new(big.Int).Set(node.account(address).balance)
+}
+
+// Nonce returns the nonce of the last packed transaction of address.
+func (node *Node) Nonce(address string) uint64 {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return node.account(address).nonce
+}
+
+// RegisterContract stubs the functions of the contract at address with call.
+// The contract does not need to be deployed first.
+func (node *Node) RegisterContract(address string, call ContractFunc) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	contract, ok := node.contracts[address]
+	if !ok {
+		contract = &contractState{}
+		node.contracts[address] = contract
+	}
+	contract.call = call
+}
+
+// Height returns the height of the tail block.
+func (node *Node) Height() uint64 {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return node.tail().result.Height
+}
+
+func (node *Node) tail() *blockRecord {
+	return node.blocks[len(node.blocks)-1]
+}
+
+func (node *Node) lib() *blockRecord {
+	height := uint64(len(node.blocks))
+	if height > node.options.LibDepth {
+		height -= node.options.LibDepth
+	} else {
+		height = 1
+	}
+	return node.blocks[height-1]
+}
+
+// submit checks a signed transaction and adds it to the pending ones, like
+// the transaction pool of a node. Nonce gaps are refused, the node does not
+// queue transactions for later.
+func (node *Node) submit(tx *transaction.Transaction) (*rpc.SendTransactionResult, error) {
+	err := tx.Verify()
+	if err != nil {
+		return nil, newNodeError(err.Error())
+	}
+	if tx.ChainID != node.options.ChainID {
+		return nil, newNodeError("invalid transaction chainID")
+	}
+	if tx.GasPrice.Cmp(node.options.GasPrice) < 0 {
+		return nil, newNodeError("below the gas price")
+	}
+	if tx.GasLimit.Sign() <= 0 || tx.GasLimit.Cmp(big.NewInt(rpc.MaxGasLimit)) > 0 {
+		return nil, newNodeError("invalid transaction gas limit")
+	}
+	payload, err := tx.DecodePayload()
+	if err != nil {
+		return nil, newNodeError(err.Error())
+	}
+
+	txHash := hex.EncodeToString(tx.Hash)
+	if _, ok := node.txs[txHash]; ok {
+		return nil, newNodeError("duplicated transaction")
+	}
+
+	from := tx.From.GetAddressString()
+	nonce := node.account(from).nonce
+	if tx.Nonce <= nonce {
+		return nil, newNodeError("transaction's nonce is invalid, should bigger than the from's nonce")
+	}
+	for _, record := range node.pending {
+		if record.tx.From.GetAddressString() == from {
+			nonce = record.tx.Nonce
+		}
+	}
+	if tx.Nonce <= nonce {
+		return nil, newNodeError("cannot accept a transaction with smaller nonce")
+	}
+	if tx.Nonce > nonce+1 {
+		return nil, newNodeError("cannot accept a transaction with too bigger nonce")
+	}
+
+	record := &txRecord{tx: tx, hash: txHash, status: rpc.TransactionStatusPending}
+	if _, ok := payload.(*transaction.TransactionDeployPayload); ok {
+		if tx.To.GetAddressString() != from {
+			return nil, newNodeError("contract transaction from-address not equal to to-address")
+		}
+		record.contractAddress = contractAddress(tx.From.GetAddress(), tx.Nonce)
+	}
+	node.txs[txHash] = record
+	node.pending = append(node.pending, record)
+	node.publish(rpc.TopicPendingTransaction, transactionEvent(tx))
+
+	if node.options.AutoMine {
+		node.mine()
+	}
+	return &rpc.SendTransactionResult{Txhash: txHash, ContractAddress: record.contractAddress}, nil
+}
+
+// Mine packs the pending transactions into a new tail block and returns it.
+func (node *Node) Mine() *rpc.BlockResult {
+	node.mining.Lock()
+	defer node.mining.Unlock()
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	block := *node.mine().result
+	return &block
+}
+
+func (node *Node) mine() *blockRecord {
+	result := &rpc.BlockResult{
+		Height:    uint64(len(node.blocks)) + 1,
+		Coinbase:  node.Coinbase(),
+		Miner:     node.Coinbase(),
+		Timestamp: time.Now().Unix(),
+		ChainId:   node.options.ChainID,
+	}
+	parentHash := []byte{}
+	if len(node.blocks) > 0 {
+		result.ParentHash = node.tail().result.Hash
+		parentHash, _ = hex.DecodeString(result.ParentHash)
+	}
+
+	block := &blockRecord{result: result, txs: node.pending}
+	node.pending = nil
+	txHashes := make([]byte, 0, 32*len(block.txs))
+	for _, record := range block.txs {
+		record.blockHeight = result.Height
+		node.apply(record)
+		txHashes = append(txHashes, record.tx.Hash...)
+	}
+	result.Hash = hex.EncodeToString(hash.Sha3256(parentHash, byteutils.FromUint64(result.Height),
+		byteutils.FromInt64(result.Timestamp), txHashes))
+
+	var lib *blockRecord
+	if len(node.blocks) > 0 {
+		lib = node.lib()
+	}
+	node.blocks = append(node.blocks, block)
+	node.blockHashes[result.Hash] = block
+	libHeight := node.lib().result.Height
+	for _, linked := range node.blocks {
+		linked.result.IsFinality = linked.result.Height <= libHeight
+	}
+
+	for _, record := range block.txs {
+		for _, event := range record.events {
+			node.publish(event.Topic, json.RawMessage(event.Data))
+		}
+	}
+	node.publish(rpc.TopicLinkBlock, blockEvent(block))
+	node.publish(rpc.TopicNewTailBlock, blockEvent(block))
+	if node.lib() != lib {
+		node.publish(rpc.TopicLatestIrreversibleBlock, blockEvent(node.lib()))
+	}
+	return block
+}
+
+// apply executes a packed transaction: the gas is paid to the coinbase and
+// the value is transferred when the transaction succeeds.
+func (node *Node) apply(record *txRecord) {
+	tx := record.tx
+	from := node.account(tx.From.GetAddressString())
+	from.nonce = tx.Nonce
+
+	gasUsed := transactionGas(tx)
+	err := error(nil)
+	if gasUsed.Cmp(tx.GasLimit) > 0 {
+		gasUsed.Set(tx.GasLimit)
+		err = errors.New("out of gas limit")
+	}
+	fee := new(big.Int).Mul(gasUsed, tx.GasPrice)
+	if from.balance.Cmp(fee) < 0 {
+		fee.Set(from.balance)
+		err = errors.New("insufficient balance")
+	}
+	from.balance.Sub(from.balance, fee)
+	coinbase := node.account(node.Coinbase())
+	coinbase.balance.Add(coinbase.balance, fee)
+	record.gasUsed = gasUsed
+
+	if err == nil {
+		record.executeResult, err = node.execute(record)
+	}
+	record.status = rpc.TransactionStatusSuccess
+	if err != nil {
+		record.status = rpc.TransactionStatusFailed
+		record.executeError = err.Error()
+	}
+
+	record.events = append(record.events, &rpc.Event{
+		Topic: rpc.TopicTransactionResult,
+		Data: string(mustMarshal(&rpc.TransactionResultEvent{
+			Hash:          record.hash,
+			Status:        record.status,
+			GasUsed:       record.gasUsed.String(),
+			Error:         record.executeError,
+			ExecuteResult: record.executeResult,
+		})),
+	})
+}
+
+func (node *Node) execute(record *txRecord) (string, error) {
+	tx := record.tx
+	from := tx.From.GetAddressString()
+	to := tx.To.GetAddressString()
+	if node.account(from).balance.Cmp(tx.Value) < 0 {
+		return "", errors.New("insufficient balance")
+	}
+
+	result := ""
+	payload, _ := tx.DecodePayload()
+	switch payload := payload.(type) {
+	case *transaction.TransactionDeployPayload:
+		to = record.contractAddress
+		contract, ok := node.contracts[to]
+		if !ok {
+			contract = &contractState{}
+			node.contracts[to] = contract
+		}
+		contract.deployTx = record
+	case *transaction.TransactionCallPayload:
+		contract, ok := node.contracts[to]
+		if !ok {
+			return "", errors.New("contract check failed")
+		}
+		if contract.call != nil {
+			var err error
+			result, err = node.callContract(contract.call, &ContractCall{
+				From:     from,
+				Contract: to,
+				Value:    new(big.Int).Set(tx.Value),
+				Function: payload.Function,
+				Args:     payload.Args,
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// the stub may have spent the balance meanwhile
+	if node.account(from).balance.Cmp(tx.Value) < 0 {
+		return "", errors.New("insufficient balance")
+	}
+	node.transfer(from, to, tx.Value)
+	return result, nil
+}
+
+// callContract runs a stub with node.mu released, the caller holds node.mu
+// and node.mining too when the call is packed into a block.
+func (node *Node) callContract(call ContractFunc, contractCall *ContractCall) (string, error) {
+	node.mu.Unlock()
+	defer node.mu.Lock()
+
+	return call(contractCall)
+}
+
+func (node *Node) transfer(from string, to string, value *big.Int) {
+	fromState := node.account(from)
+	toState := node.account(to)
+	fromState.balance.Sub(fromState.balance, value)
+	toState.balance.Add(toState.balance, value)
+}
+
+// transactionGas the gas the node charges for tx, contracts run for free.
+func transactionGas(tx *transaction.Transaction) *big.Int {
+	return big.NewInt(TransactionGas + int64(len(tx.Data.Payload)))
+}
+
+// contractAddress the address of the contract deployed by from with nonce,
+// the same the chain derives.
+func contractAddress(from []byte, nonce uint64) string {
+	content := append([]byte{account.AddressPrefix, account.ContractType},
+		hash.Ripemd160(hash.Sha3256(from, byteutils.FromUint64(nonce)))...)
+	checksum := hash.Sha3256(content)[0:4]
+	return base58.Encode(append(content, checksum...))
+}
+
+func (record *txRecord) result() *rpc.TransactionResult {
+	tx := record.tx
+	result := &rpc.TransactionResult{
+		Hash:            record.hash,
+		ChainId:         tx.ChainID,
+		From:            tx.From.GetAddressString(),
+		To:              tx.To.GetAddressString(),
+		Value:           tx.Value.String(),
+		Nonce:           tx.Nonce,
+		Timestamp:       tx.Timestamp,
+		Type:            tx.Data.Type,
+		Data:            tx.Data.Payload,
+		GasPrice:        tx.GasPrice.String(),
+		GasLimit:        tx.GasLimit.String(),
+		ContractAddress: record.contractAddress,
+		Status:          record.status,
+		ExecuteError:    record.executeError,
+		ExecuteResult:   record.executeResult,
+		BlockHeight:     record.blockHeight,
+	}
+	if record.gasUsed != nil {
+		result.GasUsed = record.gasUsed.String()
+	}
+	return result
+}
+
+func (block *blockRecord) fullResult(fullFillTransaction bool) *rpc.BlockResult {
+	result := *block.result
+	result.Transactions = make([]*rpc.TransactionResult, 0, len(block.txs))
+	for _, record := range block.txs {
+		if fullFillTransaction {
+			result.Transactions = append(result.Transactions, record.result())
+		} else {
+			result.Transactions = append(result.Transactions, &rpc.TransactionResult{Hash: record.hash})
+		}
+	}
+	return &result
+}
+
+func blockEvent(block *blockRecord) *rpc.BlockEvent {
+	return &rpc.BlockEvent{
+		Height:     block.result.Height,
+		Hash:       block.result.Hash,
+		ParentHash: block.result.ParentHash,
+		Timestamp:  block.result.Timestamp,
+		Tx:         len(block.txs),
+		Miner:      block.result.Miner,
+	}
+}
+
+func transactionEvent(tx *transaction.Transaction) *rpc.TransactionEvent {
+	return &rpc.TransactionEvent{
+		ChainId:   tx.ChainID,
+		Hash:      hex.EncodeToString(tx.Hash),
+		From:      tx.From.GetAddressString(),
+		To:        tx.To.GetAddressString(),
+		Nonce:     tx.Nonce,
+		Value:     tx.Value.String(),
+		Timestamp: tx.Timestamp,
+		GasPrice:  tx.GasPrice.String(),
+		GasLimit:  tx.GasLimit.String(),
+		Data:      string(tx.Data.Payload),
+		Type:      tx.Data.Type,
+	}
+}
@@ -0,0 +1,247 @@
+// Package rpctest runs an in-process fake Nebulas node for tests that should
+// not depend on a live network. The node serves the /v1/user and /v1/admin
+// endpoints used by rpc.Api and rpc.Admin from an in-memory ledger: it
+// accepts signed raw transactions, applies value transfers and nonces, packs
+// the transactions into blocks and streams the chain events to subscribers.
+//
+// Contracts are not executed, the node has no VM. Deploy transactions create
+// the contract account and the functions called on a contract can be stubbed
+// with RegisterContract.
+package rpctest
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+const (
+	// DefaultChainID the chain id of the node when none is given, the one of
+	// a local node.
+	DefaultChainID = 100
+
+	// DefaultGasPrice the lowest gas price the node accepts when none is
+	// given, the one of the main net.
+	DefaultGasPrice = 1000000
+
+	// TransactionGas the gas every transaction costs before the gas of its
+	// payload, one per byte.
+	TransactionGas = 20000
+)
+
+// Options configure a Node, the zero value is a local node without
+// balances whose transactions wait for Mine.
+type Options struct {
+	// ChainID the chain id of the node, DefaultChainID when 0.
+	ChainID uint32
+	// GasPrice the lowest gas price the node accepts, DefaultGasPrice when
+	// nil.
+	GasPrice *big.Int
+	// Balances the balances of the genesis block by address, in wei.
+	Balances map[string]*big.Int
+	// AutoMine packs every accepted transaction into a block of its own.
+	// Otherwise the transactions stay pending until Mine is called.
+	AutoMine bool
+	// LibDepth how many blocks the latest irreversible block trails the tail
+	// block.
+	LibDepth uint64
+}
+
+// Node is a fake Nebulas node served by an httptest.Server.
+type Node struct {
+	options  Options
+	server   *httptest.Server
+	coinbase *account.Account
+
+	// mining serializes the blocks. It is taken before mu, which is released
+	// while a ContractFunc runs so that the stub may use the Node.
+	mining      sync.Mutex
+	mu          sync.Mutex
+	accounts    map[string]*accountState
+	contracts   map[string]*contractState
+	keys        map[string]*nodeKey
+	pending     []*txRecord
+	txs         map[string]*txRecord
+	blocks      []*blockRecord
+	blockHashes map[string]*blockRecord
+	subscribers map[*subscriber]bool
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewNode starts a node with the genesis block of options. Close it when the
+// test is done.
+func NewNode(options Options) *Node {
+	if options.ChainID == 0 {
+		options.ChainID = DefaultChainID
+	}
+	if options.GasPrice == nil {
+		options.GasPrice = big.NewInt(DefaultGasPrice)
+	}
+
+	node := &Node{
+		options:     options,
+		coinbase:    account.NewAccount(),
+		accounts:    make(map[string]*accountState),
+		contracts:   make(map[string]*contractState),
+		keys:        make(map[string]*nodeKey),
+		txs:         make(map[string]*txRecord),
+		blockHashes: make(map[string]*blockRecord),
+		subscribers: make(map[*subscriber]bool),
+		closed:      make(chan struct{}),
+	}
+	for address, balance := range options.Balances {
+		node.account(address).balance.Set(balance)
+	}
+	node.mine()
+
+	node.server = httptest.NewServer(node)
+	return node
+}
+
+// URL the base url of the node, e.g. http://127.0.0.1:38471.
+func (node *Node) URL() string {
+	return node.server.URL
+}
+
+// Request returns a request to the v1 api of the node.
+func (node *Node) Request() *httprequest.HttpRequest {
+	return httprequest.NewHttpRequest(node.URL(), httprequest.APIVersion1)
+}
+
+// Neb returns a client of the node.
+func (node *Node) Neb() *rpc.Neb {
+	return rpc.NewNeb(node.Request())
+}
+
+// Close ends the subscribe streams and shuts the server down.
+func (node *Node) Close() {
+	node.closeOnce.Do(func() {
+		close(node.closed)
+		node.server.Close()
+	})
+}
+
+// ChainID the chain id of the node.
+func (node *Node) ChainID() uint32 {
+	return node.options.ChainID
+}
+
+// Coinbase the address the transaction fees are paid to.
+func (node *Node) Coinbase() string {
+	return node.coinbase.GetAddressString()
+}
+
+// nodeError is an error the node answers with a 400 status and the message
+// in the error field, like the rpc gateway of a real node.
+type nodeError struct {
+	message string
+}
+
+func (e *nodeError) Error() string {
+	return e.message
+}
+
+func newNodeError(message string) error {
+	return &nodeError{message}
+}
+
+type route struct {
+	method string
+	serve  func(r *http.Request) (interface{}, error)
+}
+
+func (node *Node) routes() map[string]route {
+	return map[string]route{
+		"/user/nebstate":                 {http.MethodGet, node.serveNebState},
+		"/user/lib":                      {http.MethodGet, node.serveLatestIrreversibleBlock},
+		"/user/accountstate":             {http.MethodPost, node.serveAccountState},
+		"/user/call":                     {http.MethodPost, node.serveCall},
+		"/user/rawtransaction":           {http.MethodPost, node.serveRawTransaction},
+		"/user/getBlockByHash":           {http.MethodPost, node.serveBlockByHash},
+		"/user/getBlockByHeight":         {http.MethodPost, node.serveBlockByHeight},
+		"/user/getTransactionReceipt":    {http.MethodPost, node.serveTransactionReceipt},
+		"/user/getTransactionByContract": {http.MethodPost, node.serveTransactionByContract},
+		"/user/getGasPrice":              {http.MethodGet, node.serveGasPrice},
+		"/user/estimateGas":              {http.MethodPost, node.serveEstimateGas},
+		"/user/getEventsByHash":          {http.MethodPost, node.serveEventsByHash},
+		"/user/dynasty":                  {http.MethodPost, node.serveDynasty},
+
+		"/admin/nodeinfo":                  {http.MethodGet, node.serveNodeInfo},
+		"/admin/accounts":                  {http.MethodGet, node.serveAccounts},
+		"/admin/account/new":               {http.MethodPost, node.serveNewAccount},
+		"/admin/account/unlock":            {http.MethodPost, node.serveUnlockAccount},
+		"/admin/account/lock":              {http.MethodPost, node.serveLockAccount},
+		"/admin/transaction":               {http.MethodPost, node.serveSendTransaction},
+		"/admin/sign/hash":                 {http.MethodPost, node.serveSignHash},
+		"/admin/sign":                      {http.MethodPost, node.serveSignTransaction},
+		"/admin/transactionWithPassphrase": {http.MethodPost, node.serveSendTransactionWithPassphrase},
+		"/admin/pprof":                     {http.MethodPost, node.servePprof},
+		"/admin/getConfig":                 {http.MethodGet, node.serveConfig},
+	}
+}
+
+func (node *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	if path == "/user/subscribe" && r.Method == http.MethodPost {
+		node.serveSubscribe(w, r)
+		return
+	}
+
+	route, ok := node.routes()[path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != route.method {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := route.serve(r)
+
+	response := struct {
+		Result interface{} `json:"result"`
+		Error  string      `json:"error,omitempty"`
+	}{Result: result}
+	status := http.StatusOK
+	if err != nil {
+		response.Result = nil
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		if _, ok := err.(*nodeError); !ok {
+			status = http.StatusInternalServerError
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&response)
+}
+
+func decodeRequest(r *http.Request, req interface{}) error {
+	err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(req)
+	if err != nil && err != io.EOF {
+		return newNodeError("invalid request: " + err.Error())
+	}
+	return nil
+}
+
+func parseAmount(name string, value string, defaultValue *big.Int) (*big.Int, error) {
+	if value == "" {
+		return new(big.Int).Set(defaultValue), nil
+	}
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, newNodeError("invalid " + name + " " + value)
+	}
+	return amount, nil
+}
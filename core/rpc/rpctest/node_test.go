@@ -0,0 +1,258 @@
+package rpctest
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+)
+
+const testTo = "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17"
+
+func newTestNode(t *testing.T, options Options) (*Node, *account.Account) {
+	acc := account.NewAccount()
+	options.Balances = map[string]*big.Int{acc.GetAddressString(): big.NewInt(1e18)}
+	node := NewNode(options)
+	t.Cleanup(node.Close)
+	return node, acc
+}
+
+func TestNode_SendRawTransaction(t *testing.T) {
+	node, acc := newTestNode(t, Options{AutoMine: true})
+	api := node.Neb().Api
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	builder := rpc.NewTransactionBuilder(api)
+	tx, resp, err := builder.Send(ctx, transaction.TransactionOptions{From: acc, To: testTo, Value: big.NewInt(1000)})
+	if err != nil {
+		t.Fatal("TestNode_SendRawTransaction failed:", err)
+	}
+	if tx.Nonce != 1 || tx.ChainID != DefaultChainID {
+		t.Errorf("TestNode_SendRawTransaction built nonce %d chain id %d", tx.Nonce, tx.ChainID)
+	}
+
+	receipt, err := api.WaitForReceipt(ctx, resp.Result.Txhash, rpc.WaitOptions{PollInterval: 10 * time.Millisecond, Finality: true})
+	if err != nil || !receipt.Success() || receipt.BlockHeight != 2 {
+		t.Fatalf("TestNode_SendRawTransaction receipt %+v: %v", receipt, err)
+	}
+	if receipt.GasUsed.Int64() != TransactionGas {
+		t.Errorf("TestNode_SendRawTransaction used %s gas", receipt.GasUsed)
+	}
+
+	state, err := api.GetAccountState(rpc.GetAccountStateRequest{Address: acc.GetAddressString()})
+	fee := new(big.Int).Mul(receipt.GasUsed, tx.GasPrice)
+	balance := new(big.Int).Sub(big.NewInt(1e18), fee)
+	balance.Sub(balance, big.NewInt(1000))
+	if err != nil || state.Result.Nonce != 1 || state.Result.Balance != balance.String() {
+		t.Errorf("TestNode_SendRawTransaction account state %+v: %v", state, err)
+	}
+	if node.Balance(testTo).Int64() != 1000 || node.Balance(node.Coinbase()).Cmp(fee) != 0 {
+		t.Errorf("TestNode_SendRawTransaction balances %s and %s", node.Balance(testTo), node.Balance(node.Coinbase()))
+	}
+
+	raw, _ := tx.ToProtoString()
+	_, err = api.SendRawTransaction(rpc.SendRawTransactionRequest{Data: raw})
+	if !errors.Is(err, rpc.ErrDuplicatedTransaction) {
+		t.Error("TestNode_SendRawTransaction resent:", err)
+	}
+
+	tx.Timestamp++
+	tx.SignTransaction()
+	raw, _ = tx.ToProtoString()
+	_, err = api.SendRawTransaction(rpc.SendRawTransactionRequest{Data: raw})
+	if !errors.Is(err, rpc.ErrNonceTooLow) {
+		t.Error("TestNode_SendRawTransaction reused nonce:", err)
+	}
+
+	block, err := api.GetBlockByHeight(rpc.GetBlockByHeightRequest{Height: 2, FullFillTransaction: true})
+	if err != nil || len(block.Result.Transactions) != 1 || block.Result.Transactions[0].Hash != resp.Result.Txhash {
+		t.Errorf("TestNode_SendRawTransaction block %+v: %v", block, err)
+	}
+}
+
+func TestNode_Mine(t *testing.T) {
+	node, acc := newTestNode(t, Options{LibDepth: 2})
+	api := node.Neb().Api
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sub := api.NewSubscription(ctx, rpc.SubscribeRequest{
+		Topics: []string{rpc.TopicPendingTransaction, rpc.TopicLinkBlock, rpc.TopicTransactionResult},
+	}, rpc.SubscriptionOptions{})
+	defer sub.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	builder := rpc.NewTransactionBuilder(api)
+	builder.Nonces = rpc.NewNonceManager(api)
+	var hashes []string
+	for i := 0; i < 2; i++ {
+		_, resp, err := builder.Send(ctx, transaction.TransactionOptions{From: acc, To: testTo, Value: big.NewInt(1)})
+		if err != nil {
+			t.Fatal("TestNode_Mine send failed:", err)
+		}
+		hashes = append(hashes, resp.Result.Txhash)
+	}
+
+	receipt, err := api.GetTransactionReceipt(rpc.HashRequest{Hash: hashes[1]})
+	if err != nil || receipt.Result.Status != rpc.TransactionStatusPending {
+		t.Errorf("TestNode_Mine pending receipt %+v: %v", receipt, err)
+	}
+
+	block := node.Mine()
+	if block.Height != 2 || node.Nonce(acc.GetAddressString()) != 2 {
+		t.Errorf("TestNode_Mine mined height %d nonce %d", block.Height, node.Nonce(acc.GetAddressString()))
+	}
+	lib, err := api.LatestIrreversibleBlock()
+	if err != nil || lib.Result.Height != 1 {
+		t.Errorf("TestNode_Mine lib %+v: %v", lib, err)
+	}
+
+	topics := make(map[string]int)
+	for topics[rpc.TopicLinkBlock] == 0 {
+		select {
+		case result := <-sub.Results():
+			topics[result.Topic]++
+			if _, err := result.Decode(); err != nil {
+				t.Error("TestNode_Mine decode failed:", err)
+			}
+		case <-ctx.Done():
+			t.Fatalf("TestNode_Mine got %v", topics)
+		}
+	}
+	if topics[rpc.TopicPendingTransaction] != 2 || topics[rpc.TopicTransactionResult] != 2 {
+		t.Errorf("TestNode_Mine got %v", topics)
+	}
+}
+
+func TestNode_Admin(t *testing.T) {
+	node, _ := newTestNode(t, Options{AutoMine: true})
+	neb := node.Neb()
+
+	resp, err := neb.Admin.NewAccount(rpc.NewAccountRequest{Passphrase: "passphrase"})
+	if err != nil {
+		t.Fatal("TestNode_Admin failed:", err)
+	}
+	address := resp.Result.Address
+	node.SetBalance(address, big.NewInt(1e18))
+
+	req := rpc.TransactionRequest{From: address, To: testTo, Value: "10", Nonce: 1}
+	if _, err := neb.Admin.SendTransaction(req); err == nil {
+		t.Error("TestNode_Admin sent with a locked account")
+	}
+	if _, err := neb.Admin.UnlockAccount(rpc.UnlockAccountRequest{Address: address, Passphrase: "wrong"}); err == nil {
+		t.Error("TestNode_Admin unlocked with a wrong passphrase")
+	}
+	if _, err := neb.Admin.UnlockAccount(rpc.UnlockAccountRequest{Address: address, Passphrase: "passphrase"}); err != nil {
+		t.Fatal("TestNode_Admin unlock failed:", err)
+	}
+	if _, err := neb.Admin.SendTransaction(req); err != nil {
+		t.Fatal("TestNode_Admin send failed:", err)
+	}
+
+	from, _ := account.FromAddress(address)
+	builder := rpc.NewTransactionBuilder(neb.Api)
+	builder.Signer = rpc.NewAdminSigner(neb.Admin, address)
+	if _, _, err := builder.Send(context.Background(), transaction.TransactionOptions{From: from, To: testTo, Value: big.NewInt(5)}); err != nil {
+		t.Fatal("TestNode_Admin send with the admin signer failed:", err)
+	}
+	if node.Balance(testTo).Int64() != 15 || node.Nonce(address) != 2 {
+		t.Errorf("TestNode_Admin balance %s nonce %d", node.Balance(testTo), node.Nonce(address))
+	}
+
+	accounts, err := neb.Admin.Accounts()
+	if err != nil || len(accounts.Result.Addresses) != 1 || accounts.Result.Addresses[0] != address {
+		t.Errorf("TestNode_Admin accounts %+v: %v", accounts, err)
+	}
+}
+
+func TestNode_Contract(t *testing.T) {
+	node, acc := newTestNode(t, Options{AutoMine: true})
+	api := node.Neb().Api
+	ctx := context.Background()
+
+	builder := rpc.NewTransactionBuilder(api)
+	source := "module.exports = function() {};"
+	_, resp, err := builder.Send(ctx, transaction.TransactionOptions{
+		From:     acc,
+		To:       acc.GetAddressString(),
+		Contract: &transaction.Contract{Source: source, SourceType: transaction.SourceTypeJavaScript},
+	})
+	if err != nil || !account.IsValidAddress(resp.Result.ContractAddress) {
+		t.Fatalf("TestNode_Contract deploy %+v: %v", resp, err)
+	}
+	contract := resp.Result.ContractAddress
+
+	deployTx, err := api.GetTransactionByContract(rpc.GetTransactionByContractRequest{Address: contract})
+	if err != nil || deployTx.Result.Hash != resp.Result.Txhash {
+		t.Errorf("TestNode_Contract deploy transaction %+v: %v", deployTx, err)
+	}
+
+	node.RegisterContract(contract, func(call *ContractCall) (string, error) {
+		if call.Function != "get" {
+			return "", errors.New("unknown function " + call.Function)
+		}
+		return `"value"`, nil
+	})
+
+	callReq := rpc.TransactionRequest{From: acc.GetAddressString(), To: contract, Contract: &rpc.ContractRequest{Function: "get"}}
+	call, err := api.Call(callReq)
+	if err != nil || call.Result.Result != `"value"` || call.Result.ExecuteErr != "" {
+		t.Errorf("TestNode_Contract call %+v: %v", call, err)
+	}
+
+	_, resp, err = builder.Send(ctx, transaction.TransactionOptions{
+		From:     acc,
+		To:       contract,
+		GasLimit: big.NewInt(100000),
+		Contract: &transaction.Contract{Function: "set"},
+	})
+	if err != nil {
+		t.Fatal("TestNode_Contract call transaction failed:", err)
+	}
+	receipt, err := api.GetTransactionReceipt(rpc.HashRequest{Hash: resp.Result.Txhash})
+	if err != nil || receipt.Result.Status != rpc.TransactionStatusFailed || receipt.Result.ExecuteError != "unknown function set" {
+		t.Errorf("TestNode_Contract receipt %+v: %v", receipt, err)
+	}
+	events, err := api.GetEventsByHash(rpc.HashRequest{Hash: resp.Result.Txhash})
+	if err != nil || len(events.Result.Events) != 1 || events.Result.Events[0].Topic != rpc.TopicTransactionResult {
+		t.Errorf("TestNode_Contract events %+v: %v", events, err)
+	}
+}
+
+func TestNode_ContractUsesNode(t *testing.T) {
+	node, acc := newTestNode(t, Options{AutoMine: true})
+	api := node.Neb().Api
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	node.RegisterContract(testTo, func(call *ContractCall) (string, error) {
+		balance := node.Balance(call.Contract)
+		node.SetBalance(call.Contract, balance.Add(balance, big.NewInt(1)))
+		return balance.String(), nil
+	})
+
+	call, err := api.CallWithContext(ctx, rpc.TransactionRequest{From: acc.GetAddressString(), To: testTo, Contract: &rpc.ContractRequest{Function: "mint"}})
+	if err != nil || call.Result.Result != "1" {
+		t.Fatalf("TestNode_ContractUsesNode call %+v: %v", call, err)
+	}
+
+	_, resp, err := rpc.NewTransactionBuilder(api).Send(ctx, transaction.TransactionOptions{
+		From:     acc,
+		To:       testTo,
+		GasLimit: big.NewInt(100000),
+		Contract: &transaction.Contract{Function: "mint"},
+	})
+	if err != nil {
+		t.Fatal("TestNode_ContractUsesNode transaction failed:", err)
+	}
+	receipt, err := api.GetTransactionReceiptWithContext(ctx, rpc.HashRequest{Hash: resp.Result.Txhash})
+	if err != nil || receipt.Result.ExecuteResult != "2" || node.Balance(testTo).Int64() != 2 {
+		t.Errorf("TestNode_ContractUsesNode receipt %+v: %v", receipt, err)
+	}
+}
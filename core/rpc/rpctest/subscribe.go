@@ -0,0 +1,97 @@
+package rpctest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/vigozhang/neb-go/core/rpc"
+)
+
+// subscriberBuffer the events a subscriber can lag behind, later events are
+// dropped until it catches up.
+const subscriberBuffer = 256
+
+type subscriber struct {
+	topics map[string]bool
+	lines  chan []byte
+}
+
+// publish sends an event to the subscribers of topic. payload is encoded as
+// the json data of the event. It is called with node.mu held.
+func (node *Node) publish(topic string, payload interface{}) {
+	line := mustMarshal(&rpc.SubscribeResponse{
+		Result: &rpc.SubscribeResult{Topic: topic, Data: string(mustMarshal(payload))},
+	})
+	line = append(line, '\n')
+
+	for sub := range node.subscribers {
+		if !sub.topics[topic] {
+			continue
+		}
+		select {
+		case sub.lines <- line:
+		default:
+		}
+	}
+}
+
+// serveSubscribe streams the events of the requested topics as json lines
+// until the client goes away or the node is closed.
+func (node *Node) serveSubscribe(w http.ResponseWriter, r *http.Request) {
+	var req rpc.SubscribeRequest
+	err := decodeRequest(r, &req)
+	if err == nil && len(req.Topics) == 0 {
+		err = newNodeError("topics is empty")
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&rpc.SubscribeResponse{Error: err.Error()})
+		return
+	}
+
+	sub := &subscriber{topics: make(map[string]bool), lines: make(chan []byte, subscriberBuffer)}
+	for _, topic := range req.Topics {
+		sub.topics[topic] = true
+	}
+	node.mu.Lock()
+	node.subscribers[sub] = true
+	node.mu.Unlock()
+	defer func() {
+		node.mu.Lock()
+		delete(node.subscribers, sub)
+		node.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case line := <-sub.lines:
+			_, err := w.Write(line)
+			if err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		case <-node.closed:
+			return
+		}
+	}
+}
+
+func mustMarshal(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
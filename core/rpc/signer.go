@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+// AdminSigner is a transaction.Signer that signs with an account kept by a
+// node, through Admin.SignHash. The account must be unlocked on the node.
+type AdminSigner struct {
+	Admin   *Admin
+	Account string
+}
+
+var _ transaction.Signer = (*AdminSigner)(nil)
+
+func NewAdminSigner(admin *Admin, address string) *AdminSigner {
+	return &AdminSigner{Admin: admin, Account: address}
+}
+
+// NewRemoteSigner returns a signer for a remote signing service that serves
+// the node's /admin/sign/hash API, e.g. with a bearer token set on request.
+func NewRemoteSigner(request httprequest.Transport, address string) *AdminSigner {
+	return NewAdminSigner(&Admin{HttpRequest: request}, address)
+}
+
+func (signer *AdminSigner) Address() string {
+	return signer.Account
+}
+
+func (signer *AdminSigner) SignHash(hash []byte) ([]byte, error) {
+	return signer.SignHashWithContext(context.Background(), hash)
+}
+
+func (signer *AdminSigner) SignHashWithContext(ctx context.Context, hash []byte) ([]byte, error) {
+	req := SignHashRequest{
+		Address: signer.Account,
+		Hash:    base64.StdEncoding.EncodeToString(hash),
+		Alg:     transaction.SECP256K1,
+	}
+	resp, err := signer.Admin.SignHashWithContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Result == nil || len(resp.Result.Data) == 0 {
+		return nil, errors.New("SignHash returned no signature")
+	}
+	return resp.Result.Data, nil
+}
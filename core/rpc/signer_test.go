@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils/secp256k1"
+)
+
+// signingTransport serves /admin/sign/hash with the key of acc.
+type signingTransport struct {
+	acc *account.Account
+}
+
+func (s *signingTransport) GetWithContext(ctx context.Context, api string, params map[string]string) ([]byte, error) {
+	return nil, io.EOF
+}
+
+func (s *signingTransport) PostWithContext(ctx context.Context, api string, reqBody interface{}) ([]byte, error) {
+	req := reqBody.(SignHashRequest)
+	hash, _ := base64.StdEncoding.DecodeString(req.Hash)
+	sign, err := secp256k1.Sign(hash, s.acc.GetPrivateKey())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(SignHashResponse{Result: &SignHashResult{Data: sign}})
+}
+
+func (s *signingTransport) OpenStreamWithContext(ctx context.Context, api string, reqBody interface{}) (io.ReadCloser, error) {
+	return nil, io.EOF
+}
+
+func TestAdminSigner_SignHash(t *testing.T) {
+	acc := newBuilderTestAccount()
+	from, _ := account.FromAddress(acc.GetAddressString())
+
+	tx := transaction.NewTransaction(transaction.TransactionOptions{
+		ChainID:  1001,
+		From:     from,
+		To:       "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17",
+		Value:    big.NewInt(10),
+		Nonce:    1,
+		GasPrice: big.NewInt(1000000),
+		GasLimit: big.NewInt(20000),
+		Contract: &transaction.Contract{},
+	})
+
+	err := tx.SignWith(NewRemoteSigner(&signingTransport{acc}, acc.GetAddressString()))
+	if err != nil {
+		t.Fatal("TestAdminSigner_SignHash failed:", err)
+	}
+	if err := tx.Verify(); err != nil {
+		t.Error("TestAdminSigner_SignHash signed an invalid transaction:", err)
+	}
+
+	// a signer holding another key than it claims is caught
+	err = tx.SignWith(NewRemoteSigner(&signingTransport{account.NewAccount()}, acc.GetAddressString()))
+	if err == nil || tx.Sign != nil {
+		t.Error("TestAdminSigner_SignHash accepted the signature of another account")
+	}
+}
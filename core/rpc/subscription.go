@@ -0,0 +1,185 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// TopicReconnected is the topic of the marker a Subscription delivers after the
+// stream was reestablished. Events emitted by the node while it was down are
+// lost, consumers should backfill them, e.g. with GetBlockByHeight.
+const TopicReconnected = "neb-go.reconnected"
+
+type SubscriptionOptions struct {
+	// Backoff the delays between reconnects, DefaultRetryPolicy when nil.
+	// MaxAttempts is ignored: the subscription reconnects until it is closed
+	// or the node rejects the request.
+	Backoff *RetryPolicy
+	// BufferSize the capacity of the Results channel, 64 by default.
+	BufferSize int
+}
+
+// Subscription delivers the events of a subscribe stream on a channel and
+// reconnects when the stream drops.
+type Subscription struct {
+	api     *Api
+	req     SubscribeRequest
+	options SubscriptionOptions
+
+	results chan *SubscribeResult
+	errors  chan error
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	closeOnce sync.Once
+}
+
+// NewSubscription starts streaming the topics of req. The subscription runs
+// until ctx is done, Close is called or the node rejects the request.
+func (api *Api) NewSubscription(ctx context.Context, req SubscribeRequest, options SubscriptionOptions) *Subscription {
+	if options.Backoff == nil {
+		options.Backoff = &DefaultRetryPolicy
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = 64
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &Subscription{
+		api:     api,
+		req:     req,
+		options: options,
+		results: make(chan *SubscribeResult, options.BufferSize),
+		errors:  make(chan error, 16),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go sub.run(ctx)
+	return sub
+}
+
+// Results returns the channel of events, closed when the subscription ends.
+func (sub *Subscription) Results() <-chan *SubscribeResult {
+	return sub.results
+}
+
+// Errors returns the channel of stream and decoding errors, closed when the
+// subscription ends. Errors are dropped when nobody reads them.
+func (sub *Subscription) Errors() <-chan error {
+	return sub.errors
+}
+
+// Done is closed when the subscription has ended.
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// Close stops the subscription and waits for it to end.
+func (sub *Subscription) Close() {
+	sub.closeOnce.Do(sub.cancel)
+	<-sub.done
+}
+
+func (sub *Subscription) run(ctx context.Context) {
+	defer close(sub.done)
+	defer sub.cancel()
+	defer close(sub.errors)
+	defer close(sub.results)
+
+	failures, reconnects := 0, -1
+	for {
+		stream, err := sub.api.HttpRequest.OpenStreamWithContext(ctx, "/user/subscribe", sub.req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			retryable := sub.options.Backoff.retryable(ctx, err)
+			sub.error(transportError("Subscribe", err))
+			if !retryable {
+				return
+			}
+			failures++
+			if !sleep(ctx, sub.options.Backoff.backoff(failures)) {
+				return
+			}
+			continue
+		}
+
+		reconnects++
+		if reconnects > 0 {
+			marker := SubscribeResult{Topic: TopicReconnected, Data: fmt.Sprintf(`{"reconnects":%d}`, reconnects)}
+			if !sub.deliver(ctx, &marker) {
+				stream.Close()
+				return
+			}
+		}
+
+		received, err := sub.read(ctx, stream)
+		stream.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			sub.error(err)
+		}
+
+		if received {
+			failures = 0
+		}
+		failures++
+		if !sleep(ctx, sub.options.Backoff.backoff(failures)) {
+			return
+		}
+	}
+}
+
+// read delivers the events of stream until it ends and reports whether any
+// event was received.
+func (sub *Subscription) read(ctx context.Context, stream io.Reader) (bool, error) {
+	received := false
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var resp SubscribeResponse
+			if jsonErr := json.Unmarshal(line, &resp); jsonErr != nil {
+				sub.error(fmt.Errorf("Subscribe: decode %q: %s", line, jsonErr))
+			} else if resp.Error != "" {
+				sub.error(newNodeError("Subscribe", resp.Error))
+			} else if resp.Result != nil {
+				received = true
+				if !sub.deliver(ctx, resp.Result) {
+					return received, nil
+				}
+			}
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				return received, nil
+			}
+			return received, err
+		}
+	}
+}
+
+func (sub *Subscription) deliver(ctx context.Context, result *SubscribeResult) bool {
+	select {
+	case sub.results <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (sub *Subscription) error(err error) {
+	select {
+	case sub.errors <- err:
+	default:
+	}
+}
@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"testing"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/vigozhang/neb-go/utils/httprequest"
+)
+
+func TestSubscription_Reconnect(t *testing.T) {
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&connections, 1) {
+		case 1:
+			fmt.Fprintln(w, `{"result":{"topic":"chain.linkBlock","data":"1"}}`)
+			fmt.Fprintln(w, `not json`)
+			fmt.Fprintln(w, `{"result":{"topic":"chain.linkBlock","data":"2"}}`)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprintln(w, `{"result":{"topic":"chain.linkBlock","data":"3"}}`)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	neb := NewNeb(httprequest.NewHttpRequest(server.URL, httprequest.APIVersion1))
+	options := SubscriptionOptions{
+		Backoff: &RetryPolicy{InitialBackoff: time.Millisecond, RetryOnStatus: []int{http.StatusServiceUnavailable}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sub := neb.Api.NewSubscription(ctx, SubscribeRequest{Topics: []string{"chain.linkBlock"}}, options)
+	defer sub.Close()
+
+	var got []string
+	for len(got) < 4 {
+		select {
+		case result := <-sub.Results():
+			got = append(got, result.Topic+":"+result.Data)
+		case <-ctx.Done():
+			t.Fatalf("TestSubscription_Reconnect timed out, got %v", got)
+		}
+	}
+	sub.Close()
+
+	expected := []string{"chain.linkBlock:1", "chain.linkBlock:2", TopicReconnected + `:{"reconnects":1}`, "chain.linkBlock:3"}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("TestSubscription_Reconnect result %d is %s, expected %s", i, got[i], expected[i])
+		}
+	}
+
+	errs := 0
+	for range sub.Errors() {
+		errs++
+	}
+	if errs != 2 {
+		t.Errorf("TestSubscription_Reconnect expected a decode and a status error, got %d errors", errs)
+	}
+}
+
+func TestSubscription_Rejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, `{"error":"invalid topic"}`)
+	}))
+	defer server.Close()
+
+	neb := NewNeb(httprequest.NewHttpRequest(server.URL, httprequest.APIVersion1))
+	sub := neb.Api.NewSubscription(context.Background(), SubscribeRequest{Topics: []string{"x"}}, SubscriptionOptions{})
+
+	select {
+	case <-sub.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("TestSubscription_Rejected should end the subscription")
+	}
+	err := <-sub.Errors()
+	if nodeErr, ok := err.(*NodeError); !ok || nodeErr.Message != "invalid topic" {
+		t.Errorf("TestSubscription_Rejected unexpected error %v", err)
+	}
+}
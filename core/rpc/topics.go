@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	TopicPendingTransaction      = "chain.pendingTransaction"
+	TopicSendTransaction         = "chain.sendTransaction"
+	TopicDropTransaction         = "chain.dropTransaction"
+	TopicTransactionResult       = "chain.transactionResult"
+	TopicLinkBlock               = "chain.linkBlock"
+	TopicNewTailBlock            = "chain.newTailBlock"
+	TopicRevertBlock             = "chain.revertBlock"
+	TopicLatestIrreversibleBlock = "chain.latestIrreversibleBlock"
+	TopicContractEvent           = "chain.contractEvent"
+	TopicInnerTransferContract   = "chain.innerTransferContract"
+
+	// TopicContractPrefix prefixes the per-contract topics, chain.contract.<address>.
+	TopicContractPrefix = "chain.contract."
+)
+
+// ErrUnknownTopic the subscribe result has a topic without a typed payload
+var ErrUnknownTopic = errors.New("unknown subscribe topic")
+
+// BlockEvent is the payload of the block topics.
+type BlockEvent struct {
+	Height     uint64 `json:"height"`
+	Hash       string `json:"hash"`
+	ParentHash string `json:"parent_hash"`
+	AccRoot    string `json:"acc_root"`
+	Timestamp  int64  `json:"timestamp"`
+	// number of transactions in the block
+	Tx     int    `json:"tx"`
+	Miner  string `json:"miner"`
+	Random string `json:"random,omitempty"`
+}
+
+// TransactionEvent is the payload of the pending, send and drop transaction topics.
+type TransactionEvent struct {
+	ChainId   uint32 `json:"chainID"`
+	Hash      string `json:"hash"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Nonce     uint64 `json:"nonce"`
+	Value     string `json:"value"`
+	Timestamp int64  `json:"timestamp"`
+	GasPrice  string `json:"gasprice"`
+	GasLimit  string `json:"gaslimit"`
+	Data      string `json:"data"`
+	Type      string `json:"type"`
+}
+
+// TransactionResultEvent is the payload of chain.transactionResult.
+type TransactionResultEvent struct {
+	Hash string `json:"hash"`
+	// transaction status 0 failed, 1 success
+	Status        int32  `json:"status"`
+	GasUsed       string `json:"gas_used"`
+	Error         string `json:"error"`
+	ExecuteResult string `json:"execute_result"`
+}
+
+// InnerTransferEvent is the payload of chain.innerTransferContract.
+type InnerTransferEvent struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value string `json:"value"`
+	Error string `json:"error"`
+}
+
+// ContractEvent is the payload of chain.contractEvent and the per-contract
+// topics. Data is the JSON the contract emitted, decode it with Unmarshal.
+type ContractEvent struct {
+	Topic string
+	Data  json.RawMessage
+}
+
+func (event *ContractEvent) Unmarshal(v interface{}) error {
+	return json.Unmarshal(event.Data, v)
+}
+
+// ReconnectEvent is the payload of the TopicReconnected marker.
+type ReconnectEvent struct {
+	Reconnects int `json:"reconnects"`
+}
+
+// Decode returns the typed payload of the result: *BlockEvent,
+// *TransactionEvent, *TransactionResultEvent, *InnerTransferEvent,
+// *ContractEvent or *ReconnectEvent depending on the topic.
+func (result *SubscribeResult) Decode() (interface{}, error) {
+	var payload interface{}
+	switch result.Topic {
+	case TopicLinkBlock, TopicNewTailBlock, TopicRevertBlock, TopicLatestIrreversibleBlock:
+		payload = &BlockEvent{}
+	case TopicPendingTransaction, TopicSendTransaction, TopicDropTransaction:
+		payload = &TransactionEvent{}
+	case TopicTransactionResult:
+		payload = &TransactionResultEvent{}
+	case TopicInnerTransferContract:
+		payload = &InnerTransferEvent{}
+	case TopicReconnected:
+		payload = &ReconnectEvent{}
+	default:
+		if result.Topic == TopicContractEvent || strings.HasPrefix(result.Topic, TopicContractPrefix) {
+			if !json.Valid([]byte(result.Data)) {
+				return nil, fmt.Errorf("decode %s: invalid json data", result.Topic)
+			}
+			return &ContractEvent{Topic: result.Topic, Data: json.RawMessage(result.Data)}, nil
+		}
+		return nil, ErrUnknownTopic
+	}
+
+	err := json.Unmarshal([]byte(result.Data), payload)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %s", result.Topic, err)
+	}
+	return payload, nil
+}
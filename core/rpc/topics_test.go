@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"testing"
+)
+
+func TestSubscribeResult_Decode(t *testing.T) {
+	block := SubscribeResult{
+		Topic: TopicLinkBlock,
+		Data:  `{"height": 1024, "hash": "a1", "parent_hash": "a0", "acc_root": "b1", "timestamp": 1527000000, "tx": 2, "miner": "n1GmkKH6nBMw4rrjt16RrJ9WcgvKUtAZP1s"}`,
+	}
+	payload, err := block.Decode()
+	if event, ok := payload.(*BlockEvent); err != nil || !ok || event.Height != 1024 || event.Tx != 2 {
+		t.Errorf("TestSubscribeResult_Decode unexpected block %+v, %v", payload, err)
+	}
+
+	pending := SubscribeResult{
+		Topic: TopicPendingTransaction,
+		Data:  `{"chainID":1001, "hash":"c1", "from":"n1a", "to":"n1b", "nonce":7, "value":"10", "timestamp":1527000000, "gasprice": "1000000", "gaslimit":"20000", "type":"binary"}`,
+	}
+	payload, err = pending.Decode()
+	if event, ok := payload.(*TransactionEvent); err != nil || !ok || event.Nonce != 7 || event.GasPrice != "1000000" {
+		t.Errorf("TestSubscribeResult_Decode unexpected transaction %+v, %v", payload, err)
+	}
+
+	txResult := SubscribeResult{
+		Topic: TopicTransactionResult,
+		Data:  `{"hash":"c1","status":1,"gas_used":"20000","error":"","execute_result":"\"\""}`,
+	}
+	payload, err = txResult.Decode()
+	if event, ok := payload.(*TransactionResultEvent); err != nil || !ok || event.Status != 1 || event.GasUsed != "20000" {
+		t.Errorf("TestSubscribeResult_Decode unexpected transaction result %+v, %v", payload, err)
+	}
+
+	contract := SubscribeResult{
+		Topic: TopicContractPrefix + "n1f5rgBtVKVEjxPBwrDcaV8H8QqxniFyhPk",
+		Data:  `{"Transfer":{"from":"n1a","value":"3"}}`,
+	}
+	payload, err = contract.Decode()
+	event, ok := payload.(*ContractEvent)
+	if err != nil || !ok {
+		t.Fatalf("TestSubscribeResult_Decode unexpected contract event %+v, %v", payload, err)
+	}
+	var transfer struct {
+		Transfer struct {
+			Value string `json:"value"`
+		}
+	}
+	if err := event.Unmarshal(&transfer); err != nil || transfer.Transfer.Value != "3" {
+		t.Errorf("TestSubscribeResult_Decode unexpected contract data %s, %v", event.Data, err)
+	}
+
+	unknown := SubscribeResult{Topic: "chain.unknown", Data: "{}"}
+	if _, err := unknown.Decode(); err != ErrUnknownTopic {
+		t.Errorf("TestSubscribeResult_Decode expected ErrUnknownTopic, got %v", err)
+	}
+}
@@ -0,0 +1,547 @@
+// Package signserver is a signing daemon for the accounts that should not be
+// unlocked on public nodes. It serves the sign and unlock calls of the node's
+// admin HTTP API for an allow-list of keystore accounts, so it can be used
+// with rpc.NewRemoteSigner or an rpc.Admin pointed at it.
+package signserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils"
+	"github.com/vigozhang/neb-go/utils/secp256k1"
+)
+
+const (
+	// DefaultUnlockDuration the unlock duration when the request has none,
+	// the same default the node uses.
+	DefaultUnlockDuration = 300 * time.Second
+
+	// DefaultMaxUnlockDuration the longest an account stays unlocked.
+	DefaultMaxUnlockDuration = time.Hour
+)
+
+var (
+	// ErrAddressNotAllowed the address is not on the allow-list of the server
+	ErrAddressNotAllowed = errors.New("address is not allowed")
+
+	// ErrAccountLocked the account is not unlocked and no passphrase was given
+	ErrAccountLocked = errors.New("account is locked")
+
+	// ErrChainIDRequired the options have no chain id for /admin/sign
+	ErrChainIDRequired = errors.New("chain id is required")
+
+	// ErrTokenRequired the options have no token and do not allow serving
+	// without one
+	ErrTokenRequired = errors.New("token is required")
+
+	// ErrNotLoopback ListenAndServe was given an address other hosts can
+	// reach, use ListenAndServeTLS for them
+	ErrNotLoopback = errors.New("plain http is only served on a loopback address")
+)
+
+// Options configure a Server, ChainID and Token are required.
+type Options struct {
+	// ChainID the chain id of the transactions signed by /admin/sign.
+	ChainID uint32
+	// Allowed the addresses the server signs for, keys of other addresses
+	// are refused.
+	Allowed []string
+	// Token the bearer token the requests must carry.
+	Token string
+	// Insecure serves the requests without authentication when Token is
+	// empty, any local process can then sign with the unlocked accounts.
+	Insecure bool
+	// MaxUnlockDuration caps the unlock durations, DefaultMaxUnlockDuration
+	// when 0.
+	MaxUnlockDuration time.Duration
+	// AuditLog receives a json line per request, nothing is logged when nil.
+	AuditLog io.Writer
+}
+
+// Server is an http.Handler serving /v1/admin/sign/hash, /v1/admin/sign,
+// /v1/admin/account/unlock and /v1/admin/account/lock.
+type Server struct {
+	options Options
+	allowed map[string]bool
+
+	mu       sync.Mutex
+	keys     map[string]string
+	unlocked map[string]*unlockedAccount
+
+	auditMu sync.Mutex
+	now     func() time.Time
+}
+
+type unlockedAccount struct {
+	account *account.Account
+	expires time.Time
+}
+
+func NewServer(options Options) (*Server, error) {
+	if options.ChainID == 0 {
+		return nil, ErrChainIDRequired
+	}
+	if options.Token == "" && !options.Insecure {
+		return nil, ErrTokenRequired
+	}
+	if options.MaxUnlockDuration == 0 {
+		options.MaxUnlockDuration = DefaultMaxUnlockDuration
+	}
+
+	server := &Server{
+		options:  options,
+		allowed:  make(map[string]bool),
+		keys:     make(map[string]string),
+		unlocked: make(map[string]*unlockedAccount),
+		now:      time.Now,
+	}
+	for _, address := range options.Allowed {
+		server.allowed[address] = true
+	}
+	return server, nil
+}
+
+// AddKey adds the keystore json of an allowed address, it stays locked until
+// Unlock.
+func (server *Server) AddKey(keyjson string) error {
+	key := account.Key{}
+	err := json.Unmarshal([]byte(keyjson), &key)
+	if err != nil {
+		return err
+	}
+	if !account.IsValidAddress(key.Address) {
+		return errors.New("invalid keystore address " + key.Address)
+	}
+	if !server.allowed[key.Address] {
+		return ErrAddressNotAllowed
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.keys[key.Address] = keyjson
+	return nil
+}
+
+// Unlock decrypts the key of address for duration, capped at
+// MaxUnlockDuration.
+func (server *Server) Unlock(address string, passphrase string, duration time.Duration) error {
+	acc, err := server.decrypt(address, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if duration <= 0 {
+		duration = DefaultUnlockDuration
+	}
+	if duration > server.options.MaxUnlockDuration {
+		duration = server.options.MaxUnlockDuration
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.lock(address)
+	server.unlocked[address] = &unlockedAccount{account: acc, expires: server.now().Add(duration)}
+	return nil
+}
+
+// Lock drops the decrypted key of address.
+func (server *Server) Lock(address string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.lock(address)
+}
+
+func (server *Server) lock(address string) {
+	unlocked, ok := server.unlocked[address]
+	if !ok {
+		return
+	}
+	privateKey := unlocked.account.PrivateKey
+	for i := range privateKey {
+		privateKey[i] = 0
+	}
+	delete(server.unlocked, address)
+}
+
+// LockExpired drops the decrypted keys whose unlock duration has passed.
+// Expired keys are refused anyway, this only removes them from memory.
+func (server *Server) LockExpired() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	for address, unlocked := range server.unlocked {
+		if !server.now().Before(unlocked.expires) {
+			server.lock(address)
+		}
+	}
+}
+
+// account returns the key of address, decrypted with passphrase when it is
+// given and unlocked otherwise. The key is a copy the caller zeroes, lock
+// zeroes the key of the unlocked account while it may be signing.
+func (server *Server) account(address string, passphrase string) (*account.Account, error) {
+	if !server.allowed[address] {
+		return nil, ErrAddressNotAllowed
+	}
+	if passphrase != "" {
+		return server.decrypt(address, passphrase)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	unlocked, ok := server.unlocked[address]
+	if !ok {
+		return nil, ErrAccountLocked
+	}
+	if !server.now().Before(unlocked.expires) {
+		server.lock(address)
+		return nil, ErrAccountLocked
+	}
+	acc := unlocked.account
+	return &account.Account{
+		PrivateKey: append([]byte(nil), acc.PrivateKey...),
+		PublicKey:  acc.PublicKey,
+		Address:    acc.Address,
+	}, nil
+}
+
+func (server *Server) decrypt(address string, passphrase string) (*account.Account, error) {
+	if !server.allowed[address] {
+		return nil, ErrAddressNotAllowed
+	}
+
+	server.mu.Lock()
+	keyjson, ok := server.keys[address]
+	server.mu.Unlock()
+	if !ok {
+		return nil, errors.New("no key for address " + address)
+	}
+
+	return account.NewAccount().FromKey(keyjson, passphrase, false)
+}
+
+// SignHash signs hash with the unlocked key of address.
+func (server *Server) SignHash(address string, hash []byte) ([]byte, error) {
+	if len(hash) != 32 {
+		return nil, errors.New("hash must be 32 bytes")
+	}
+
+	acc, err := server.account(address, "")
+	if err != nil {
+		return nil, err
+	}
+	defer utils.ZeroBytes(acc.PrivateKey)
+	return secp256k1.Sign(hash, acc.GetPrivateKey())
+}
+
+// SignTransaction builds the transaction of req and returns it signed, in
+// the proto encoding SendRawTransaction expects once base64 encoded.
+func (server *Server) SignTransaction(req *rpc.TransactionRequest, passphrase string) ([]byte, error) {
+	if req == nil {
+		return nil, errors.New("transaction is missing")
+	}
+
+	acc, err := server.account(req.From, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.ZeroBytes(acc.PrivateKey)
+
+	opts := transaction.TransactionOptions{
+		ChainID:  server.options.ChainID,
+		From:     acc,
+		To:       req.To,
+		Nonce:    req.Nonce,
+		Contract: &transaction.Contract{Binary: req.Binary},
+	}
+	if req.Contract != nil {
+		opts.Contract.Source = req.Contract.Source
+		opts.Contract.SourceType = req.Contract.SourceType
+		opts.Contract.Function = req.Contract.Function
+		opts.Contract.Args = req.Contract.Args
+	}
+	if !account.IsValidAddress(req.To) {
+		return nil, errors.New("invalid to address " + req.To)
+	}
+	opts.Value, err = parseAmount("value", req.Value, 0)
+	if err != nil {
+		return nil, err
+	}
+	opts.GasPrice, err = parseAmount("gas_price", req.GasPrice, 1000000)
+	if err != nil {
+		return nil, err
+	}
+	opts.GasLimit, err = parseAmount("gas_limit", req.GasLimit, 20000)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := transaction.NewTransaction(opts)
+	err = tx.SignTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return tx.ToProto()
+}
+
+func parseAmount(name string, value string, defaultValue int64) (*big.Int, error) {
+	if value == "" {
+		return big.NewInt(defaultValue), nil
+	}
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, errors.New("invalid " + name + " " + value)
+	}
+	return amount, nil
+}
+
+// auditEntry is a line of the audit log. Passphrases and keys are never
+// logged.
+type auditEntry struct {
+	Time    string `json:"time"`
+	Remote  string `json:"remote"`
+	Path    string `json:"path"`
+	Address string `json:"address,omitempty"`
+	// Hash the signed hash, hex encoded
+	Hash  string `json:"hash,omitempty"`
+	Ok    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	var serve func(r *http.Request, entry *auditEntry) (interface{}, error)
+	switch path {
+	case "/admin/sign/hash":
+		serve = server.serveSignHash
+	case "/admin/sign":
+		serve = server.serveSignTransaction
+	case "/admin/account/unlock":
+		serve = server.serveUnlock
+	case "/admin/account/lock":
+		serve = server.serveLock
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	entry := auditEntry{Path: path}
+	var result interface{}
+	var err error
+	status := http.StatusOK
+	if !server.authorized(r) {
+		status = http.StatusUnauthorized
+		err = errors.New("unauthorized")
+	} else {
+		result, err = serve(r, &entry)
+		if err != nil {
+			status = http.StatusBadRequest
+		}
+	}
+
+	server.audit(r, &entry, err)
+
+	response := struct {
+		Result interface{} `json:"result"`
+		Error  string      `json:"error,omitempty"`
+	}{Result: result}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&response)
+}
+
+func (server *Server) authorized(r *http.Request) bool {
+	if server.options.Token == "" {
+		return server.options.Insecure
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(server.options.Token)) == 1
+}
+
+func (server *Server) serveSignHash(r *http.Request, entry *auditEntry) (interface{}, error) {
+	var req rpc.SignHashRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	entry.Address = req.Address
+
+	if req.Alg != 0 && req.Alg != transaction.SECP256K1 {
+		return nil, transaction.ErrUnsupportedSignatureAlg
+	}
+	hash, err := base64.StdEncoding.DecodeString(req.Hash)
+	if err != nil {
+		return nil, errors.New("invalid hash " + req.Hash)
+	}
+	entry.Hash = hex.EncodeToString(hash)
+
+	sign, err := server.SignHash(req.Address, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.SignHashResult{Data: sign}, nil
+}
+
+func (server *Server) serveSignTransaction(r *http.Request, entry *auditEntry) (interface{}, error) {
+	var req rpc.SignTransactionPassphraseRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Transaction != nil {
+		entry.Address = req.Transaction.From
+	}
+
+	data, err := server.SignTransaction(req.Transaction, req.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := new(transaction.Transaction).FromProto(base64.StdEncoding.EncodeToString(data))
+	if err == nil {
+		entry.Hash = hex.EncodeToString(tx.Hash)
+	}
+	return &rpc.SignTransactionPassphraseResult{Data: data}, nil
+}
+
+func (server *Server) serveUnlock(r *http.Request, entry *auditEntry) (interface{}, error) {
+	var req rpc.UnlockAccountRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	entry.Address = req.Address
+
+	// the node takes the duration in nanoseconds
+	err = server.Unlock(req.Address, req.Passphrase, time.Duration(req.Duration))
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.UnlockAccountResult{Result: true}, nil
+}
+
+func (server *Server) serveLock(r *http.Request, entry *auditEntry) (interface{}, error) {
+	var req rpc.LockAccountRequest
+	err := decodeRequest(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	entry.Address = req.Address
+
+	if !server.allowed[req.Address] {
+		return nil, ErrAddressNotAllowed
+	}
+	server.Lock(req.Address)
+	return &rpc.LockAccountResult{Result: true}, nil
+}
+
+func decodeRequest(r *http.Request, req interface{}) error {
+	err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(req)
+	if err != nil {
+		return errors.New("invalid request: " + err.Error())
+	}
+	return nil
+}
+
+func (server *Server) audit(r *http.Request, entry *auditEntry, err error) {
+	if server.options.AuditLog == nil {
+		return
+	}
+
+	entry.Time = server.now().UTC().Format(time.RFC3339)
+	entry.Remote = r.RemoteAddr
+	if host, _, splitErr := net.SplitHostPort(r.RemoteAddr); splitErr == nil {
+		entry.Remote = host
+	}
+	entry.Ok = err == nil
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	server.auditMu.Lock()
+	defer server.auditMu.Unlock()
+	json.NewEncoder(server.options.AuditLog).Encode(entry)
+}
+
+// ListenAndServe serves the server over plain http on addr until ctx is
+// done, locking the expired accounts every second. addr must be a loopback
+// address, e.g. 127.0.0.1:8686, the tokens and passphrases would go over the
+// network in the clear otherwise.
+func (server *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if !isLoopback(addr) {
+		return ErrNotLoopback
+	}
+	httpServer := &http.Server{Addr: addr, Handler: server}
+	return server.serve(ctx, httpServer, httpServer.ListenAndServe)
+}
+
+// ListenAndServeTLS is ListenAndServe over https with the certificate and key
+// files, on any address.
+func (server *Server) ListenAndServeTLS(ctx context.Context, addr string, certFile string, keyFile string) error {
+	httpServer := &http.Server{Addr: addr, Handler: server}
+	return server.serve(ctx, httpServer, func() error {
+		return httpServer.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+func (server *Server) serve(ctx context.Context, httpServer *http.Server, listenAndServe func() error) error {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				server.LockExpired()
+			case <-ctx.Done():
+				httpServer.Close()
+				return
+			}
+		}
+	}()
+
+	err := listenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// isLoopback tells if the host of addr only accepts local connections, an
+// empty host listens on every interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
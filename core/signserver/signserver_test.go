@@ -0,0 +1,236 @@
+package signserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/core/rpc"
+	"github.com/vigozhang/neb-go/core/transaction"
+	"github.com/vigozhang/neb-go/utils/httprequest"
+	"github.com/vigozhang/neb-go/utils/secp256k1"
+)
+
+func newTestServer(t *testing.T) (*Server, *account.Account, *bytes.Buffer) {
+	acc := account.NewAccount()
+	keyjson, err := acc.ToKeyString("passphrase", &account.KeyOptions{N: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audit := new(bytes.Buffer)
+	server, err := NewServer(Options{
+		ChainID:  1001,
+		Allowed:  []string{acc.GetAddressString()},
+		Token:    "secret",
+		AuditLog: audit,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.AddKey(keyjson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, acc, audit
+}
+
+func TestServer_SignHash(t *testing.T) {
+	server, acc, audit := newTestServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	request := httprequest.NewHttpRequest(httpServer.URL, httprequest.APIVersion1)
+	request.SetBearerToken("secret")
+	admin := rpc.NewNeb(request).Admin
+
+	from, _ := account.FromAddress(acc.GetAddressString())
+	tx := transaction.NewTransaction(transaction.TransactionOptions{
+		ChainID:  1001,
+		From:     from,
+		To:       "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17",
+		Value:    big.NewInt(10),
+		Nonce:    1,
+		GasPrice: big.NewInt(1000000),
+		GasLimit: big.NewInt(20000),
+		Contract: &transaction.Contract{},
+	})
+	signer := rpc.NewRemoteSigner(request, acc.GetAddressString())
+
+	err := tx.SignWith(signer)
+	if err == nil {
+		t.Error("TestServer_SignHash signed with a locked account")
+	}
+
+	_, err = admin.UnlockAccount(rpc.UnlockAccountRequest{
+		Address:    acc.GetAddressString(),
+		Passphrase: "passphrase",
+		Duration:   uint64(time.Minute),
+	})
+	if err != nil {
+		t.Fatal("TestServer_SignHash unlock failed:", err)
+	}
+
+	err = tx.SignWith(signer)
+	if err != nil {
+		t.Fatal("TestServer_SignHash failed:", err)
+	}
+
+	// the unlock expires
+	server.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := server.SignHash(acc.GetAddressString(), tx.Hash); err != ErrAccountLocked {
+		t.Error("TestServer_SignHash signed after the unlock expired:", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("TestServer_SignHash audit log has %d lines: %s", len(lines), audit.String())
+	}
+	var entry auditEntry
+	json.Unmarshal([]byte(lines[2]), &entry)
+	if !entry.Ok || entry.Path != "/admin/sign/hash" || entry.Address != acc.GetAddressString() || entry.Hash == "" {
+		t.Errorf("TestServer_SignHash audit entry %+v", entry)
+	}
+	if strings.Contains(audit.String(), "passphrase") {
+		t.Error("TestServer_SignHash logged the passphrase")
+	}
+}
+
+func TestServer_SignTransaction(t *testing.T) {
+	server, acc, _ := newTestServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	request := httprequest.NewHttpRequest(httpServer.URL, httprequest.APIVersion1)
+	admin := rpc.NewNeb(request).Admin
+
+	req := rpc.SignTransactionPassphraseRequest{
+		Transaction: &rpc.TransactionRequest{
+			From:  acc.GetAddressString(),
+			To:    "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17",
+			Value: "10",
+			Nonce: 3,
+		},
+		Passphrase: "passphrase",
+	}
+	_, err := admin.SignTransactionWithPassphrase(req)
+	if err == nil {
+		t.Error("TestServer_SignTransaction accepted a request without token")
+	}
+
+	request.SetBearerToken("secret")
+	resp, err := admin.SignTransactionWithPassphrase(req)
+	if err != nil {
+		t.Fatal("TestServer_SignTransaction failed:", err)
+	}
+
+	tx, err := new(transaction.Transaction).FromProto(base64.StdEncoding.EncodeToString(resp.Result.Data))
+	if err != nil {
+		t.Fatal("TestServer_SignTransaction failed:", err)
+	}
+	if err := tx.Verify(); err != nil || tx.ChainID != 1001 || tx.Nonce != 3 {
+		t.Errorf("TestServer_SignTransaction signed %+v: %v", tx, err)
+	}
+
+	req.Transaction.From = account.NewAccount().GetAddressString()
+	_, err = admin.SignTransactionWithPassphrase(req)
+	if err == nil || !strings.Contains(err.Error(), ErrAddressNotAllowed.Error()) {
+		t.Error("TestServer_SignTransaction signed for an address not allowed:", err)
+	}
+}
+
+func TestServer_LockWhileSigning(t *testing.T) {
+	server, acc, _ := newTestServer(t)
+	if err := server.Unlock(acc.GetAddressString(), "passphrase", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := make([]byte, 32)
+	signer, err := server.account(acc.GetAddressString(), "")
+	if err != nil {
+		t.Fatal("TestServer_LockWhileSigning failed:", err)
+	}
+	server.Lock(acc.GetAddressString())
+	if !bytes.Equal(signer.PrivateKey, acc.PrivateKey) {
+		t.Fatal("TestServer_LockWhileSigning lock zeroed the key being used")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			server.Unlock(acc.GetAddressString(), "passphrase", time.Minute)
+			server.Lock(acc.GetAddressString())
+		}()
+		go func() {
+			defer wg.Done()
+			signature, err := server.SignHash(acc.GetAddressString(), hash)
+			if err == nil && !bytes.Equal(signature, mustSign(t, hash, acc)) {
+				t.Error("TestServer_LockWhileSigning signed with another key")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func mustSign(t *testing.T, hash []byte, acc *account.Account) []byte {
+	signature, err := secp256k1.Sign(hash, acc.GetPrivateKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signature
+}
+
+func TestNewServer_ChainID(t *testing.T) {
+	if _, err := NewServer(Options{}); err != ErrChainIDRequired {
+		t.Errorf("TestNewServer_ChainID expected ErrChainIDRequired, got %v", err)
+	}
+}
+
+func TestNewServer_Token(t *testing.T) {
+	if _, err := NewServer(Options{ChainID: 1001}); err != ErrTokenRequired {
+		t.Errorf("TestNewServer_Token expected ErrTokenRequired, got %v", err)
+	}
+
+	server, err := NewServer(Options{ChainID: 1001, Insecure: true})
+	if err != nil {
+		t.Fatal("TestNewServer_Token failed:", err)
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	admin := rpc.NewNeb(httprequest.NewHttpRequest(httpServer.URL, httprequest.APIVersion1)).Admin
+	_, err = admin.LockAccount(rpc.LockAccountRequest{Address: "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17"})
+	if err == nil || !strings.Contains(err.Error(), ErrAddressNotAllowed.Error()) {
+		t.Errorf("TestNewServer_Token insecure server refused the request: %v", err)
+	}
+}
+
+func TestServer_ListenAndServe(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, addr := range []string{":8686", "0.0.0.0:8686", "192.168.1.2:8686", "[::]:8686", "8686"} {
+		if err := server.ListenAndServe(ctx, addr); err != ErrNotLoopback {
+			t.Errorf("TestServer_ListenAndServe %s expected ErrNotLoopback, got %v", addr, err)
+		}
+	}
+	for _, addr := range []string{"127.0.0.1:0", "[::1]:0", "localhost:0"} {
+		if err := server.ListenAndServe(ctx, addr); err == ErrNotLoopback {
+			t.Errorf("TestServer_ListenAndServe refused %s", addr)
+		}
+	}
+	if err := server.ListenAndServeTLS(ctx, "0.0.0.0:0", "missing.pem", "missing.key"); err == nil || err == ErrNotLoopback {
+		t.Errorf("TestServer_ListenAndServe tls without certificate: %v", err)
+	}
+}
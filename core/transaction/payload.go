@@ -0,0 +1,194 @@
+package transaction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/vigozhang/neb-go/utils"
+)
+
+const (
+	TxPayloadProtocolType = "protocol"
+	TxPayloadDipType      = "dip"
+
+	SourceTypeJavaScript = "js"
+	SourceTypeTypeScript = "ts"
+)
+
+var (
+	// ErrUnknownPayloadType no payload is registered for the payload type
+	ErrUnknownPayloadType = errors.New("unknown transaction payload type")
+
+	// ErrInvalidPayload the payload misses required fields or can not be decoded
+	ErrInvalidPayload = errors.New("invalid transaction payload")
+)
+
+// functionNameRegexp the names of the contract functions that can be called,
+// the same check the chain applies.
+var functionNameRegexp = regexp.MustCompile("^[a-zA-Z$][A-Za-z0-9_$]*$")
+
+// Payload is the typed content of the data of a transaction.
+type Payload interface {
+	// Type the payload type stored in TxPayload.Type
+	Type() string
+	// Validate checks the required fields of the payload
+	Validate() error
+}
+
+var (
+	payloadsMu sync.RWMutex
+	payloads   = map[string]func() Payload{
+		TxPayloadBinaryType:   func() Payload { return new(TransactionBinaryPayload) },
+		TxPayloadDeployType:   func() Payload { return new(TransactionDeployPayload) },
+		TxPayloadCallType:     func() Payload { return new(TransactionCallPayload) },
+		TxPayloadProtocolType: func() Payload { return new(TransactionProtocolPayload) },
+		TxPayloadDipType:      func() Payload { return new(TransactionDipPayload) },
+	}
+)
+
+// RegisterPayload registers the payload of a payload type, newPayload returns
+// a pointer to the struct the json payload is decoded into. It replaces the
+// payload registered for the type before.
+func RegisterPayload(payloadType string, newPayload func() Payload) {
+	payloadsMu.Lock()
+	defer payloadsMu.Unlock()
+
+	payloads[payloadType] = newPayload
+}
+
+// EncodePayload validates payload and encodes it as transaction data.
+func EncodePayload(payload Payload) (*TxPayload, error) {
+	err := payload.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return encodePayload(payload), nil
+}
+
+// Decode returns the registered payload struct of the payload type with the
+// payload decoded into it and validated.
+func (txPayload *TxPayload) Decode() (Payload, error) {
+	payloadsMu.RLock()
+	newPayload, ok := payloads[txPayload.Type]
+	payloadsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPayloadType, txPayload.Type)
+	}
+
+	payload := newPayload()
+	if len(txPayload.Payload) > 0 {
+		err := json.Unmarshal(txPayload.Payload, payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s payload: %s", ErrInvalidPayload, txPayload.Type, err)
+		}
+	}
+
+	err := payload.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// DecodePayload returns the typed payload of the transaction, e.g.
+// *TransactionCallPayload for a call transaction.
+func (tx *Transaction) DecodePayload() (Payload, error) {
+	return tx.Data.Decode()
+}
+
+func (payload *TransactionBinaryPayload) Type() string {
+	return TxPayloadBinaryType
+}
+
+func (payload *TransactionBinaryPayload) Validate() error {
+	return nil
+}
+
+func (payload *TransactionDeployPayload) Type() string {
+	return TxPayloadDeployType
+}
+
+func (payload *TransactionDeployPayload) Validate() error {
+	if payload.SourceType != SourceTypeJavaScript && payload.SourceType != SourceTypeTypeScript {
+		return fmt.Errorf("%w: deploy source type %q is not %s or %s", ErrInvalidPayload,
+			payload.SourceType, SourceTypeJavaScript, SourceTypeTypeScript)
+	}
+	if len(payload.Source) == 0 {
+		return fmt.Errorf("%w: deploy source is empty", ErrInvalidPayload)
+	}
+	return validateArgs(TxPayloadDeployType, payload.Args)
+}
+
+func (payload *TransactionCallPayload) Type() string {
+	return TxPayloadCallType
+}
+
+func (payload *TransactionCallPayload) Validate() error {
+	if !functionNameRegexp.MatchString(payload.Function) || payload.Function == "init" {
+		return fmt.Errorf("%w: call function %q can not be called", ErrInvalidPayload, payload.Function)
+	}
+	return validateArgs(TxPayloadCallType, payload.Args)
+}
+
+// TransactionProtocolPayload is the payload of the transactions that update
+// the chain protocol, Data is the compiled protocol code.
+type TransactionProtocolPayload struct {
+	Data []byte
+}
+
+func (payload *TransactionProtocolPayload) Type() string {
+	return TxPayloadProtocolType
+}
+
+func (payload *TransactionProtocolPayload) Validate() error {
+	if len(payload.Data) == 0 {
+		return fmt.Errorf("%w: protocol data is empty", ErrInvalidPayload)
+	}
+	return nil
+}
+
+// TransactionDipPayload is the payload of the DIP (developer incentive
+// protocol) reward transactions for the blocks StartHeight to EndHeight.
+type TransactionDipPayload struct {
+	StartHeight uint64
+	EndHeight   uint64
+	Version     uint64
+}
+
+func (payload *TransactionDipPayload) Type() string {
+	return TxPayloadDipType
+}
+
+func (payload *TransactionDipPayload) Validate() error {
+	if payload.StartHeight == 0 || payload.EndHeight < payload.StartHeight {
+		return fmt.Errorf("%w: dip heights %d to %d", ErrInvalidPayload, payload.StartHeight, payload.EndHeight)
+	}
+	return nil
+}
+
+// validateArgs checks the contract args are a json array, the chain passes
+// them to the contract as the arguments of the function.
+func validateArgs(payloadType string, args string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	var argsArray []interface{}
+	err := json.Unmarshal([]byte(args), &argsArray)
+	if err != nil {
+		return fmt.Errorf("%w: %s args %q are not a json array", ErrInvalidPayload, payloadType, args)
+	}
+	return nil
+}
+
+// encodePayload encodes payload without validating it, like parseContract.
+func encodePayload(payload Payload) *TxPayload {
+	txPayload := TxPayload{Type: payload.Type()}
+	if binary, ok := payload.(*TransactionBinaryPayload); !ok || len(binary.Data) > 0 {
+		txPayload.Payload = utils.EncodeToJsonBytes(payload)
+	}
+	return &txPayload
+}
@@ -0,0 +1,91 @@
+package transaction
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTxPayload_Decode(t *testing.T) {
+	payloads := []Payload{
+		&TransactionBinaryPayload{},
+		&TransactionBinaryPayload{Data: []byte("binary data")},
+		&TransactionDeployPayload{SourceType: "js", Source: "module.exports = {}", Args: `["a"]`},
+		&TransactionCallPayload{Function: "save", Args: `[0]`},
+		&TransactionProtocolPayload{Data: []byte("protocol")},
+		&TransactionDipPayload{StartHeight: 100, EndHeight: 200, Version: 1},
+	}
+
+	for _, payload := range payloads {
+		txPayload, err := EncodePayload(payload)
+		if err != nil {
+			t.Fatalf("TestTxPayload_Decode %s failed: %s", payload.Type(), err)
+		}
+		if txPayload.Type != payload.Type() {
+			t.Errorf("TestTxPayload_Decode type %s != %s", txPayload.Type, payload.Type())
+		}
+
+		decoded, err := txPayload.Decode()
+		if err != nil {
+			t.Fatalf("TestTxPayload_Decode %s failed: %s", payload.Type(), err)
+		}
+		if !reflect.DeepEqual(decoded, payload) {
+			t.Errorf("TestTxPayload_Decode %s: %+v != %+v", payload.Type(), decoded, payload)
+		}
+	}
+}
+
+func TestTxPayload_Validate(t *testing.T) {
+	payloads := []Payload{
+		&TransactionDeployPayload{SourceType: "py", Source: "source"},
+		&TransactionDeployPayload{SourceType: "js"},
+		&TransactionCallPayload{Function: "init"},
+		&TransactionCallPayload{Function: "1save"},
+		&TransactionCallPayload{Function: "save", Args: `{"a":1}`},
+		&TransactionProtocolPayload{},
+		&TransactionDipPayload{StartHeight: 200, EndHeight: 100},
+	}
+
+	for _, payload := range payloads {
+		_, err := EncodePayload(payload)
+		if !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("TestTxPayload_Validate accepted %s payload %+v", payload.Type(), payload)
+		}
+	}
+
+	_, err := (&TxPayload{Type: TxPayloadCallType, Payload: []byte("not json")}).Decode()
+	if !errors.Is(err, ErrInvalidPayload) {
+		t.Error("TestTxPayload_Validate decoded an invalid payload:", err)
+	}
+	_, err = (&TxPayload{Type: "unknown"}).Decode()
+	if !errors.Is(err, ErrUnknownPayloadType) {
+		t.Error("TestTxPayload_Validate decoded an unknown payload type:", err)
+	}
+}
+
+func TestTransaction_DecodePayload(t *testing.T) {
+	tx := newTransaction()
+
+	payload, err := tx.DecodePayload()
+	if err != nil {
+		t.Fatal("TestTransaction_DecodePayload failed:", err)
+	}
+	call, ok := payload.(*TransactionCallPayload)
+	if !ok || call.Function != "save" || call.Args != "[0]" {
+		t.Errorf("TestTransaction_DecodePayload failed: %+v", payload)
+	}
+
+	opts := TransactionOptions{
+		From:     newAccount(),
+		To:       "n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17",
+		Value:    tx.Value,
+		GasPrice: tx.GasPrice,
+		GasLimit: tx.GasLimit,
+		Payload:  &TransactionDipPayload{StartHeight: 1, EndHeight: 10},
+	}
+	tx = NewTransaction(opts)
+	payload, err = tx.DecodePayload()
+	if err != nil || !reflect.DeepEqual(payload, opts.Payload) {
+		t.Errorf("TestTransaction_DecodePayload dip failed: %+v %v", payload, err)
+	}
+}
@@ -0,0 +1,65 @@
+package transaction
+
+import (
+	"errors"
+
+	"github.com/vigozhang/neb-go/core/account"
+	"github.com/vigozhang/neb-go/utils/secp256k1"
+)
+
+// Signer signs transaction hashes with the secp256k1 key of an account,
+// wherever the key is kept.
+type Signer interface {
+	// Address the address of the signing account
+	Address() string
+	// SignHash returns the secp256k1 signature of hash
+	SignHash(hash []byte) ([]byte, error)
+}
+
+// AccountSigner signs with the private key of an in-memory account, it is the
+// signer of SignTransaction.
+type AccountSigner struct {
+	Account *account.Account
+}
+
+func NewAccountSigner(acc *account.Account) *AccountSigner {
+	return &AccountSigner{Account: acc}
+}
+
+func (signer *AccountSigner) Address() string {
+	return signer.Account.GetAddressString()
+}
+
+func (signer *AccountSigner) SignHash(hash []byte) ([]byte, error) {
+	if signer.Account.GetPrivateKey() == nil {
+		return nil, errors.New("transaction from address's private key is invalid")
+	}
+	return secp256k1.Sign(hash, signer.Account.GetPrivateKey())
+}
+
+// SignWith signs the transaction with signer, which must sign for the from
+// address. The signature is verified, so a misbehaving remote signer can not
+// produce an invalid transaction.
+func (tx *Transaction) SignWith(signer Signer) error {
+	if signer.Address() != tx.From.GetAddressString() {
+		return errors.New("signer " + signer.Address() + " does not match from address " + tx.From.GetAddressString())
+	}
+
+	hashValue := tx.HashTransaction()
+	sign, err := signer.SignHash(hashValue)
+	if err != nil {
+		return err
+	}
+
+	tx.Hash = hashValue
+	tx.Alg = SECP256K1
+	tx.Sign = sign
+	err = tx.Verify()
+	if err != nil {
+		tx.Hash = nil
+		tx.Alg = 0
+		tx.Sign = nil
+		return err
+	}
+	return nil
+}
@@ -1,12 +1,15 @@
 package transaction
 
 import (
+	"bytes"
+	"fmt"
 	"math/big"
 	"time"
 	"errors"
 	"encoding/hex"
 	"encoding/json"
 	"encoding/base64"
+	"unicode/utf8"
 
 	"github.com/vigozhang/neb-go/core/account"
 	"github.com/vigozhang/neb-go/utils"
@@ -26,6 +29,23 @@ const (
 	SECP256K1 = 1
 )
 
+var (
+	// ErrTransactionNotSigned the transaction has no hash or signature
+	ErrTransactionNotSigned = errors.New("transaction is not signed")
+
+	// ErrInvalidTransactionHash the hash does not match the transaction content
+	ErrInvalidTransactionHash = errors.New("invalid transaction hash")
+
+	// ErrUnsupportedSignatureAlg the signature algorithm is not secp256k1
+	ErrUnsupportedSignatureAlg = errors.New("unsupported signature algorithm")
+
+	// ErrInvalidTransactionSignature no public key can be recovered from the signature
+	ErrInvalidTransactionSignature = errors.New("invalid transaction signature")
+
+	// ErrInvalidTransactionSigner the signature was made by another account than from
+	ErrInvalidTransactionSigner = errors.New("transaction signer does not match from address")
+)
+
 type TransactionOptions struct {
 	ChainID  uint32
 	From     *account.Account
@@ -35,6 +55,9 @@ type TransactionOptions struct {
 	GasPrice *big.Int
 	GasLimit *big.Int
 	Contract *Contract
+	// Payload the typed payload of the transaction, e.g. a
+	// *TransactionDipPayload. Contract is ignored when it is set.
+	Payload Payload
 }
 
 type Transaction struct {
@@ -91,7 +114,11 @@ func NewTransaction(opts TransactionOptions) *Transaction {
 	transaction.Timestamp = time.Now().Unix()
 	transaction.GasPrice = opts.GasPrice
 	transaction.GasLimit = opts.GasLimit
-	transaction.Data = parseContract(opts.Contract)
+	if opts.Payload != nil {
+		transaction.Data = encodePayload(opts.Payload)
+	} else {
+		transaction.Data = parseContract(opts.Contract)
+	}
 
 	if transaction.GasPrice.Cmp(big.NewInt(0)) == -1 {
 		transaction.GasPrice = big.NewInt(1000000)
@@ -128,12 +155,39 @@ func (tx *Transaction) SignTransaction() error {
 		return errors.New("transaction from address's private key is invalid")
 	}
 
-	tx.Hash = tx.HashTransaction()
-	tx.Alg = SECP256K1
-	var err error
-	tx.Sign, err = secp256k1.Sign(tx.Hash, tx.From.GetPrivateKey())
+	return tx.SignWith(NewAccountSigner(tx.From))
+}
+
+// Verify checks a signed transaction, typically one decoded by FromProto:
+// the hash must match the transaction content and the signature must have
+// been made by the from account.
+func (tx *Transaction) Verify() error {
+	if tx.Hash == nil || tx.Sign == nil {
+		return ErrTransactionNotSigned
+	}
+
+	hashValue := tx.HashTransaction()
+	if !bytes.Equal(hashValue, tx.Hash) {
+		return fmt.Errorf("%w: computed %x, transaction has %x", ErrInvalidTransactionHash, hashValue, tx.Hash)
+	}
+
+	if tx.Alg != SECP256K1 {
+		return fmt.Errorf("%w: %d", ErrUnsupportedSignatureAlg, tx.Alg)
+	}
+
+	publicKey, err := secp256k1.RecoverECDSAPublicKey(tx.Hash, tx.Sign)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %s", ErrInvalidTransactionSignature, err)
+	}
+
+	signer, err := account.FromPublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTransactionSignature, err)
+	}
+
+	if !bytes.Equal(signer.GetAddress(), tx.From.GetAddress()) {
+		return fmt.Errorf("%w: signed by %s, from is %s", ErrInvalidTransactionSigner,
+			signer.GetAddressString(), tx.From.GetAddressString())
 	}
 	return nil
 }
@@ -143,34 +197,192 @@ func (tx *Transaction) ToString() (string, error) {
 		return "", errors.New("you should sign transaction before this operation")
 	}
 
-	var payloadMap map[string]interface{}
-	if tx.Data.Payload != nil {
-		json.Unmarshal(tx.Data.Payload, &payloadMap)
+	txBytes, err := tx.MarshalJSON()
+	if err != nil {
+		return "", err
 	}
+	return string(txBytes), nil
+}
 
-	mapData := make(map[string]interface{})
-	mapData["payloadType"] = tx.Data.Type
-	mapData["payload"] = payloadMap
+// FromString decodes a transaction encoded by ToString or json.Marshal.
+func (tx *Transaction) FromString(txString string) (*Transaction, error) {
+	err := tx.UnmarshalJSON([]byte(txString))
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+type txJSON struct {
+	ChainID   uint32     `json:"chainID"`
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Value     string     `json:"value"`
+	Nonce     uint64     `json:"nonce"`
+	Timestamp int64      `json:"timestamp"`
+	Data      txDataJSON `json:"data"`
+	GasPrice  string     `json:"gasPrice"`
+	GasLimit  string     `json:"gasLimit"`
+	Hash      string     `json:"hash"`
+	Alg       uint32     `json:"alg"`
+	Sign      string     `json:"sign"`
+}
+
+type txDataJSON struct {
+	PayloadType string `json:"payloadType"`
+	// Payload the payload object, or the payload bytes as a json string when
+	// the object would not give them back, the hash depends on them.
+	Payload json.RawMessage `json:"payload"`
+}
 
-	mapTx := make(map[string]interface{})
-	mapTx["chainID"] = tx.ChainID
-	mapTx["from"] = tx.From.GetAddressString()
-	mapTx["to"] = tx.To.GetAddressString()
-	mapTx["value"] = tx.Value.String()
-	mapTx["nonce"] = tx.Nonce
-	mapTx["timestamp"] = tx.Timestamp
-	mapTx["data"] = mapData
-	mapTx["gasPrice"] = tx.GasPrice.String()
-	mapTx["gasLimit"] = tx.GasLimit.String()
-	mapTx["hash"] = hex.EncodeToString(tx.Hash)
-	mapTx["alg"] = tx.Alg
-	mapTx["sign"] = hex.EncodeToString(tx.Sign)
+// MarshalJSON encodes the transaction like ToString, unsigned transactions
+// have an empty hash and sign.
+func (tx *Transaction) MarshalJSON() ([]byte, error) {
+	switch {
+	case tx.Data == nil:
+		return nil, errors.New("transaction data is missing")
+	case tx.From == nil || tx.To == nil:
+		return nil, errors.New("transaction from or to is missing")
+	case tx.Value == nil || tx.GasPrice == nil || tx.GasLimit == nil:
+		return nil, errors.New("transaction value, gas price or gas limit is missing")
+	}
 
-	txBytes, err := json.Marshal(&mapTx)
+	payload, err := marshalPayload(tx.Data.Payload)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(txBytes), nil
+
+	txData := txJSON{
+		ChainID:   tx.ChainID,
+		From:      tx.From.GetAddressString(),
+		To:        tx.To.GetAddressString(),
+		Value:     tx.Value.String(),
+		Nonce:     tx.Nonce,
+		Timestamp: tx.Timestamp,
+		Data:      txDataJSON{PayloadType: tx.Data.Type, Payload: payload},
+		GasPrice:  tx.GasPrice.String(),
+		GasLimit:  tx.GasLimit.String(),
+		Hash:      hex.EncodeToString(tx.Hash),
+		Alg:       tx.Alg,
+		Sign:      hex.EncodeToString(tx.Sign),
+	}
+	return json.Marshal(&txData)
+}
+
+func (tx *Transaction) UnmarshalJSON(data []byte) error {
+	var txData txJSON
+	err := json.Unmarshal(data, &txData)
+	if err != nil {
+		return err
+	}
+
+	from, err := account.FromAddress(txData.From)
+	if err != nil {
+		return err
+	}
+	to, err := account.FromAddress(txData.To)
+	if err != nil {
+		return err
+	}
+
+	value, err := parseAmount("value", txData.Value)
+	if err != nil {
+		return err
+	}
+	gasPrice, err := parseAmount("gasPrice", txData.GasPrice)
+	if err != nil {
+		return err
+	}
+	gasLimit, err := parseAmount("gasLimit", txData.GasLimit)
+	if err != nil {
+		return err
+	}
+
+	if txData.Data.PayloadType == "" {
+		return errors.New("transaction payload type is missing")
+	}
+	payload, err := unmarshalPayload(txData.Data.Payload)
+	if err != nil {
+		return err
+	}
+
+	hashValue, err := hex.DecodeString(txData.Hash)
+	if err != nil {
+		return errors.New("invalid transaction hash " + txData.Hash)
+	}
+	sign, err := hex.DecodeString(txData.Sign)
+	if err != nil {
+		return errors.New("invalid transaction sign " + txData.Sign)
+	}
+
+	tx.ChainID = txData.ChainID
+	tx.From = from
+	tx.To = to
+	tx.Value = value
+	tx.Nonce = txData.Nonce
+	tx.Timestamp = txData.Timestamp
+	tx.Data = &TxPayload{Type: txData.Data.PayloadType, Payload: payload}
+	tx.GasPrice = gasPrice
+	tx.GasLimit = gasLimit
+	tx.Hash = nil
+	if len(hashValue) > 0 {
+		tx.Hash = hashValue
+	}
+	tx.Alg = txData.Alg
+	tx.Sign = nil
+	if len(sign) > 0 {
+		tx.Sign = sign
+	}
+	return nil
+}
+
+// marshalPayload encodes the payload as an object when compacting the object
+// gives the payload bytes back, e.g. after the transaction json is indented,
+// and as a json string otherwise, so the hash still matches once decoded.
+func marshalPayload(payload []byte) (json.RawMessage, error) {
+	if payload == nil {
+		return json.RawMessage("null"), nil
+	}
+	if !utf8.Valid(payload) {
+		return nil, errors.New("transaction payload is not valid utf-8")
+	}
+	if payload[0] == '{' && json.Valid(payload) {
+		// json.Marshal compacts and escapes a raw message as it does the
+		// transaction around it
+		object, err := json.Marshal(json.RawMessage(payload))
+		if err == nil && bytes.Equal(object, payload) {
+			return payload, nil
+		}
+	}
+	return json.Marshal(string(payload))
+}
+
+// unmarshalPayload decodes a payload object or string. Objects are compacted
+// back to the payload bytes.
+func unmarshalPayload(data json.RawMessage) ([]byte, error) {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	if data[0] == '"' {
+		var payload string
+		err := json.Unmarshal(data, &payload)
+		if err != nil {
+			return nil, err
+		}
+		if payload == "" {
+			return nil, nil
+		}
+		return []byte(payload), nil
+	}
+	if data[0] != '{' {
+		return nil, errors.New("invalid transaction payload " + string(data))
+	}
+	var payload bytes.Buffer
+	err := json.Compact(&payload, data)
+	if err != nil {
+		return nil, err
+	}
+	return payload.Bytes(), nil
 }
 
 func (tx *Transaction) ToProto() ([]byte, error) {
@@ -178,6 +390,14 @@ func (tx *Transaction) ToProto() ([]byte, error) {
 		return nil, errors.New("you should sign transaction before this operation")
 	}
 
+	txBytes, err := proto.Marshal(tx.protoMessage())
+	if err != nil {
+		return nil, err
+	}
+	return txBytes, nil
+}
+
+func (tx *Transaction) protoMessage() *corepb.Transaction {
 	data := corepb.Data{
 		Type:    tx.Data.Type,
 		Payload: tx.Data.Payload,
@@ -197,12 +417,7 @@ func (tx *Transaction) ToProto() ([]byte, error) {
 		Alg:       tx.Alg,
 		Sign:      tx.Sign,
 	}
-
-	txBytes, err := proto.Marshal(&txData)
-	if err != nil {
-		return nil, err
-	}
-	return txBytes, nil
+	return &txData
 }
 
 func (tx *Transaction) ToProtoString() (string, error) {
@@ -225,6 +440,15 @@ func (tx *Transaction) FromProto(protoString string) (*Transaction, error) {
 		return nil, err
 	}
 
+	return tx.fromProtoMessage(&txProto)
+}
+
+func (tx *Transaction) fromProtoMessage(txProto *corepb.Transaction) (*Transaction, error) {
+	if txProto.Data == nil {
+		return nil, errors.New("transaction data is missing")
+	}
+
+	var err error
 	tx.Hash = txProto.Hash
 	tx.From, err = account.FromAddress(base58.Encode(txProto.From))
 	if err != nil {
@@ -2,9 +2,15 @@ package transaction
 
 import (
 	"testing"
+	"bytes"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"github.com/vigozhang/neb-go/core/account"
 	"math/big"
+	"strings"
+
+	"github.com/vigozhang/neb-go/utils/secp256k1"
 )
 
 func TestTransaction_HashTransaction(t *testing.T) {
@@ -72,6 +78,54 @@ func TestTransaction_FromProto(t *testing.T) {
 
 }
 
+func TestTransaction_Verify(t *testing.T) {
+	tx := newTransaction()
+	tx.SignTransaction()
+
+	err := tx.Verify()
+	if err != nil {
+		t.Error("TestTransaction_Verify failed:", err)
+	}
+
+	tampered := *tx
+	tampered.Value = big.NewInt(11)
+	err = tampered.Verify()
+	if !errors.Is(err, ErrInvalidTransactionHash) {
+		t.Error("TestTransaction_Verify expected an invalid hash, got", err)
+	}
+
+	unsigned := newTransaction()
+	err = unsigned.Verify()
+	if err != ErrTransactionNotSigned {
+		t.Error("TestTransaction_Verify expected an unsigned transaction, got", err)
+	}
+}
+
+func TestTransaction_VerifySigner(t *testing.T) {
+	tx := newTransaction()
+	tx.SignTransaction()
+
+	// same content signed by another key
+	tx.Sign, _ = secp256k1.Sign(tx.Hash, account.NewAccount().GetPrivateKey())
+	err := tx.Verify()
+	if !errors.Is(err, ErrInvalidTransactionSigner) {
+		t.Error("TestTransaction_VerifySigner expected an invalid signer, got", err)
+	}
+}
+
+func TestTransaction_VerifyFromProto(t *testing.T) {
+	protoStr := "CiAuTz4bhJj54X/doi6EXmIf3f1H1oOil7r2U/nOTGNX9hIaGVdDhNxJ4+OzYNWr2if95MASrtEj0U0nmgYaGhlXf89CeLWgHFjKu9/6tn4KNbelsMDAIIi2IhAAAAAAAAAAAAAAAAAAAAAKKAww3d7p2AU6KAoEY2FsbBIgeyJGdW5jdGlvbiI6InNhdmUiLCJBcmdzIjoiWzBdIn1AAUoQAAAAAAAAAAAAAAAAAA9CQFIQAAAAAAAAAAAAAAAAAB6EgFgBYkGkVEUhcFggQZVmN+2C5c6UPOgqF/pFWTk/g3HKqDBjpRq3Gz/Rtp7znhRTQCYZ2bp6FzX5OaVv90LTuIW3kwaIAA=="
+
+	tx, err := new(Transaction).FromProto(protoStr)
+	if err != nil {
+		t.Fatal("TestTransaction_VerifyFromProto failed:", err)
+	}
+	err = tx.Verify()
+	if err != nil {
+		t.Error("TestTransaction_VerifyFromProto failed:", err)
+	}
+}
+
 func newAccount() *account.Account {
 	acc := account.NewAccount()
 
@@ -99,3 +153,211 @@ func newTransaction() *Transaction {
 
 	return NewTransaction(txopts)
 }
+
+func TestTransaction_UnsignedRoundTrip(t *testing.T) {
+	acc := newAccount()
+	keyjson, err := acc.ToKeyString("passphrase", &account.KeyOptions{N: 1024})
+	if err != nil {
+		t.Fatal("TestTransaction_UnsignedRoundTrip failed:", err)
+	}
+
+	tx := newTransaction()
+	// the online machine only knows the from address
+	tx.From, _ = account.FromAddress(acc.GetAddressString())
+
+	jsonBytes, err := tx.ToUnsignedJSON()
+	if err != nil {
+		t.Fatal("TestTransaction_UnsignedRoundTrip failed:", err)
+	}
+	protoStr, err := tx.ToUnsignedProtoString()
+	if err != nil {
+		t.Fatal("TestTransaction_UnsignedRoundTrip failed:", err)
+	}
+
+	for _, input := range []string{string(jsonBytes), protoStr} {
+		offline, err := ParseUnsigned(input)
+		if err != nil {
+			t.Fatal("TestTransaction_UnsignedRoundTrip failed:", err)
+		}
+		t.Log(offline.Summary())
+
+		err = offline.SignWithKey(keyjson, "passphrase")
+		if err != nil {
+			t.Fatal("TestTransaction_UnsignedRoundTrip failed:", err)
+		}
+		if err := offline.Verify(); err != nil {
+			t.Error("TestTransaction_UnsignedRoundTrip signed an invalid transaction:", err)
+		}
+		if !bytes.Equal(offline.Hash, tx.HashTransaction()) {
+			t.Error("TestTransaction_UnsignedRoundTrip changed the transaction content")
+		}
+	}
+
+	other, _ := account.NewAccount().ToKeyString("passphrase", &account.KeyOptions{N: 1024})
+	offline, _ := ParseUnsigned(string(jsonBytes))
+	if offline.SignWithKey(other, "passphrase") == nil {
+		t.Error("TestTransaction_UnsignedRoundTrip should refuse a keystore of another account")
+	}
+
+	// keystores are validated strictly
+	var fields map[string]interface{}
+	json.Unmarshal([]byte(keyjson), &fields)
+	fields["id"] = "not a uuid"
+	invalid, _ := json.Marshal(fields)
+	var keyErr *account.KeyError
+	if err := offline.SignWithKey(string(invalid), "passphrase"); !errors.As(err, &keyErr) {
+		t.Error("TestTransaction_UnsignedRoundTrip should refuse an invalid keystore:", err)
+	}
+}
+
+func TestTransaction_MarshalIndent(t *testing.T) {
+	tx := newTransaction()
+	if err := tx.SignTransaction(); err != nil {
+		t.Fatal("TestTransaction_MarshalIndent failed:", err)
+	}
+
+	jsonBytes, err := json.MarshalIndent(tx, "", "  ")
+	if err != nil {
+		t.Fatal("TestTransaction_MarshalIndent failed:", err)
+	}
+	var decoded Transaction
+	if err := json.Unmarshal(jsonBytes, &decoded); err != nil {
+		t.Fatal("TestTransaction_MarshalIndent failed:", err)
+	}
+	if !bytes.Equal(decoded.Data.Payload, tx.Data.Payload) {
+		t.Errorf("TestTransaction_MarshalIndent payload %q != %q", decoded.Data.Payload, tx.Data.Payload)
+	}
+	if err := decoded.Verify(); err != nil {
+		t.Error("TestTransaction_MarshalIndent broke the signature:", err)
+	}
+
+	// transactions written with the payload as an object
+	var legacy map[string]interface{}
+	json.Unmarshal(jsonBytes, &legacy)
+	legacy["data"].(map[string]interface{})["payload"] = json.RawMessage("{\n  \"Function\": \"save\",\n  \"Args\": \"[0]\"\n}")
+	legacyBytes, _ := json.MarshalIndent(legacy, "", "  ")
+	if err := json.Unmarshal(legacyBytes, &decoded); err != nil || decoded.Verify() != nil {
+		t.Errorf("TestTransaction_MarshalIndent payload object %q: %v", decoded.Data.Payload, err)
+	}
+}
+
+func TestTransaction_SummaryEscapesPayload(t *testing.T) {
+	tx := newTransaction()
+	tx.Data.Payload = []byte("{}\nTo:         n1evil\x1b[2K")
+
+	summary := tx.Summary()
+	if strings.Count(summary, "\n") != 10 || strings.Contains(summary, "\x1b") || strings.Contains(summary, "\nTo:         n1evil") {
+		t.Errorf("TestTransaction_SummaryEscapesPayload forged lines:\n%s", summary)
+	}
+}
+
+func TestTransaction_FromString(t *testing.T) {
+	contracts := []*Contract{
+		{},
+		{Binary: []byte("binary data")},
+		{Source: "if (a < b && b > c) { return '<tag>' }", SourceType: "js", Args: `[]`},
+		{Function: "save", Args: `[0]`},
+	}
+
+	for _, contract := range contracts {
+		tx := newTransaction()
+		tx.Data = parseContract(contract)
+		tx.SignTransaction()
+
+		txStr, err := tx.ToString()
+		if err != nil {
+			t.Fatal("TestTransaction_FromString failed:", err)
+		}
+
+		decoded, err := new(Transaction).FromString(txStr)
+		if err != nil {
+			t.Fatal("TestTransaction_FromString failed:", err)
+		}
+		if err := decoded.Verify(); err != nil {
+			t.Errorf("TestTransaction_FromString %s payload failed: %s", tx.Data.Type, err)
+		}
+
+		protoStr, _ := tx.ToProtoString()
+		decodedProtoStr, _ := decoded.ToProtoString()
+		if decodedProtoStr != protoStr {
+			t.Errorf("TestTransaction_FromString %s payload changed the transaction", tx.Data.Type)
+		}
+
+		decodedStr, _ := decoded.ToString()
+		if decodedStr != txStr {
+			t.Errorf("TestTransaction_FromString %s payload: %s != %s", tx.Data.Type, decodedStr, txStr)
+		}
+	}
+}
+
+func TestTransaction_MarshalJSON(t *testing.T) {
+	tx := newTransaction()
+	tx.Data.Payload = []byte(`{ "Function": "save" }`)
+
+	jsonBytes, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal("TestTransaction_MarshalJSON failed:", err)
+	}
+
+	var decoded Transaction
+	err = json.Unmarshal(jsonBytes, &decoded)
+	if err != nil {
+		t.Fatal("TestTransaction_MarshalJSON failed:", err)
+	}
+	if !bytes.Equal(decoded.Data.Payload, tx.Data.Payload) {
+		t.Errorf("TestTransaction_MarshalJSON payload %s != %s", decoded.Data.Payload, tx.Data.Payload)
+	}
+	if decoded.Hash != nil || decoded.Sign != nil {
+		t.Error("TestTransaction_MarshalJSON unsigned transaction decoded with a signature")
+	}
+	if !bytes.Equal(decoded.HashTransaction(), tx.HashTransaction()) {
+		t.Error("TestTransaction_MarshalJSON changed the transaction")
+	}
+
+	_, err = new(Transaction).FromString(`{"from":"n1invalid","to":"n1SAeQRVn33bamxN4ehWUT7JGdxipwn8b17"}`)
+	if err == nil {
+		t.Error("TestTransaction_MarshalJSON accepted an invalid from address")
+	}
+}
+
+func TestTransaction_MarshalJSONMissing(t *testing.T) {
+	if _, err := json.Marshal(&Transaction{}); err == nil {
+		t.Error("TestTransaction_MarshalJSONMissing encoded a transaction without data")
+	}
+
+	tx := newTransaction()
+	tx.Value = nil
+	if _, err := tx.ToString(); err == nil {
+		t.Error("TestTransaction_MarshalJSONMissing encoded a transaction without value")
+	}
+}
+
+func TestTransaction_ToStringPayloadObject(t *testing.T) {
+	tx := newTransaction()
+	if err := tx.SignTransaction(); err != nil {
+		t.Fatal("TestTransaction_ToStringPayloadObject failed:", err)
+	}
+	txStr, err := tx.ToString()
+	if err != nil {
+		t.Fatal("TestTransaction_ToStringPayloadObject failed:", err)
+	}
+
+	var txMap struct {
+		Data struct {
+			Payload map[string]interface{} `json:"payload"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(txStr), &txMap); err != nil {
+		t.Fatal("TestTransaction_ToStringPayloadObject payload is not an object:", err)
+	}
+	if txMap.Data.Payload["Function"] != "save" {
+		t.Errorf("TestTransaction_ToStringPayloadObject payload %v", txMap.Data.Payload)
+	}
+
+	// payloads the object would not give back stay strings
+	tx.Data.Payload = []byte(`{ "Function": "save" }`)
+	jsonBytes, _ := json.Marshal(tx)
+	if !strings.Contains(string(jsonBytes), `"payload":"{ \"Function\": \"save\" }"`) {
+		t.Errorf("TestTransaction_ToStringPayloadObject payload %s", jsonBytes)
+	}
+}
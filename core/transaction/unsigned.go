@@ -0,0 +1,185 @@
+package transaction
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/nebulasio/go-nebulas/core/pb"
+	"github.com/vigozhang/neb-go/core/account"
+)
+
+// UnsignedTransaction is the portable JSON form of a transaction that is
+// built on an online machine and signed on an offline one.
+type UnsignedTransaction struct {
+	ChainID     uint32 `json:"chainID"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Value       string `json:"value"`
+	Nonce       uint64 `json:"nonce"`
+	Timestamp   int64  `json:"timestamp"`
+	PayloadType string `json:"payloadType"`
+	Payload     []byte `json:"payload,omitempty"`
+	GasPrice    string `json:"gasPrice"`
+	GasLimit    string `json:"gasLimit"`
+}
+
+// ToUnsigned exports the transaction content without hash and signature.
+func (tx *Transaction) ToUnsigned() *UnsignedTransaction {
+	return &UnsignedTransaction{
+		ChainID:     tx.ChainID,
+		From:        tx.From.GetAddressString(),
+		To:          tx.To.GetAddressString(),
+		Value:       tx.Value.String(),
+		Nonce:       tx.Nonce,
+		Timestamp:   tx.Timestamp,
+		PayloadType: tx.Data.Type,
+		Payload:     tx.Data.Payload,
+		GasPrice:    tx.GasPrice.String(),
+		GasLimit:    tx.GasLimit.String(),
+	}
+}
+
+func (tx *Transaction) ToUnsignedJSON() ([]byte, error) {
+	return json.MarshalIndent(tx.ToUnsigned(), "", "  ")
+}
+
+// ToUnsignedProtoString exports the transaction like ToProtoString, without
+// hash and signature.
+func (tx *Transaction) ToUnsignedProtoString() (string, error) {
+	txProto := tx.protoMessage()
+	txProto.Hash = nil
+	txProto.Alg = 0
+	txProto.Sign = nil
+
+	txBytes, err := proto.Marshal(txProto)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(txBytes), nil
+}
+
+func (tx *Transaction) FromUnsigned(unsigned *UnsignedTransaction) (*Transaction, error) {
+	var err error
+	tx.From, err = account.FromAddress(unsigned.From)
+	if err != nil {
+		return nil, err
+	}
+	tx.To, err = account.FromAddress(unsigned.To)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.Value, err = parseAmount("value", unsigned.Value)
+	if err != nil {
+		return nil, err
+	}
+	tx.GasPrice, err = parseAmount("gasPrice", unsigned.GasPrice)
+	if err != nil {
+		return nil, err
+	}
+	tx.GasLimit, err = parseAmount("gasLimit", unsigned.GasLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.ChainID = unsigned.ChainID
+	tx.Nonce = unsigned.Nonce
+	tx.Timestamp = unsigned.Timestamp
+	tx.Data = &TxPayload{Type: unsigned.PayloadType, Payload: unsigned.Payload}
+	if len(tx.Data.Payload) == 0 {
+		tx.Data.Payload = nil
+	}
+	tx.Hash = nil
+	tx.Alg = 0
+	tx.Sign = nil
+	return tx, nil
+}
+
+// ParseUnsigned imports an unsigned transaction exported by ToUnsignedJSON or
+// ToUnsignedProtoString. Any hash or signature in the input is dropped.
+func ParseUnsigned(input string) (*Transaction, error) {
+	input = strings.TrimSpace(input)
+	if strings.HasPrefix(input, "{") {
+		var unsigned UnsignedTransaction
+		err := json.Unmarshal([]byte(input), &unsigned)
+		if err != nil {
+			return nil, err
+		}
+		return new(Transaction).FromUnsigned(&unsigned)
+	}
+
+	txBytes, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return nil, err
+	}
+	txProto := corepb.Transaction{}
+	err = proto.Unmarshal(txBytes, &txProto)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := new(Transaction).fromProtoMessage(&txProto)
+	if err != nil {
+		return nil, err
+	}
+	tx.Hash = nil
+	tx.Alg = 0
+	tx.Sign = nil
+	return tx, nil
+}
+
+// Summary describes the transaction for a human to review before signing.
+// The type and payload come from the transaction and are quoted, so control
+// characters can not forge the other lines.
+func (tx *Transaction) Summary() string {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Chain ID:   %d\n", tx.ChainID)
+	fmt.Fprintf(&summary, "From:       %s\n", tx.From.GetAddressString())
+	fmt.Fprintf(&summary, "To:         %s\n", tx.To.GetAddressString())
+	fmt.Fprintf(&summary, "Value:      %s NAS (%s wei)\n", formatNas(tx.Value), tx.Value.String())
+	fmt.Fprintf(&summary, "Nonce:      %d\n", tx.Nonce)
+	fmt.Fprintf(&summary, "Gas price:  %s\n", tx.GasPrice.String())
+	fmt.Fprintf(&summary, "Gas limit:  %s\n", tx.GasLimit.String())
+	maxFee := new(big.Int).Mul(tx.GasPrice, tx.GasLimit)
+	fmt.Fprintf(&summary, "Max fee:    %s NAS\n", formatNas(maxFee))
+	fmt.Fprintf(&summary, "Type:       %q\n", tx.Data.Type)
+	if tx.Data.Payload != nil {
+		fmt.Fprintf(&summary, "Payload:    %q\n", tx.Data.Payload)
+	}
+	return summary.String()
+}
+
+// SignWithKey unlocks the from account from its keystore json and signs the
+// transaction, the keystore must belong to the from address.
+func (tx *Transaction) SignWithKey(keyjson string, passphrase string) error {
+	acc, err := account.NewAccount().FromKey(keyjson, passphrase, false)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(acc.GetAddress(), tx.From.GetAddress()) {
+		return errors.New("keystore " + acc.GetAddressString() + " does not match from address " + tx.From.GetAddressString())
+	}
+
+	tx.From = acc
+	return tx.SignTransaction()
+}
+
+func parseAmount(name string, value string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, errors.New("invalid " + name + " " + value)
+	}
+	return amount, nil
+}
+
+// formatNas formats an amount of wei (1/10^18 NAS) in NAS.
+func formatNas(wei *big.Int) string {
+	nas := new(big.Rat).SetFrac(wei, new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+	return strings.TrimRight(strings.TrimRight(nas.FloatString(18), "0"), ".")
+}
@@ -0,0 +1,59 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package utils
+
+import (
+	"github.com/spacemonkeygo/openssl"
+)
+
+func OpensslEncrypt(input []byte, algorithm string, key []byte, iv []byte) ([]byte, error) {
+	cipher, err := openssl.GetCipherByName(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := openssl.NewEncryptionCipherCtx(cipher, nil, key, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherbytes, err := ctx.EncryptUpdate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	finalbytes, err := ctx.EncryptFinal()
+	if err != nil {
+		return nil, err
+	}
+
+	cipherbytes = append(cipherbytes, finalbytes...)
+	return cipherbytes, nil
+}
+
+func OpensslDecrypt(input []byte, algorithm string, key []byte, iv []byte) ([]byte, error) {
+	cipher, err := openssl.GetCipherByName(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := openssl.NewDecryptionCipherCtx(cipher, nil, key, iv)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cipherbytes, err := ctx.DecryptUpdate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	finalbytes, err := ctx.DecryptFinal()
+	if err != nil {
+		return nil, err
+	}
+
+	cipherbytes = append(cipherbytes, finalbytes...)
+	return cipherbytes, nil
+}
@@ -0,0 +1,106 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"strings"
+)
+
+// the pure Go OpensslEncrypt and OpensslDecrypt, selected by the purego build
+// tag or when cgo is disabled. They implement the aes ciphers of openssl
+// with the same output: CBC is PKCS#7 padded, CTR is not.
+
+// aesMode the key size in bytes and mode of an openssl aes cipher name.
+func aesMode(algorithm string) (int, string, error) {
+	switch strings.ToLower(algorithm) {
+	case "aes-128-ctr":
+		return 16, "ctr", nil
+	case "aes-192-ctr":
+		return 24, "ctr", nil
+	case "aes-256-ctr":
+		return 32, "ctr", nil
+	case "aes-128-cbc":
+		return 16, "cbc", nil
+	case "aes-192-cbc":
+		return 24, "cbc", nil
+	case "aes-256-cbc":
+		return 32, "cbc", nil
+	}
+	return 0, "", errors.New("cipher " + algorithm + " not found")
+}
+
+// newAESBlock checks the key and iv like the openssl backend: the key must
+// be exactly the key size of the cipher.
+func newAESBlock(algorithm string, key []byte, iv []byte) (cipher.Block, string, error) {
+	keySize, mode, err := aesMode(algorithm)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(key) != keySize {
+		return nil, "", errors.New("bad key size")
+	}
+	if len(iv) < aes.BlockSize {
+		return nil, "", errors.New("bad IV size")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+	return block, mode, nil
+}
+
+func OpensslEncrypt(input []byte, algorithm string, key []byte, iv []byte) ([]byte, error) {
+	block, mode, err := newAESBlock(algorithm, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	iv = iv[:aes.BlockSize]
+
+	if mode == "ctr" {
+		output := make([]byte, len(input))
+		cipher.NewCTR(block, iv).XORKeyStream(output, input)
+		return output, nil
+	}
+
+	padding := aes.BlockSize - len(input)%aes.BlockSize
+	output := append(append([]byte{}, input...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(output, output)
+	return output, nil
+}
+
+func OpensslDecrypt(input []byte, algorithm string, key []byte, iv []byte) ([]byte, error) {
+	block, mode, err := newAESBlock(algorithm, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	iv = iv[:aes.BlockSize]
+
+	if mode == "ctr" {
+		output := make([]byte, len(input))
+		cipher.NewCTR(block, iv).XORKeyStream(output, input)
+		return output, nil
+	}
+
+	if len(input) == 0 || len(input)%aes.BlockSize != 0 {
+		return nil, errors.New("bad decrypt")
+	}
+	output := make([]byte, len(input))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(output, input)
+
+	padding := int(output[len(output)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errors.New("bad decrypt")
+	}
+	for _, b := range output[len(output)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("bad decrypt")
+		}
+	}
+	return output[:len(output)-padding], nil
+}
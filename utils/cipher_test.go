@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+// ciphertexts produced by the openssl command line, e.g.
+// printf nebulas | openssl enc -aes-128-cbc -K <key> -iv <iv>
+// Both backends, selected by the purego tag, must match them.
+var cipherVectors = []struct {
+	algorithm  string
+	key        string
+	plaintext  string
+	ciphertext string
+}{
+	{"aes-128-ctr", "000102030405060708090a0b0c0d0e0f", "nebulas", "08c2a59d583342"},
+	{"aes-128-ctr", "000102030405060708090a0b0c0d0e0f", "0123456789abcdef0123456789abcdef", "5696f5db0067077faf68bf655072c8cb82b0e53383ab0a9a9c9412d90df87a8c"},
+	{"aes-128-cbc", "000102030405060708090a0b0c0d0e0f", "", "d02a48244eccdc2379224dbc54703612"},
+	{"aes-128-cbc", "000102030405060708090a0b0c0d0e0f", "nebulas", "8daa73aa7a256867625bb101201589bc"},
+	{"aes-128-cbc", "000102030405060708090a0b0c0d0e0f", "0123456789abcdef0123456789abcdef", "71e21619aa870db1922c69f851b5160f5adca316ac4f5df730c577b11b73c558b52583dec328544282d74c7514a50993"},
+	{"aes-192-ctr", "000102030405060708090a0b0c0d0e0f1011121314151617", "nebulas", "45e72a2738931a"},
+	{"aes-192-ctr", "000102030405060708090a0b0c0d0e0f1011121314151617", "0123456789abcdef0123456789abcdef", "1bb37a6160c75fa78be057609381cd95eb3919b0fda1c106bf56e9a648a5cc62"},
+	{"aes-192-cbc", "000102030405060708090a0b0c0d0e0f1011121314151617", "", "52a617776bd278a1a7e08ade0aa1c22a"},
+	{"aes-192-cbc", "000102030405060708090a0b0c0d0e0f1011121314151617", "nebulas", "c9a3521de62be31b5fc9c3bb18cd8c8c"},
+	{"aes-192-cbc", "000102030405060708090a0b0c0d0e0f1011121314151617", "0123456789abcdef0123456789abcdef", "c905bf4ab87110a7f5583f2469b6754ff3014ffc7c60503b1e94f991740578934eef43e55e1ba2b4c6989fd3fc9731ea"},
+	{"aes-256-ctr", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "nebulas", "fc65aff84ff7f3"},
+	{"aes-256-ctr", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "0123456789abcdef0123456789abcdef", "a231ffbe17a3b6fc6250873623560672fa6e4973c51f02f6569e34432913e3e8"},
+	{"aes-256-cbc", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "", "edaf9e57d045ac857f023f9dc238b14e"},
+	{"aes-256-cbc", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "nebulas", "d22eeba695d178399653669354fbf7de"},
+	{"aes-256-cbc", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "0123456789abcdef0123456789abcdef", "cd951146cc74046a56c93a30e4a7cd504382b0cfb2b1dcfebeb9914799e6e32bdf640e4d0d003e4a0f9ba734acf75152"},
+}
+
+func TestOpensslEncrypt_Vectors(t *testing.T) {
+	iv, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	for _, vector := range cipherVectors {
+		key, _ := hex.DecodeString(vector.key)
+
+		ciphertext, err := OpensslEncrypt([]byte(vector.plaintext), vector.algorithm, key, iv)
+		if err != nil || hex.EncodeToString(ciphertext) != vector.ciphertext {
+			t.Errorf("OpensslEncrypt %s %q: %x %v", vector.algorithm, vector.plaintext, ciphertext, err)
+		}
+
+		expected, _ := hex.DecodeString(vector.ciphertext)
+		plaintext, err := OpensslDecrypt(expected, vector.algorithm, key, iv)
+		if err != nil || string(plaintext) != vector.plaintext {
+			t.Errorf("OpensslDecrypt %s %q: %q %v", vector.algorithm, vector.plaintext, plaintext, err)
+		}
+	}
+
+	_, err := OpensslEncrypt([]byte("nebulas"), "unknown-cipher", make([]byte, 32), iv)
+	if err == nil {
+		t.Error("OpensslEncrypt accepted an unknown cipher")
+	}
+}
+
+func TestOpensslEncrypt_KeySize(t *testing.T) {
+	iv := make([]byte, aes.BlockSize)
+	for _, vector := range cipherVectors {
+		size := len(vector.key) / 2
+		for _, keySize := range []int{size - 1, size + 1, 2 * size} {
+			if _, err := OpensslEncrypt([]byte("nebulas"), vector.algorithm, make([]byte, keySize), iv); err == nil {
+				t.Errorf("OpensslEncrypt %s accepted a %d bytes key", vector.algorithm, keySize)
+			}
+			if _, err := OpensslDecrypt(make([]byte, aes.BlockSize), vector.algorithm, make([]byte, keySize), iv); err == nil {
+				t.Errorf("OpensslDecrypt %s accepted a %d bytes key", vector.algorithm, keySize)
+			}
+		}
+	}
+}
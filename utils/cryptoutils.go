@@ -3,8 +3,6 @@ package utils
 import (
 	"io"
 	"crypto/rand"
-
-	"github.com/spacemonkeygo/openssl"
 )
 
 // RandomCSPRNG a cryptographically secure pseudo-random number generator
@@ -23,54 +21,3 @@ func ZeroBytes(bytes []byte) {
 		bytes[i] = 0
 	}
 }
-
-func OpensslEncrypt(input []byte, algorithm string, key []byte, iv []byte) ([]byte, error) {
-	cipher, err := openssl.GetCipherByName(algorithm)
-	if err != nil {
-		return nil, err
-	}
-
-	ctx, err := openssl.NewEncryptionCipherCtx(cipher, nil, key, iv)
-	if err != nil {
-		return nil, err
-	}
-
-	cipherbytes, err := ctx.EncryptUpdate(input)
-	if err != nil {
-		return nil, err
-	}
-
-	finalbytes, err := ctx.EncryptFinal()
-	if err != nil {
-		return nil, err
-	}
-
-	cipherbytes = append(cipherbytes, finalbytes...)
-	return cipherbytes, nil
-}
-
-func OpensslDecrypt(input []byte, algorithm string, key []byte, iv []byte) ([]byte, error) {
-	cipher, err := openssl.GetCipherByName(algorithm)
-	if err != nil {
-		return nil, err
-	}
-
-	ctx, err := openssl.NewDecryptionCipherCtx(cipher, nil, key, iv)
-
-	if err != nil {
-		return nil, err
-	}
-
-	cipherbytes, err := ctx.DecryptUpdate(input)
-	if err != nil {
-		return nil, err
-	}
-
-	finalbytes, err := ctx.DecryptFinal()
-	if err != nil {
-		return nil, err
-	}
-
-	cipherbytes = append(cipherbytes, finalbytes...)
-	return cipherbytes, nil
-}
@@ -1,6 +1,9 @@
 package httprequest
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"strings"
 	"io/ioutil"
 	"net/http"
@@ -9,9 +12,24 @@ import (
 	"bufio"
 )
 
+// Transport sends the requests of the rpc client to a node. HttpRequest is the
+// default implementation; tests and applications can supply their own.
+type Transport interface {
+	GetWithContext(ctx context.Context, api string, params map[string]string) ([]byte, error)
+	PostWithContext(ctx context.Context, api string, reqBody interface{}) ([]byte, error)
+	OpenStreamWithContext(ctx context.Context, api string, reqBody interface{}) (io.ReadCloser, error)
+}
+
 type HttpRequest struct {
 	Host       string
 	ApiVersion string
+
+	// Client sends the requests, http.DefaultClient when nil.
+	Client *http.Client
+	// Header is added to every request, e.g. gateway API keys.
+	Header http.Header
+	// UserAgent overrides the Go default User-Agent when set.
+	UserAgent string
 }
 
 const (
@@ -22,8 +40,43 @@ const (
 	APIVersion1 = "v1"
 )
 
+var _ Transport = (*HttpRequest)(nil)
+
+// StatusError is returned when the node answers with a non-2xx status. Body
+// holds the response payload, which usually carries the node's error message.
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected http status %d: %s", e.StatusCode, strings.TrimSpace(string(e.Body)))
+}
+
 func NewHttpRequest(host string, apiVersion string) *HttpRequest {
-	return &HttpRequest{host, apiVersion}
+	return &HttpRequest{Host: host, ApiVersion: apiVersion}
+}
+
+func (req *HttpRequest) SetClient(client *http.Client) {
+	req.Client = client
+}
+
+// SetHeader sets a header sent with every request, replacing any existing
+// values of key.
+func (req *HttpRequest) SetHeader(key string, value string) {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set(key, value)
+}
+
+// SetBearerToken authenticates every request with an Authorization bearer token.
+func (req *HttpRequest) SetBearerToken(token string) {
+	req.SetHeader("Authorization", "Bearer "+token)
+}
+
+func (req *HttpRequest) SetUserAgent(userAgent string) {
+	req.UserAgent = userAgent
 }
 
 func (req *HttpRequest) CreateUrl(api string) string {
@@ -31,6 +84,12 @@ func (req *HttpRequest) CreateUrl(api string) string {
 }
 
 func (req *HttpRequest) Get(api string, params map[string]string) ([]byte, error) {
+	return req.GetWithContext(context.Background(), api, params)
+}
+
+// GetWithContext is like Get but the request is bound to ctx, so it is aborted
+// when ctx is cancelled or its deadline expires.
+func (req *HttpRequest) GetWithContext(ctx context.Context, api string, params map[string]string) ([]byte, error) {
 	url := req.CreateUrl(api)
 	if params != nil {
 		url += "?"
@@ -40,12 +99,16 @@ func (req *HttpRequest) Get(api string, params map[string]string) ([]byte, error
 		url = strings.TrimSuffix(url, "&")
 	}
 
-	response, err := http.Get(url)
-	defer response.Body.Close()
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
 
+	response, err := req.do(ctx, request)
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
 
 	body, err := ioutil.ReadAll(response.Body)
 
@@ -53,66 +116,121 @@ func (req *HttpRequest) Get(api string, params map[string]string) ([]byte, error
 		return nil, err
 	}
 
+	if !isSuccess(response.StatusCode) {
+		return body, &StatusError{response.StatusCode, body}
+	}
+
 	return body, nil
 }
 
 func (req *HttpRequest) Post(api string, reqBody interface{}) ([]byte, error) {
-	url := req.CreateUrl(api)
-	contentType := "application/json"
-
-	jsonReqBody, err := json.Marshal(reqBody)
+	return req.PostWithContext(context.Background(), api, reqBody)
+}
 
+// PostWithContext is like Post but the request is bound to ctx.
+func (req *HttpRequest) PostWithContext(ctx context.Context, api string, reqBody interface{}) ([]byte, error) {
+	response, err := req.post(ctx, api, reqBody)
 	if err != nil {
 		return nil, err
 	}
-
-	response, err := http.Post(url, contentType, bytes.NewBuffer(jsonReqBody))
 	defer response.Body.Close()
 
+	body, err := ioutil.ReadAll(response.Body)
+
 	if err != nil {
 		return nil, err
 	}
 
-	body, err := ioutil.ReadAll(response.Body)
+	if !isSuccess(response.StatusCode) {
+		return body, &StatusError{response.StatusCode, body}
+	}
 
+	return body, nil
+}
+
+func (req *HttpRequest) PostStream(api string, reqBody interface{}, callback func([]byte)) (error) {
+	return req.PostStreamWithContext(context.Background(), api, reqBody, callback)
+}
+
+// PostStreamWithContext is like PostStream but the stream is closed when ctx
+// is cancelled, which unblocks the read loop.
+func (req *HttpRequest) PostStreamWithContext(ctx context.Context, api string, reqBody interface{}, callback func([]byte)) (error) {
+	stream, err := req.OpenStreamWithContext(ctx, api, reqBody)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadBytes('\n')
+
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+
+		callback(line)
+	}
+
+	return nil
+}
+
+// OpenStreamWithContext posts reqBody to api and returns the response body
+// without reading it. The caller must close the returned stream.
+func (req *HttpRequest) OpenStreamWithContext(ctx context.Context, api string, reqBody interface{}) (io.ReadCloser, error) {
+	response, err := req.post(ctx, api, reqBody)
 	if err != nil {
 		return nil, err
 	}
 
-	return body, nil
+	if !isSuccess(response.StatusCode) {
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		return nil, &StatusError{response.StatusCode, body}
+	}
+
+	return response.Body, nil
 }
 
-func (req *HttpRequest) PostStream(api string, reqBody interface{}, callback func([]byte)) (error) {
+func (req *HttpRequest) post(ctx context.Context, api string, reqBody interface{}) (*http.Response, error) {
 	url := req.CreateUrl(api)
 	contentType := "application/json"
 
 	jsonReqBody, err := json.Marshal(reqBody)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	response, err := http.Post(url, contentType, bytes.NewBuffer(jsonReqBody))
-	defer response.Body.Close()
-
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonReqBody))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	request.Header.Set("Content-Type", contentType)
 
-	reader := bufio.NewReader(response.Body)
-	for {
-		line, err := reader.ReadBytes('\n')
+	return req.do(ctx, request)
+}
 
-		if err != nil {
-			break
+func (req *HttpRequest) do(ctx context.Context, request *http.Request) (*http.Response, error) {
+	for key, values := range req.Header {
+		for _, value := range values {
+			request.Header.Add(key, value)
 		}
-
-		callback(line)
+	}
+	if req.UserAgent != "" {
+		request.Header.Set("User-Agent", req.UserAgent)
 	}
 
-	if err != nil {
-		return err
+	client := req.Client
+	if client == nil {
+		client = http.DefaultClient
 	}
+	return client.Do(request.WithContext(ctx))
+}
 
-	return nil
+func isSuccess(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
 }
@@ -0,0 +1,59 @@
+package httprequest
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"io/ioutil"
+	"context"
+)
+
+func TestHttpRequest_PostWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/user/accountstate" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("X-Api-Key") != "key" {
+			t.Errorf("unexpected X-Api-Key header %q", r.Header.Get("X-Api-Key"))
+		}
+		if r.UserAgent() != "neb-go-test" {
+			t.Errorf("unexpected User-Agent %q", r.UserAgent())
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	req := NewHttpRequest(server.URL, APIVersion1)
+	req.SetClient(server.Client())
+	req.SetBearerToken("token")
+	req.SetHeader("X-Api-Key", "key")
+	req.SetUserAgent("neb-go-test")
+
+	resp, err := req.PostWithContext(context.Background(), "/user/accountstate", map[string]string{"address": "n1"})
+	if err != nil {
+		t.Fatal("TestHttpRequest_PostWithContext failed:", err)
+	}
+	if string(resp) != `{"address":"n1"}` {
+		t.Errorf("TestHttpRequest_PostWithContext unexpected response %s", resp)
+	}
+}
+
+func TestHttpRequest_GetWithContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	req := NewHttpRequest(server.URL, APIVersion1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := req.GetWithContext(ctx, "/user/nebstate", nil)
+	if err == nil {
+		t.Error("TestHttpRequest_GetWithContextCancelled should fail with a cancelled context")
+	}
+}
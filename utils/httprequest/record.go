@@ -0,0 +1,428 @@
+package httprequest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	MethodGet    = "GET"
+	MethodPost   = "POST"
+	MethodStream = "STREAM"
+)
+
+// ErrUnexpectedRequest the replayed cassette has no interaction for the request
+var ErrUnexpectedRequest = errors.New("unexpected request")
+
+// Redacted replaces the secrets of the recorded request bodies, the json
+// fields named passphrase or password, so the golden files can be committed.
+const Redacted = "REDACTED"
+
+var redactedFields = []string{"passphrase", "password"}
+
+// Interaction is a request sent to a node and the response it answered.
+type Interaction struct {
+	// Method MethodGet, MethodPost or MethodStream for OpenStreamWithContext
+	Method string            `json:"method"`
+	Api    string            `json:"api"`
+	Params map[string]string `json:"params,omitempty"`
+	// Request the json request body
+	Request RecordedBody `json:"request,omitempty"`
+
+	StatusCode int          `json:"status_code"`
+	Response   RecordedBody `json:"response,omitempty"`
+	// Lines the lines of a stream response, without the line feeds
+	Lines []RecordedBody `json:"lines,omitempty"`
+}
+
+// Cassette is the golden file of a Recorder and a Replayer.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// RecordedBody is a body kept as json in the golden files. Bodies that are
+// not a json object or array are kept as a json string.
+type RecordedBody []byte
+
+func (body RecordedBody) MarshalJSON() ([]byte, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return trimmed, nil
+	}
+	return json.Marshal(string(body))
+}
+
+func (body *RecordedBody) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		err := json.Unmarshal(data, &text)
+		if err != nil {
+			return err
+		}
+		*body = RecordedBody(text)
+		return nil
+	}
+	// drop the indentation of the golden file, a stream line must stay on a
+	// single line
+	var compact bytes.Buffer
+	err := json.Compact(&compact, data)
+	if err != nil {
+		return err
+	}
+	*body = compact.Bytes()
+	return nil
+}
+
+// ReadCassette reads the golden file at path.
+func ReadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := Cassette{}
+	err = json.Unmarshal(data, &cassette)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette %s: %s", path, err)
+	}
+	return &cassette, nil
+}
+
+// WriteFile writes the cassette to path, creating its directory if needed.
+func (cassette *Cassette) WriteFile(path string) error {
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Recorder is a Transport that sends the requests through Transport,
+// usually an *HttpRequest to a live node, and records them with the node
+// responses. Network errors are not recorded, and the passphrases of the
+// requests are recorded as Redacted.
+type Recorder struct {
+	Transport Transport
+	// StreamLines ends the recorded streams after this many lines, so a
+	// subscription to a live node does not run forever. No limit when 0.
+	StreamLines int
+
+	path     string
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+var _ Transport = (*Recorder)(nil)
+
+// NewRecorder returns a recorder that Save writes to the golden file at path.
+func NewRecorder(transport Transport, path string) *Recorder {
+	return &Recorder{Transport: transport, path: path}
+}
+
+func (r *Recorder) GetWithContext(ctx context.Context, api string, params map[string]string) ([]byte, error) {
+	body, err := r.Transport.GetWithContext(ctx, api, params)
+	r.record(&Interaction{Method: MethodGet, Api: api, Params: params}, body, err)
+	return body, err
+}
+
+func (r *Recorder) PostWithContext(ctx context.Context, api string, reqBody interface{}) ([]byte, error) {
+	body, err := r.Transport.PostWithContext(ctx, api, reqBody)
+	request, marshalErr := json.Marshal(reqBody)
+	if marshalErr == nil {
+		r.record(&Interaction{Method: MethodPost, Api: api, Request: redact(request)}, body, err)
+	}
+	return body, err
+}
+
+func (r *Recorder) OpenStreamWithContext(ctx context.Context, api string, reqBody interface{}) (io.ReadCloser, error) {
+	request, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	interaction := &Interaction{Method: MethodStream, Api: api, Request: redact(request)}
+
+	stream, err := r.Transport.OpenStreamWithContext(ctx, api, reqBody)
+	if err != nil {
+		r.record(interaction, nil, err)
+		return nil, err
+	}
+
+	interaction.StatusCode = 200
+	reader, writer := io.Pipe()
+	go r.recordStream(interaction, stream, writer)
+	return reader, nil
+}
+
+// recordStream copies the lines of stream to writer and records them, until
+// the stream ends, the reader is closed or StreamLines are recorded.
+func (r *Recorder) recordStream(interaction *Interaction, stream io.ReadCloser, writer *io.PipeWriter) {
+	defer stream.Close()
+	defer r.add(interaction)
+
+	reader := bufio.NewReader(stream)
+	for r.StreamLines == 0 || len(interaction.Lines) < r.StreamLines {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			interaction.Lines = append(interaction.Lines, RecordedBody(bytes.TrimRight(line, "\r\n")))
+			_, writeErr := writer.Write(line)
+			if writeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				writer.CloseWithError(err)
+				return
+			}
+			break
+		}
+	}
+	writer.Close()
+}
+
+func (r *Recorder) record(interaction *Interaction, body []byte, err error) {
+	if err != nil {
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) {
+			return
+		}
+		interaction.StatusCode = statusErr.StatusCode
+		body = statusErr.Body
+	} else {
+		interaction.StatusCode = 200
+	}
+	interaction.Response = body
+	r.add(interaction)
+}
+
+func (r *Recorder) add(interaction *Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+}
+
+// Save writes the recorded interactions to the golden file.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cassette.WriteFile(r.path)
+}
+
+// Replayer is a Transport that answers the requests with the responses of a
+// golden file, without any network access. Requests are matched on their
+// method, api, params and json body, whatever their passphrases, which the
+// golden files do not keep. Identical requests get the recorded
+// responses in order, the last one is repeated when they run out. Requests
+// that were never recorded fail with ErrUnexpectedRequest.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []*Interaction
+	served       []int
+}
+
+var _ Transport = (*Replayer)(nil)
+
+// NewReplayer reads the golden file at path.
+func NewReplayer(path string) (*Replayer, error) {
+	cassette, err := ReadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewCassetteReplayer(cassette), nil
+}
+
+// NewCassetteReplayer replays the interactions of cassette.
+func NewCassetteReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{
+		interactions: cassette.Interactions,
+		served:       make([]int, len(cassette.Interactions)),
+	}
+}
+
+func (r *Replayer) GetWithContext(ctx context.Context, api string, params map[string]string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	interaction, err := r.match(&Interaction{Method: MethodGet, Api: api, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	return interaction.response()
+}
+
+func (r *Replayer) PostWithContext(ctx context.Context, api string, reqBody interface{}) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	request, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction, err := r.match(&Interaction{Method: MethodPost, Api: api, Request: redact(request)})
+	if err != nil {
+		return nil, err
+	}
+	return interaction.response()
+}
+
+func (r *Replayer) OpenStreamWithContext(ctx context.Context, api string, reqBody interface{}) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	request, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction, err := r.match(&Interaction{Method: MethodStream, Api: api, Request: redact(request)})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := interaction.response(); err != nil {
+		return nil, err
+	}
+
+	var stream bytes.Buffer
+	for _, line := range interaction.Lines {
+		stream.Write(line)
+		stream.WriteByte('\n')
+	}
+	return ioutil.NopCloser(&stream), nil
+}
+
+// Unused returns the recorded interactions no request has matched, e.g. to
+// check a test still sends every recorded request.
+func (r *Replayer) Unused() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []*Interaction
+	for i, interaction := range r.interactions {
+		if r.served[i] == 0 {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+func (r *Replayer) match(request *Interaction) (*Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i, interaction := range r.interactions {
+		if !interaction.matches(request) {
+			continue
+		}
+		if r.served[i] == 0 {
+			r.served[i]++
+			return interaction, nil
+		}
+		last = i
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("%w: %s %s %s", ErrUnexpectedRequest, request.Method, request.Api, request.Request)
+	}
+	r.served[last]++
+	return r.interactions[last], nil
+}
+
+func (interaction *Interaction) matches(request *Interaction) bool {
+	if interaction.Method != request.Method || interaction.Api != request.Api {
+		return false
+	}
+	if len(interaction.Params) > 0 || len(request.Params) > 0 {
+		if !reflect.DeepEqual(interaction.Params, request.Params) {
+			return false
+		}
+	}
+	return jsonEqual(interaction.Request, request.Request)
+}
+
+// redact replaces the redacted fields of a json body with Redacted, at any
+// depth. Bodies without them are returned unchanged.
+func redact(body []byte) []byte {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if decoder.Decode(&value) != nil || !redactValue(value) {
+		return body
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+func redactValue(value interface{}) bool {
+	redacted := false
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if isRedactedField(key) {
+				value[key] = Redacted
+				redacted = true
+			} else if redactValue(field) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if redactValue(item) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+func isRedactedField(key string) bool {
+	for _, field := range redactedFields {
+		if strings.EqualFold(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEqual compares two json bodies regardless of their formatting and key
+// order.
+func jsonEqual(a []byte, b []byte) bool {
+	if len(bytes.TrimSpace(a)) == 0 || len(bytes.TrimSpace(b)) == 0 {
+		return len(bytes.TrimSpace(a)) == len(bytes.TrimSpace(b))
+	}
+
+	var valueA, valueB interface{}
+	if json.Unmarshal(a, &valueA) != nil || json.Unmarshal(b, &valueB) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(valueA, valueB)
+}
+
+func (interaction *Interaction) response() ([]byte, error) {
+	body := []byte(interaction.Response)
+	if !isSuccess(interaction.StatusCode) {
+		return body, &StatusError{interaction.StatusCode, body}
+	}
+	return body, nil
+}
@@ -0,0 +1,174 @@
+package httprequest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newRecordServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/user/nebstate":
+			w.Write([]byte(`{"result":{"chain_id":100}}` + "\n"))
+		case "/v1/user/accountstate":
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write([]byte(`{"result":` + string(body) + `}`))
+		case "/v1/user/subscribe":
+			w.Write([]byte(`{"result":{"topic":"chain.linkBlock"}}` + "\n"))
+			w.Write([]byte(`{"result":{"topic":"chain.pendingTransaction"}}` + "\n"))
+			w.Write([]byte(`{"result":{"topic":"chain.linkBlock"}}` + "\n"))
+		default:
+			http.Error(w, "404 page not found", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestRecorder_Replay(t *testing.T) {
+	server := newRecordServer()
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "testdata", "cassette.json")
+	recorder := NewRecorder(NewHttpRequest(server.URL, APIVersion1), path)
+	recorder.StreamLines = 2
+	ctx := context.Background()
+
+	state, err := recorder.GetWithContext(ctx, "/user/nebstate", nil)
+	if err != nil {
+		t.Fatal("TestRecorder_Replay failed:", err)
+	}
+	account, err := recorder.PostWithContext(ctx, "/user/accountstate", map[string]string{"address": "n1"})
+	if err != nil {
+		t.Fatal("TestRecorder_Replay failed:", err)
+	}
+	_, notFound := recorder.PostWithContext(ctx, "/user/missing", nil)
+	stream, err := recorder.OpenStreamWithContext(ctx, "/user/subscribe", map[string][]string{"topics": {"chain.linkBlock"}})
+	if err != nil {
+		t.Fatal("TestRecorder_Replay failed:", err)
+	}
+	lines, _ := ioutil.ReadAll(stream)
+	stream.Close()
+	if err := recorder.Save(); err != nil {
+		t.Fatal("TestRecorder_Replay save failed:", err)
+	}
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal("TestRecorder_Replay failed:", err)
+	}
+	replayedState, err := replayer.GetWithContext(ctx, "/user/nebstate", nil)
+	if err != nil || string(replayedState) != `{"result":{"chain_id":100}}` || string(state) != `{"result":{"chain_id":100}}`+"\n" {
+		t.Errorf("TestRecorder_Replay nebstate %s: %v", replayedState, err)
+	}
+	replayedAccount, err := replayer.PostWithContext(ctx, "/user/accountstate", map[string]string{"address": "n1"})
+	if err != nil || string(replayedAccount) != string(account) {
+		t.Errorf("TestRecorder_Replay accountstate %s: %v", replayedAccount, err)
+	}
+
+	var statusErr *StatusError
+	_, err = replayer.PostWithContext(ctx, "/user/missing", nil)
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound || err.Error() != notFound.Error() {
+		t.Errorf("TestRecorder_Replay missing api: %v", err)
+	}
+
+	replayedStream, err := replayer.OpenStreamWithContext(ctx, "/user/subscribe", map[string][]string{"topics": {"chain.linkBlock"}})
+	if err != nil {
+		t.Fatal("TestRecorder_Replay stream failed:", err)
+	}
+	replayedLines, _ := ioutil.ReadAll(replayedStream)
+	expected := `{"result":{"topic":"chain.linkBlock"}}` + "\n" + `{"result":{"topic":"chain.pendingTransaction"}}` + "\n"
+	if string(lines) != expected || string(replayedLines) != expected {
+		t.Errorf("TestRecorder_Replay recorded %q replayed %q", lines, replayedLines)
+	}
+
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("TestRecorder_Replay %d interactions unused", len(unused))
+	}
+}
+
+func TestReplayer_Match(t *testing.T) {
+	cassette := &Cassette{Interactions: []*Interaction{
+		{Method: MethodPost, Api: "/user/accountstate", Request: RecordedBody(`{"address":"n1"}`), StatusCode: 200, Response: RecordedBody(`{"nonce":"1"}`)},
+		{Method: MethodPost, Api: "/user/accountstate", Request: RecordedBody(`{"address":"n1"}`), StatusCode: 200, Response: RecordedBody(`{"nonce":"2"}`)},
+		{Method: MethodGet, Api: "/user/nebstate", StatusCode: 200, Response: RecordedBody(`{}`)},
+	}}
+	replayer := NewCassetteReplayer(cassette)
+	ctx := context.Background()
+
+	for _, expected := range []string{`{"nonce":"1"}`, `{"nonce":"2"}`, `{"nonce":"2"}`} {
+		resp, err := replayer.PostWithContext(ctx, "/user/accountstate", map[string]string{"address": "n1"})
+		if err != nil || string(resp) != expected {
+			t.Errorf("TestReplayer_Match got %s: %v, expected %s", resp, err, expected)
+		}
+	}
+
+	_, err := replayer.PostWithContext(ctx, "/user/accountstate", map[string]string{"address": "n2"})
+	if !errors.Is(err, ErrUnexpectedRequest) {
+		t.Error("TestReplayer_Match unexpected request:", err)
+	}
+	_, err = replayer.GetWithContext(ctx, "/user/lib", nil)
+	if !errors.Is(err, ErrUnexpectedRequest) {
+		t.Error("TestReplayer_Match unexpected api:", err)
+	}
+
+	if unused := replayer.Unused(); len(unused) != 1 || unused[0].Api != "/user/nebstate" {
+		t.Errorf("TestReplayer_Match unused %v", unused)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = replayer.GetWithContext(cancelled, "/user/nebstate", nil)
+	if err != context.Canceled {
+		t.Error("TestReplayer_Match should fail with a cancelled context:", err)
+	}
+}
+
+func TestRecorder_Redact(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":{"result":true}}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder := NewRecorder(NewHttpRequest(server.URL, APIVersion1), path)
+	ctx := context.Background()
+
+	unlock := map[string]interface{}{"address": "n1", "passphrase": "secret", "duration": "1000000000"}
+	if _, err := recorder.PostWithContext(ctx, "/admin/account/unlock", unlock); err != nil {
+		t.Fatal("TestRecorder_Redact failed:", err)
+	}
+	send := map[string]interface{}{"transaction": map[string]string{"from": "n1"}, "Password": "secret"}
+	if _, err := recorder.PostWithContext(ctx, "/admin/transactionWithPassphrase", send); err != nil {
+		t.Fatal("TestRecorder_Redact failed:", err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal("TestRecorder_Redact save failed:", err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), "secret") || strings.Count(string(data), Redacted) != 2 {
+		t.Errorf("TestRecorder_Redact recorded the passphrases:\n%s", data)
+	}
+
+	// requests match whatever their passphrases
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal("TestRecorder_Redact failed:", err)
+	}
+	unlock["passphrase"] = "other"
+	if _, err := replayer.PostWithContext(ctx, "/admin/account/unlock", unlock); err != nil {
+		t.Error("TestRecorder_Redact unlock not replayed:", err)
+	}
+	if _, err := replayer.PostWithContext(ctx, "/admin/transactionWithPassphrase", send); err != nil {
+		t.Error("TestRecorder_Redact send not replayed:", err)
+	}
+	unlock["address"] = "n2"
+	if _, err := replayer.PostWithContext(ctx, "/admin/account/unlock", unlock); !errors.Is(err, ErrUnexpectedRequest) {
+		t.Error("TestRecorder_Redact matched another address:", err)
+	}
+}
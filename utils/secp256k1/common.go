@@ -0,0 +1,68 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package secp256k1
+
+import (
+	"errors"
+
+	"github.com/vigozhang/neb-go/utils"
+)
+
+const (
+	// EcdsaPrivateKeyLength private key length
+	EcdsaPrivateKeyLength = 32
+)
+
+var (
+	// ErrInvalidMsgLen invalid message length
+	ErrInvalidMsgLen = errors.New("invalid message length, need 32 bytes")
+
+	// ErrGetPublicKeyFailed private key to public failed
+	ErrGetPublicKeyFailed = errors.New("private key to public failed")
+
+	// ErrInvalidSignature invalid signature length
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrInvalidPrivateKey invalid private key
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+
+	// ErrInvalidPublicKey invalid public key
+	ErrInvalidPublicKey = errors.New("invalid public key")
+
+	// ErrSignFailed sign failed
+	ErrSignFailed = errors.New("sign failed")
+
+	// ErrRecoverFailed recover failed
+	ErrRecoverFailed = errors.New("recovery failed")
+)
+
+// NewSeckey generate a ecdsa private key by secp256k1
+func NewSeckey() []byte {
+	var priv []byte
+
+	// in bitcoin src, they call SeckeyVerify func to verify the generated private key
+	// to make sure valid.
+	for {
+		priv = utils.RandomCSPRNG(EcdsaPrivateKeyLength)
+		if SeckeyVerify(priv) {
+			break
+		}
+	}
+	return priv
+}
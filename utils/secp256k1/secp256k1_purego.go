@@ -0,0 +1,316 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+//go:build !cgo || purego
+// +build !cgo purego
+
+package secp256k1
+
+// pure Go implementation of the secp256k1 functions on the bitelliptic curve,
+// selected by the purego build tag or when cgo is disabled. It produces the
+// same keys and signatures as libsecp256k1: RFC 6979 nonces with the message
+// and key as input and low S values. Unlike libsecp256k1 it is not constant
+// time.
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/vigozhang/neb-go/utils/secp256k1/bitelliptic"
+)
+
+var (
+	curve = bitelliptic.S256()
+	// halfN the highest S of a low S signature
+	halfN = new(big.Int).Rsh(curve.N, 1)
+)
+
+// SeckeyVerify check private is ok for secp256k1
+func SeckeyVerify(seckey []byte) bool {
+	if len(seckey) != EcdsaPrivateKeyLength {
+		return false
+	}
+	d := new(big.Int).SetBytes(seckey)
+	return d.Sign() > 0 && d.Cmp(curve.N) < 0
+}
+
+// GetPublicKey private key to public key
+func GetPublicKey(seckey []byte) ([]byte, error) {
+	if !SeckeyVerify(seckey) {
+		return nil, ErrGetPublicKeyFailed
+	}
+	x, y := curve.ScalarBaseMult(seckey)
+	return curve.Marshal(x, y), nil
+}
+
+// RecoverECDSAPublicKey recover verifies the compact signature "signature" of "hash"
+func RecoverECDSAPublicKey(msg []byte, signature []byte) ([]byte, error) {
+	if len(msg) != 32 {
+		return nil, ErrInvalidMsgLen
+	}
+	if len(signature) != 65 {
+		return nil, ErrInvalidSignature
+	}
+
+	r, s, ok := parseCompact(signature)
+	recid := signature[64]
+	if !ok || recid > 3 {
+		return nil, ErrRecoverFailed
+	}
+
+	// R is the point of the nonce, with x = r (+ N) and the parity of recid
+	rx := new(big.Int).Set(r)
+	if recid&2 != 0 {
+		rx.Add(rx, curve.N)
+		if rx.Cmp(curve.P) >= 0 {
+			return nil, ErrRecoverFailed
+		}
+	}
+	ry, ok := decompressY(rx, recid&1 == 1)
+	if !ok {
+		return nil, ErrRecoverFailed
+	}
+
+	// Q = r⁻¹(sR - zG) = (-z r⁻¹)G + (s r⁻¹)R
+	rinv := new(big.Int).ModInverse(r, curve.N)
+	z := hashToInt(msg)
+	u1 := new(big.Int).Mul(z, rinv)
+	u1.Neg(u1).Mod(u1, curve.N)
+	u2 := new(big.Int).Mul(s, rinv)
+	u2.Mod(u2, curve.N)
+
+	x1, y1 := curve.ScalarBaseMult(scalarBytes(u1))
+	x2, y2 := curve.ScalarMult(rx, ry, scalarBytes(u2))
+	qx, qy := addPoints(x1, y1, x2, y2)
+	if qx == nil {
+		return nil, ErrRecoverFailed
+	}
+	return curve.Marshal(qx, qy), nil
+}
+
+// Sign sign hash with private key
+func Sign(msg []byte, seckey []byte) ([]byte, error) {
+	if len(msg) != 32 {
+		return nil, ErrInvalidMsgLen
+	}
+	if !SeckeyVerify(seckey) {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	d := new(big.Int).SetBytes(seckey)
+	z := hashToInt(msg)
+	nonces := newRFC6979(seckey, msg)
+	for {
+		k := nonces.next()
+
+		rx, ry := curve.ScalarBaseMult(scalarBytes(k))
+		recid := byte(ry.Bit(0))
+		if rx.Cmp(curve.N) >= 0 {
+			recid |= 2
+		}
+		r := new(big.Int).Mod(rx, curve.N)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		// s = k⁻¹(z + rd)
+		s := new(big.Int).Mul(r, d)
+		s.Add(s, z)
+		s.Mul(s, new(big.Int).ModInverse(k, curve.N))
+		s.Mod(s, curve.N)
+		if s.Sign() == 0 {
+			continue
+		}
+		if s.Cmp(halfN) > 0 {
+			s.Sub(curve.N, s)
+			recid ^= 1
+		}
+
+		sig := make([]byte, 65)
+		copy(sig[0:32], scalarBytes(r))
+		copy(sig[32:64], scalarBytes(s))
+		sig[64] = recid
+		return sig, nil
+	}
+}
+
+// Verify verify with public key
+func Verify(msg []byte, signature []byte, pub []byte) (bool, error) {
+	if len(msg) != 32 {
+		return false, ErrInvalidMsgLen
+	}
+
+	qx, qy, ok := parsePublicKey(pub)
+	if !ok {
+		return false, ErrInvalidPublicKey
+	}
+	if len(signature) < 64 {
+		return false, ErrInvalidSignature
+	}
+	r, s, ok := parseCompact(signature)
+	if !ok {
+		return false, ErrInvalidSignature
+	}
+	// libsecp256k1 only accepts low S signatures
+	if s.Cmp(halfN) > 0 {
+		return false, nil
+	}
+
+	sinv := new(big.Int).ModInverse(s, curve.N)
+	u1 := new(big.Int).Mul(hashToInt(msg), sinv)
+	u1.Mod(u1, curve.N)
+	u2 := new(big.Int).Mul(r, sinv)
+	u2.Mod(u2, curve.N)
+
+	x1, y1 := curve.ScalarBaseMult(scalarBytes(u1))
+	x2, y2 := curve.ScalarMult(qx, qy, scalarBytes(u2))
+	x, _ := addPoints(x1, y1, x2, y2)
+	if x == nil {
+		return false, nil
+	}
+	return x.Mod(x, curve.N).Cmp(r) == 0, nil
+}
+
+// parseCompact reads r and s of a 64 bytes compact signature, both must be
+// in [1, N-1].
+func parseCompact(signature []byte) (r, s *big.Int, ok bool) {
+	r = new(big.Int).SetBytes(signature[0:32])
+	s = new(big.Int).SetBytes(signature[32:64])
+	if r.Sign() == 0 || s.Sign() == 0 || r.Cmp(curve.N) >= 0 || s.Cmp(curve.N) >= 0 {
+		return nil, nil, false
+	}
+	return r, s, true
+}
+
+// parsePublicKey reads a compressed, uncompressed or hybrid public key.
+func parsePublicKey(pub []byte) (x, y *big.Int, ok bool) {
+	switch {
+	case len(pub) == 33 && (pub[0] == 2 || pub[0] == 3):
+		x = new(big.Int).SetBytes(pub[1:])
+		if x.Cmp(curve.P) >= 0 {
+			return nil, nil, false
+		}
+		y, ok = decompressY(x, pub[0] == 3)
+		return x, y, ok
+	case len(pub) == 65 && (pub[0] == 4 || pub[0] == 6 || pub[0] == 7):
+		x = new(big.Int).SetBytes(pub[1:33])
+		y = new(big.Int).SetBytes(pub[33:])
+		if x.Cmp(curve.P) >= 0 || y.Cmp(curve.P) >= 0 || !curve.IsOnCurve(x, y) {
+			return nil, nil, false
+		}
+		if pub[0] != 4 && (pub[0] == 7) != (y.Bit(0) == 1) {
+			return nil, nil, false
+		}
+		return x, y, true
+	}
+	return nil, nil, false
+}
+
+// decompressY returns the y of x on the curve with the parity odd.
+func decompressY(x *big.Int, odd bool) (*big.Int, bool) {
+	// y² = x³ + 7, P ≡ 3 mod 4 so y = (y²)^((P+1)/4)
+	y2 := new(big.Int).Mul(x, x)
+	y2.Mul(y2, x)
+	y2.Add(y2, curve.B)
+	y2.Mod(y2, curve.P)
+
+	exp := new(big.Int).Add(curve.P, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	y := new(big.Int).Exp(y2, exp, curve.P)
+	if new(big.Int).Exp(y, big.NewInt(2), curve.P).Cmp(y2) != 0 {
+		return nil, false
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(curve.P, y)
+	}
+	return y, true
+}
+
+// addPoints adds two points, nil standing for the point at infinity, which
+// bitelliptic does not handle.
+func addPoints(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) == 0 {
+			return curve.Double(x1, y1)
+		}
+		return nil, nil
+	}
+	return curve.Add(x1, y1, x2, y2)
+}
+
+// hashToInt reads the message as a scalar mod N, like libsecp256k1.
+func hashToInt(msg []byte) *big.Int {
+	z := new(big.Int).SetBytes(msg)
+	return z.Mod(z, curve.N)
+}
+
+func scalarBytes(k *big.Int) []byte {
+	return paddedBigBytes(k, 32)
+}
+
+// rfc6979 generates the nonces of libsecp256k1's nonce_function_rfc6979: a
+// HMAC-SHA256 DRBG seeded with the key and the message.
+type rfc6979 struct {
+	k, v  []byte
+	first bool
+}
+
+func newRFC6979(seckey []byte, msg []byte) *rfc6979 {
+	gen := &rfc6979{k: make([]byte, 32), v: make([]byte, 32), first: true}
+	for i := range gen.v {
+		gen.v[i] = 0x01
+	}
+
+	gen.k = gen.mac(gen.k, gen.v, []byte{0x00}, seckey, msg)
+	gen.v = gen.mac(gen.k, gen.v)
+	gen.k = gen.mac(gen.k, gen.v, []byte{0x01}, seckey, msg)
+	gen.v = gen.mac(gen.k, gen.v)
+	return gen
+}
+
+// next returns the next nonce in [1, N-1].
+func (gen *rfc6979) next() *big.Int {
+	for {
+		if !gen.first {
+			gen.k = gen.mac(gen.k, gen.v, []byte{0x00})
+			gen.v = gen.mac(gen.k, gen.v)
+		}
+		gen.first = false
+
+		gen.v = gen.mac(gen.k, gen.v)
+		k := new(big.Int).SetBytes(gen.v)
+		if k.Sign() > 0 && k.Cmp(curve.N) < 0 {
+			return k
+		}
+	}
+}
+
+func (gen *rfc6979) mac(key []byte, data ...[]byte) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
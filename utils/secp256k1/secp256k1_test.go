@@ -0,0 +1,97 @@
+// Copyright (C) 2017 go-nebulas authors
+//
+// This file is part of the go-nebulas library.
+//
+// the go-nebulas library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// the go-nebulas library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the go-nebulas library.  If not, see <http://www.gnu.org/licenses/>.
+//
+
+package secp256k1
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/vigozhang/neb-go/utils/hash"
+)
+
+// vectors produced by libsecp256k1, the pure Go backend must match them:
+// go test -tags purego ./utils/secp256k1/
+var signVectors = []struct {
+	seckey    string
+	msg       string
+	publicKey string
+	signature string
+}{
+	{"ac3773e06ae74c0fa566b0e421d4e391333f31aef90b383f0c0e83e4873609d6", "", "0420ba34f53b39cb6e43b68a46f1fc2cf987d8fb362eba158c6d4685de7e6e9daca5d6dd1326f5e902562e2661785b6b94c1566b75b7383f3662e2a2399d2c8d09", "b3ed1f4b779d5d42f910b72a64d0f9aef1af93ddbe357244a9eae4652708f6202866642f0f5cb8ca38f707733f501ab3782699441fe711291a66e59744596ebe00"},
+	{"ac3773e06ae74c0fa566b0e421d4e391333f31aef90b383f0c0e83e4873609d6", "nebulas", "0420ba34f53b39cb6e43b68a46f1fc2cf987d8fb362eba158c6d4685de7e6e9daca5d6dd1326f5e902562e2661785b6b94c1566b75b7383f3662e2a2399d2c8d09", "e9a1b26bf7de10f8deacfb3aa858c76a74b0750ac8cbfbee0e6bc472bda4a41571e55a39084122c68bc15470ad1bfaf675cd828b5da46d0c5760a584c9740e9200"},
+	{"ac3773e06ae74c0fa566b0e421d4e391333f31aef90b383f0c0e83e4873609d6", "the quick brown fox", "0420ba34f53b39cb6e43b68a46f1fc2cf987d8fb362eba158c6d4685de7e6e9daca5d6dd1326f5e902562e2661785b6b94c1566b75b7383f3662e2a2399d2c8d09", "16cbbb75cd64a8700d895fd362e1db31081973d893453d015a9516da7953d8f03508a22a87fa73b24dd41e349a7e7ad48de6eb59e7975cbd9beccea69d7ac2a800"},
+	{"0000000000000000000000000000000000000000000000000000000000000001", "", "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", "ab1a548e7964281d13c2e4c3d260fdf29acd9d6e8d21edc63522c972ab3d2aba0e94f1d6c7a3282498a7f15eaabda6ed2c60adf28208d7f3ffcaef36a23d1eb200"},
+	{"0000000000000000000000000000000000000000000000000000000000000001", "nebulas", "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", "95909168967d211979eac8a030cf7afca0708240e71cd38f09ddb610e2234a47349e60390bc2b6f7fa2cca633db9b829a63f0699bbeae18441816ccdd53b113901"},
+	{"0000000000000000000000000000000000000000000000000000000000000001", "the quick brown fox", "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", "8e6e5b1ee86c4ff39c686d1b031b51eacdb652c2b184d657c29e2b933b5c0fa868c17903d4144fab0b36bfb29023495939e33bdabc82830ec195f70a650d901d00"},
+	{"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140", "", "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777", "191dbcd20cac7bcab62098d68405ca79b015bb312195a3eef87efe35303765540d68cb3a627c048f2085dd2bca539eade89775799287cd9070d67143fbd44b4c00"},
+	{"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140", "nebulas", "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777", "e6c9626d8f5899f6f8b53a1ada9cc80edfba7f5b1ff097e3339bfeb4cbcb526d4bac60060e31ff602e8affea2f242ad14047f9e68f41daa54af587f12b50966c00"},
+	{"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140", "the quick brown fox", "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777", "6d7ccf25147b36e640ba33f8af9c0a27a26ca1d72814c2e8b1fef9295aab69bc346bd874076eaa07feb988185035a94d7832229fd0b661981c0bae970f5cd61d01"},
+	{"00f2c2b3e0e3ec6dc0bb6e4a2f5c1a4fe1fd5f0d56d3a0e0f0e2a8d2f7a1b1c3", "", "042f3b7bb270f52cde72760e6dd24193adeb1c0b5e3fab8f78c0ec45d16285ebaf5d6b84e1997956f4193f59e3e7c7dca9868df89774d01f0578eaceb7ceb02e17", "a0efb735ba97d7ae1effcfc69827f83b5df6e88e86fdc7e7dd346b96fed9cdb93955368a21fda57367d8f1eebd754f284507dd2baa6cd40caa9b7bb2b2d617ed00"},
+	{"00f2c2b3e0e3ec6dc0bb6e4a2f5c1a4fe1fd5f0d56d3a0e0f0e2a8d2f7a1b1c3", "nebulas", "042f3b7bb270f52cde72760e6dd24193adeb1c0b5e3fab8f78c0ec45d16285ebaf5d6b84e1997956f4193f59e3e7c7dca9868df89774d01f0578eaceb7ceb02e17", "6ed5fcab508565351cf87aefe60e7679385b2d9e0569d54825672958ef7141f034c2c93b07c8fb4169f4d8b2a0b4d047628d3e7cc8a30f3c68a80e823e35970201"},
+	{"00f2c2b3e0e3ec6dc0bb6e4a2f5c1a4fe1fd5f0d56d3a0e0f0e2a8d2f7a1b1c3", "the quick brown fox", "042f3b7bb270f52cde72760e6dd24193adeb1c0b5e3fab8f78c0ec45d16285ebaf5d6b84e1997956f4193f59e3e7c7dca9868df89774d01f0578eaceb7ceb02e17", "0f0e21030c8523aa8ec8e8223e238d28c97e85ac8fa3a08a26510ea72df90a6e39231fafab471dc9391c57922134ca42e9a368d6921a77f95576852bcb78256b00"},
+}
+
+func TestSign_Vectors(t *testing.T) {
+	for _, vector := range signVectors {
+		seckey, _ := hex.DecodeString(vector.seckey)
+		msg := hash.Sha3256([]byte(vector.msg))
+
+		publicKey, err := GetPublicKey(seckey)
+		if err != nil || hex.EncodeToString(publicKey) != vector.publicKey {
+			t.Errorf("GetPublicKey %s: %x %v", vector.seckey, publicKey, err)
+		}
+
+		signature, err := Sign(msg, seckey)
+		if err != nil || hex.EncodeToString(signature) != vector.signature {
+			t.Errorf("Sign %s %q: %x %v", vector.seckey, vector.msg, signature, err)
+		}
+
+		recovered, err := RecoverECDSAPublicKey(msg, signature)
+		if err != nil || hex.EncodeToString(recovered) != vector.publicKey {
+			t.Errorf("RecoverECDSAPublicKey %s %q: %x %v", vector.seckey, vector.msg, recovered, err)
+		}
+
+		valid, err := Verify(msg, signature[:64], publicKey)
+		if err != nil || !valid {
+			t.Errorf("Verify %s %q: %v", vector.seckey, vector.msg, err)
+		}
+		msg[0] ^= 0xff
+		valid, _ = Verify(msg, signature[:64], publicKey)
+		if valid {
+			t.Errorf("Verify %s %q accepted another message", vector.seckey, vector.msg)
+		}
+	}
+}
+
+func TestSeckeyVerify(t *testing.T) {
+	invalid := []string{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+	}
+	for _, key := range invalid {
+		seckey, _ := hex.DecodeString(key)
+		if SeckeyVerify(seckey) {
+			t.Error("SeckeyVerify accepted", key)
+		}
+	}
+	if !SeckeyVerify(NewSeckey()) {
+		t.Error("SeckeyVerify rejected a new key")
+	}
+}